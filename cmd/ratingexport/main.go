@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ugurcancaykara/odd-service/gen"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/export"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// implementing an app that exports ratings of the rating service to a file for the data warehouse.
+// Ratings are streamed with the ExportRatings RPC, which reads a consistent snapshot of the MySQL repository.
+
+func main() {
+	addr := flag.String("addr", "localhost:8082", "address of a rating service instance")
+	format := flag.String("format", string(export.FormatJSONL), "export format: jsonl, csv or columnar")
+	fileName := flag.String("out", "-", "file to export ratings to, - for stdout")
+	recordType := flag.String("type", "", "export only ratings of the given record type")
+	providerID := flag.String("provider", "", "export only ratings received from the given provider")
+	from := flag.String("from", "", "export only ratings last updated at or after the given RFC 3339 time")
+	to := flag.String("to", "", "export only ratings last updated before the given RFC 3339 time")
+	flag.Parse()
+
+	req := &gen.ExportRatingsRequest{RecordType: *recordType, ProviderId: *providerID}
+	var err error
+	if req.From, err = parseTime(*from); err != nil {
+		panic(err)
+	}
+	if req.To, err = parseTime(*to); err != nil {
+		panic(err)
+	}
+
+	out := os.Stdout
+	if *fileName != "-" {
+		out, err = os.Create(*fileName)
+		if err != nil {
+			panic(err)
+		}
+		defer out.Close()
+	}
+	buf := bufio.NewWriter(out)
+	w, err := export.NewWriter(export.Format(*format), buf)
+	if err != nil {
+		panic(err)
+	}
+
+	conn, err := grpc.Dial(*addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	stream, err := gen.NewRatingServiceClient(conn).ExportRatings(context.Background(), req)
+	if err != nil {
+		panic(err)
+	}
+
+	exported := 0
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			panic(err)
+		}
+		for _, r := range resp.Ratings {
+			if err := w.Write(model.RatingFromProto(r)); err != nil {
+				panic(err)
+			}
+			exported++
+		}
+	}
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+	if err := buf.Flush(); err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d ratings as %s\n", exported, *format)
+}
+
+func parseTime(s string) (*timestamppb.Timestamp, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, err
+	}
+	return timestamppb.New(t), nil
+}
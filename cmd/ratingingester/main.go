@@ -1,37 +1,83 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/codec"
 	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// implementing example app that reads rating data from a provided file and produces it in Kafka.
+// implementing example app that produces rating events in Kafka, either read from a provided file
+// or generated synthetically for load testing the rating ingestion.
 
 func main() {
-	fmt.Println("Creating a Kafka producer")
-
-	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": "localhost"})
+	broker := flag.String("broker", "localhost", "Kafka bootstrap servers")
+	topic := flag.String("topic", "ratings", "topic to produce rating events to")
+	fileName := flag.String("file", "ratingsdata.json", "JSON file containing an array of rating events to produce")
+	encoding := flag.String("encoding", "json", "rating event encoding, json or proto")
+	synthetic := flag.Bool("synthetic", false, "generate synthetic rating events instead of reading them from a file")
+	var cfg syntheticConfig
+	flag.IntVar(&cfg.Movies, "movies", 10000, "synthetic mode: number of movies, picked following a Zipf distribution")
+	flag.Float64Var(&cfg.Skew, "skew", 1.1, "synthetic mode: Zipf skew of the movie popularity, must be greater than 1")
+	flag.IntVar(&cfg.Users, "users", 100000, "synthetic mode: number of users, picked uniformly")
+	flag.Float64Var(&cfg.Rate, "rate", 100, "synthetic mode: events produced per second")
+	flag.Float64Var(&cfg.DeleteRatio, "delete-ratio", 0.05, "synthetic mode: share of delete events, deletes target previously produced ratings")
+	flag.StringVar(&cfg.ProviderID, "provider", "synthetic-provider", "synthetic mode: provider id of the events")
+	flag.IntVar(&cfg.Count, "count", 0, "synthetic mode: number of events to produce, 0 produces until interrupted")
+	flag.DurationVar(&cfg.Duration, "duration", 0, "synthetic mode: how long to produce events, 0 produces until interrupted")
+	flag.Int64Var(&cfg.Seed, "seed", 0, "synthetic mode: random seed, 0 uses the current time")
+	var traceCfg tracing.Config
+	flag.StringVar(&traceCfg.Exporter, "trace-exporter", tracing.ExporterNone, "exporter of the spans of produced events: otlp, file or none")
+	flag.StringVar(&traceCfg.Endpoint, "trace-endpoint", "localhost:4317", "OTLP gRPC collector of the otlp trace exporter")
+	flag.StringVar(&traceCfg.File, "trace-file", "traces.jsonl", "file of the file trace exporter")
+	flag.Float64Var(&traceCfg.SampleRatio, "trace-sample-ratio", 1, "ratio of produced events which are traced")
+	flag.Parse()
+	contentType, err := codec.ContentType(*encoding)
 	if err != nil {
 		panic(err)
 	}
-	defer producer.Close()
+	shutdownTracing, err := tracing.Init(context.Background(), "ratingingester", traceCfg)
+	if err != nil {
+		panic(err)
+	}
+	defer shutdownTracing(context.Background())
 
-	const fileName = "ratingsdata.json"
-	fmt.Println("Reading rating events from file " + fileName)
+	fmt.Println("Creating a Kafka producer")
 
-	ratingEvents, err := readRatingEvents(fileName)
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": *broker})
 	if err != nil {
 		panic(err)
 	}
+	defer producer.Close()
+	go reportDeliveryErrors(producer)
 
-	var topic = "ratings"
-	if err := produceRatingEvents(topic, producer, ratingEvents); err != nil {
-		panic(err)
+	if *synthetic {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := produceSyntheticEvents(ctx, *topic, producer, cfg, contentType); err != nil {
+			panic(err)
+		}
+	} else {
+		fmt.Println("Reading rating events from file " + *fileName)
+
+		ratingEvents, err := readRatingEvents(*fileName)
+		if err != nil {
+			panic(err)
+		}
+		if err := produceRatingEvents(context.Background(), *topic, producer, ratingEvents, contentType); err != nil {
+			panic(err)
+		}
 	}
 
 	var timeout = 10 * time.Second
@@ -53,19 +99,57 @@ func readRatingEvents(fileName string) ([]model.RatingEvent, error) {
 	return ratings, nil
 }
 
-func produceRatingEvents(topic string, producer *kafka.Producer, events []model.RatingEvent) error {
+func produceRatingEvents(ctx context.Context, topic string, producer *kafka.Producer, events []model.RatingEvent, contentType string) error {
 	for _, event := range events {
-		encodedEvent, err := json.Marshal(event)
-		if err != nil {
+		if err := produceRatingEvent(ctx, topic, producer, &event, contentType); err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		if err := producer.Produce(&kafka.Message{
-			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
-			Value:          []byte(encodedEvent),
-		}, nil); err != nil {
-			return err
+// produceRatingEvent produces a single event keyed by its record, so events of a record stay in one partition.
+// It waits for the producer queue to drain if it is full. The trace context of the event is carried in the message headers.
+func produceRatingEvent(ctx context.Context, topic string, producer *kafka.Producer, event *model.RatingEvent, contentType string) error {
+	encodedEvent, err := codec.Encode(event, contentType)
+	if err != nil {
+		return err
+	}
+	ctx, span := tracing.Start(ctx, topic+" publish",
+		attribute.String("messaging.system", "kafka"),
+		attribute.String("messaging.destination.name", topic),
+	)
+	defer span.End()
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte(string(event.RecordType) + "/" + string(event.RecordID)),
+		Value:          encodedEvent,
+		Headers:        []kafka.Header{{Key: codec.HeaderContentType, Value: []byte(contentType)}},
+	}
+	for key, value := range tracing.Inject(ctx) {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+	for {
+		err := producer.Produce(msg, nil)
+		var kerr kafka.Error
+		if errors.As(err, &kerr) && kerr.Code() == kafka.ErrQueueFull {
+			producer.Flush(100)
+			continue
+		}
+		return err
+	}
+}
+
+// reportDeliveryErrors prints failed deliveries. The producer events must be drained, otherwise producing blocks.
+func reportDeliveryErrors(producer *kafka.Producer) {
+	for e := range producer.Events() {
+		switch ev := e.(type) {
+		case *kafka.Message:
+			if ev.TopicPartition.Error != nil {
+				fmt.Println("Delivery error: " + ev.TopicPartition.Error.Error())
+			}
+		case kafka.Error:
+			fmt.Println("Producer error: " + ev.Error())
 		}
 	}
-	return nil
 }
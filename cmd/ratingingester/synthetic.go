@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// syntheticConfig defines the shape of the generated rating traffic.
+type syntheticConfig struct {
+	Movies      int
+	Skew        float64
+	Users       int
+	Rate        float64
+	DeleteRatio float64
+	ProviderID  string
+	Count       int
+	Duration    time.Duration
+	Seed        int64
+}
+
+// recentRatings defines how many produced ratings are remembered as delete candidates.
+const recentRatings = 10000
+
+// ratingValueWeights skews generated rating values towards positive ratings, index 0 is value 1.
+var ratingValueWeights = []int{10, 10, 20, 30, 30}
+
+// generator generates rating events. Movie popularity follows a Zipf distribution,
+// so a few movies receive most of the ratings like in real traffic.
+type generator struct {
+	cfg    syntheticConfig
+	rand   *rand.Rand
+	movies *rand.Zipf
+	// recent holds a sample of produced ratings, deletes pick one of them.
+	recent []model.RatingEvent
+}
+
+func newGenerator(cfg syntheticConfig) (*generator, error) {
+	if cfg.Movies < 1 || cfg.Users < 1 {
+		return nil, errors.New("movies and users must be positive")
+	}
+	if cfg.Skew <= 1 {
+		return nil, errors.New("skew must be greater than 1")
+	}
+	if cfg.DeleteRatio < 0 || cfg.DeleteRatio > 1 {
+		return nil, errors.New("delete ratio must be between 0 and 1")
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	r := rand.New(rand.NewSource(seed))
+	return &generator{
+		cfg:    cfg,
+		rand:   r,
+		movies: rand.NewZipf(r, cfg.Skew, 1, uint64(cfg.Movies-1)),
+	}, nil
+}
+
+// event returns the next rating event.
+func (g *generator) event() model.RatingEvent {
+	if len(g.recent) > 0 && g.rand.Float64() < g.cfg.DeleteRatio {
+		i := g.rand.Intn(len(g.recent))
+		e := g.recent[i]
+		// A rating is deleted only once.
+		last := len(g.recent) - 1
+		g.recent[i] = g.recent[last]
+		g.recent = g.recent[:last]
+		e.EventType = model.RatingEventTypeDelete
+		e.Value = 0
+		return e
+	}
+	e := model.RatingEvent{
+		UserID:     model.UserID(strconv.Itoa(g.rand.Intn(g.cfg.Users) + 1)),
+		RecordID:   model.RecordID(strconv.FormatUint(g.movies.Uint64()+1, 10)),
+		RecordType: model.RecordTypeMovie,
+		Value:      g.value(),
+		ProviderID: g.cfg.ProviderID,
+		EventType:  model.RatingEventTypePut,
+	}
+	if len(g.recent) < recentRatings {
+		g.recent = append(g.recent, e)
+	} else {
+		g.recent[g.rand.Intn(recentRatings)] = e
+	}
+	return e
+}
+
+func (g *generator) value() model.RatingValue {
+	total := 0
+	for _, w := range ratingValueWeights {
+		total += w
+	}
+	n := g.rand.Intn(total)
+	for i, w := range ratingValueWeights {
+		if n < w {
+			return model.RatingValue(i + 1)
+		}
+		n -= w
+	}
+	return model.RatingValue(len(ratingValueWeights))
+}
+
+// produceSyntheticEvents produces generated events at the configured rate until the count or duration
+// is reached or the context is done, printing the achieved rate every few seconds.
+func produceSyntheticEvents(ctx context.Context, topic string, producer *kafka.Producer, cfg syntheticConfig, contentType string) error {
+	if cfg.Rate <= 0 {
+		return errors.New("rate must be positive")
+	}
+	g, err := newGenerator(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+	fmt.Printf("Producing synthetic rating events at %.0f/s for %d movies and %d users\n", cfg.Rate, cfg.Movies, cfg.Users)
+
+	const tick = 10 * time.Millisecond
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	report := time.NewTicker(5 * time.Second)
+	defer report.Stop()
+
+	start := time.Now()
+	produced, reported := 0, 0
+	lastReport := start
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Printf("Produced %d synthetic rating events in %s\n", produced, time.Since(start).Round(time.Millisecond))
+			return nil
+		case now := <-report.C:
+			fmt.Printf("Produced %d events, %.0f/s\n", produced, float64(produced-reported)/now.Sub(lastReport).Seconds())
+			reported, lastReport = produced, now
+		case now := <-ticker.C:
+			// Catch up to the number of events due since the start, so the rate holds even if ticks are late.
+			due := int(now.Sub(start).Seconds() * cfg.Rate)
+			for ; produced < due; produced++ {
+				if cfg.Count > 0 && produced >= cfg.Count {
+					fmt.Printf("Produced %d synthetic rating events in %s\n", produced, time.Since(start).Round(time.Millisecond))
+					return nil
+				}
+				event := g.event()
+				if err := produceRatingEvent(ctx, topic, producer, &event, contentType); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+func testSyntheticConfig() syntheticConfig {
+	return syntheticConfig{Movies: 1000, Skew: 1.1, Users: 500, DeleteRatio: 0.1, ProviderID: "synthetic-provider", Seed: 42}
+}
+
+func TestNewGenerator(t *testing.T) {
+	tests := []struct {
+		name    string
+		update  func(cfg *syntheticConfig)
+		wantErr bool
+	}{
+		{name: "valid", update: func(*syntheticConfig) {}},
+		{name: "single movie", update: func(cfg *syntheticConfig) { cfg.Movies = 1 }},
+		{name: "no movies", update: func(cfg *syntheticConfig) { cfg.Movies = 0 }, wantErr: true},
+		{name: "no users", update: func(cfg *syntheticConfig) { cfg.Users = 0 }, wantErr: true},
+		{name: "skew of 1", update: func(cfg *syntheticConfig) { cfg.Skew = 1 }, wantErr: true},
+		{name: "negative delete ratio", update: func(cfg *syntheticConfig) { cfg.DeleteRatio = -0.1 }, wantErr: true},
+		{name: "delete ratio above 1", update: func(cfg *syntheticConfig) { cfg.DeleteRatio = 1.5 }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testSyntheticConfig()
+			tt.update(&cfg)
+			_, err := newGenerator(cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func generate(t *testing.T, cfg syntheticConfig, n int) []model.RatingEvent {
+	t.Helper()
+	g, err := newGenerator(cfg)
+	require.NoError(t, err)
+	events := make([]model.RatingEvent, n)
+	for i := range events {
+		events[i] = g.event()
+	}
+	return events
+}
+
+func TestGenerator_Seed(t *testing.T) {
+	cfg := testSyntheticConfig()
+	assert.Equal(t, generate(t, cfg, 1000), generate(t, cfg, 1000), "the same seed generates the same events")
+	cfg.Seed = 43
+	assert.NotEqual(t, generate(t, testSyntheticConfig(), 1000), generate(t, cfg, 1000))
+}
+
+func TestGenerator_EventShape(t *testing.T) {
+	cfg := testSyntheticConfig()
+	type key struct {
+		user   model.UserID
+		record model.RecordID
+	}
+	// put counts the ratings generated per user and record which weren't deleted yet.
+	put := map[key]int{}
+	deletes := 0
+	for _, e := range generate(t, cfg, 20000) {
+		assert.Equal(t, model.RecordTypeMovie, e.RecordType)
+		assert.Equal(t, cfg.ProviderID, e.ProviderID)
+		user, err := strconv.Atoi(string(e.UserID))
+		require.NoError(t, err)
+		assert.True(t, user >= 1 && user <= cfg.Users, "user id %d", user)
+		movie, err := strconv.Atoi(string(e.RecordID))
+		require.NoError(t, err)
+		assert.True(t, movie >= 1 && movie <= cfg.Movies, "movie id %d", movie)
+
+		k := key{e.UserID, e.RecordID}
+		switch e.EventType {
+		case model.RatingEventTypePut:
+			assert.True(t, e.Value >= 1 && e.Value <= 5, "value %d", e.Value)
+			put[k]++
+		case model.RatingEventTypeDelete:
+			assert.Zero(t, e.Value)
+			assert.Positive(t, put[k], "deletes target a previously put rating")
+			put[k]--
+			deletes++
+		default:
+			t.Fatalf("unexpected event type %q", e.EventType)
+		}
+	}
+	assert.InDelta(t, 0.1, float64(deletes)/20000, 0.01)
+}
+
+func TestGenerator_Distribution(t *testing.T) {
+	cfg := testSyntheticConfig()
+	cfg.DeleteRatio = 0
+	const n = 50000
+	movies := map[model.RecordID]int{}
+	values := map[model.RatingValue]int{}
+	for _, e := range generate(t, cfg, n) {
+		movies[e.RecordID]++
+		values[e.Value]++
+	}
+
+	// Popularity follows the rank of the movie: the first movies receive most of the ratings.
+	assert.Greater(t, movies["1"], movies["2"])
+	assert.Greater(t, movies["2"], movies["10"])
+	assert.Greater(t, movies["10"], movies["100"])
+	// The share of movie k is proportional to k^-skew.
+	var top, topWeight, totalWeight float64
+	for id := 1; id <= cfg.Movies; id++ {
+		w := math.Pow(float64(id), -cfg.Skew)
+		totalWeight += w
+		if id <= cfg.Movies/100 {
+			top += float64(movies[model.RecordID(strconv.Itoa(id))])
+			topWeight += w
+		}
+	}
+	assert.InDelta(t, topWeight/totalWeight, top/n, 0.01, "share of the top 1%% of the movies")
+
+	// Values follow ratingValueWeights, which sum up to 100.
+	for i, w := range ratingValueWeights {
+		assert.InDelta(t, float64(w)/100, float64(values[model.RatingValue(i+1)])/n, 0.01, "value %d", i+1)
+	}
+}
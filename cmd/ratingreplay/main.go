@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/codec"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// implementing an app that replays dead-letter entries of the rating service back to the ratings topic.
+// Entries are read from a dead-letter file or topic. To fix an entry before replaying it, edit its event field,
+// which takes precedence over the raw payload.
+
+// options defines the command-line options of the replay.
+type options struct {
+	broker   string
+	topic    string
+	fileName string
+	dlqTopic string
+	groupID  string
+	reason   model.DeadLetterReason
+	dryRun   bool
+}
+
+// parseFlags parses the command-line arguments, without the program name.
+func parseFlags(args []string) (options, error) {
+	var o options
+	var reason string
+	fs := flag.NewFlagSet("ratingreplay", flag.ContinueOnError)
+	fs.StringVar(&o.broker, "broker", "localhost", "Kafka bootstrap servers")
+	fs.StringVar(&o.topic, "topic", "ratings", "topic to replay rating events to")
+	fs.StringVar(&o.fileName, "file", "ratings-dlq.jsonl", "dead-letter file to read entries from")
+	fs.StringVar(&o.dlqTopic, "dlq-topic", "", "dead-letter topic to read entries from instead of a file")
+	fs.StringVar(&o.groupID, "group", "odd-service-rating-replay", "consumer group used to read the dead-letter topic")
+	fs.StringVar(&reason, "reason", "", "replay only entries with the given reason (decode, validate or persist)")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "print entries instead of producing them")
+	if err := fs.Parse(args); err != nil {
+		return o, err
+	}
+	if fs.NArg() > 0 {
+		return o, fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+	o.reason = model.DeadLetterReason(reason)
+	switch o.reason {
+	case "", model.DeadLetterReasonDecode, model.DeadLetterReasonValidate, model.DeadLetterReasonPersist:
+	default:
+		return o, fmt.Errorf("unknown reason %q, expected decode, validate or persist", reason)
+	}
+	return o, nil
+}
+
+// selected reports whether an entry is replayed with the options.
+func (o options) selected(e model.DeadLetterEntry) bool {
+	return o.reason == "" || e.Reason == o.reason
+}
+
+func main() {
+	opts, err := parseFlags(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	var entries []model.DeadLetterEntry
+	if opts.dlqTopic != "" {
+		fmt.Println("Reading dead-letter entries from topic " + opts.dlqTopic)
+		entries, err = readTopicEntries(opts.broker, opts.groupID, opts.dlqTopic)
+	} else {
+		fmt.Println("Reading dead-letter entries from file " + opts.fileName)
+		entries, err = readFileEntries(opts.fileName)
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	var producer *kafka.Producer
+	if !opts.dryRun {
+		producer, err = kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": opts.broker})
+		if err != nil {
+			panic(err)
+		}
+		defer producer.Close()
+	}
+
+	replayed := 0
+	for _, e := range entries {
+		if !opts.selected(e) {
+			continue
+		}
+		payload, contentType, err := replayPayload(e)
+		if err != nil {
+			fmt.Printf("Skipping entry %+v: %v\n", e.Source, err)
+			continue
+		}
+		if opts.dryRun {
+			fmt.Println(string(payload))
+			replayed++
+			continue
+		}
+		if err := producer.Produce(&kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &opts.topic, Partition: kafka.PartitionAny},
+			Value:          payload,
+			Headers:        []kafka.Header{{Key: codec.HeaderContentType, Value: []byte(contentType)}},
+		}, nil); err != nil {
+			panic(err)
+		}
+		replayed++
+	}
+
+	if producer != nil {
+		var timeout = 10 * time.Second
+		fmt.Println("Waiting " + timeout.String() + " until all events get produced")
+		producer.Flush(int(timeout.Milliseconds()))
+	}
+	fmt.Printf("Replayed %d of %d dead-letter entries\n", replayed, len(entries))
+}
+
+// replayPayload returns the message and its content type to replay for an entry,
+// preferring the (possibly fixed) decoded event.
+func replayPayload(e model.DeadLetterEntry) ([]byte, string, error) {
+	if e.Event != nil {
+		b, err := codec.Encode(e.Event, codec.ContentTypeJSON)
+		return b, codec.ContentTypeJSON, err
+	}
+	if len(e.Payload) == 0 {
+		return nil, "", errors.New("entry has neither event nor payload")
+	}
+	return e.Payload, e.ContentType, nil
+}
+
+func readFileEntries(fileName string) ([]model.DeadLetterEntry, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []model.DeadLetterEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e model.DeadLetterEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// readTopicEntries consumes the dead-letter topic until no new entries arrive for a while.
+func readTopicEntries(broker string, groupID string, topic string) ([]model.DeadLetterEntry, error) {
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers": broker,
+		"group.id":          groupID,
+		"auto.offset.reset": "earliest",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer consumer.Close()
+	if err := consumer.SubscribeTopics([]string{topic}, nil); err != nil {
+		return nil, err
+	}
+	const idleTimeout = 5 * time.Second
+	var entries []model.DeadLetterEntry
+	for {
+		msg, err := consumer.ReadMessage(idleTimeout)
+		if err != nil {
+			var kerr kafka.Error
+			if errors.As(err, &kerr) && kerr.Code() == kafka.ErrTimedOut {
+				return entries, nil
+			}
+			return nil, err
+		}
+		var e model.DeadLetterEntry
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			fmt.Println("Unmarshall error: " + err.Error())
+			continue
+		}
+		entries = append(entries, e)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/codec"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    options
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: options{broker: "localhost", topic: "ratings", fileName: "ratings-dlq.jsonl", groupID: "odd-service-rating-replay"},
+		},
+		{
+			name: "topic replay of persist failures",
+			args: []string{"-broker", "kafka:9092", "-dlq-topic", "ratings-dlq", "-reason", "persist", "-dry-run"},
+			want: options{broker: "kafka:9092", topic: "ratings", fileName: "ratings-dlq.jsonl", dlqTopic: "ratings-dlq",
+				groupID: "odd-service-rating-replay", reason: model.DeadLetterReasonPersist, dryRun: true},
+		},
+		{name: "unknown reason", args: []string{"-reason", "timeout"}, wantErr: true},
+		{name: "unknown flag", args: []string{"-partition", "1"}, wantErr: true},
+		{name: "positional argument", args: []string{"ratings-dlq.jsonl"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFlags(tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReplayFileEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratings-dlq.jsonl")
+	fixed := &model.RatingEvent{UserID: "105", RecordID: "1", RecordType: model.RecordTypeMovie, Value: 4, EventType: model.RatingEventTypePut}
+	written := []model.DeadLetterEntry{
+		{Payload: []byte(`{"userId":"106","recordId":"2","recordType":"movie","value":3}`), ContentType: codec.ContentTypeJSON,
+			Reason: model.DeadLetterReasonPersist, Time: time.Now().UTC()},
+		{Payload: []byte(`{"userId":"105","recordId":"1","recordType":"movie","value":40}`), Event: fixed,
+			Reason: model.DeadLetterReasonValidate, Time: time.Now().UTC()},
+		{Reason: model.DeadLetterReasonDecode, Error: "empty message", Time: time.Now().UTC()},
+	}
+	// The file sink of the rating service writes one JSON entry per line.
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	enc := json.NewEncoder(f)
+	for _, e := range written {
+		require.NoError(t, enc.Encode(e))
+	}
+	// Blank lines, e.g. left by editing the file, are skipped.
+	_, err = f.WriteString("\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	entries, err := readFileEntries(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	payload, contentType, err := replayPayload(entries[0])
+	require.NoError(t, err)
+	assert.Equal(t, written[0].Payload, payload, "the raw payload is replayed without a decoded event")
+	assert.Equal(t, codec.ContentTypeJSON, contentType)
+
+	payload, contentType, err = replayPayload(entries[1])
+	require.NoError(t, err)
+	replayed, err := codec.Decode(payload, contentType)
+	require.NoError(t, err)
+	fixed.SchemaVersion = model.CurrentSchemaVersion
+	assert.Equal(t, fixed, replayed, "the fixed event takes precedence over the payload")
+
+	_, _, err = replayPayload(entries[2])
+	assert.Error(t, err)
+
+	opts, err := parseFlags([]string{"-reason", "validate"})
+	require.NoError(t, err)
+	assert.False(t, opts.selected(entries[0]))
+	assert.True(t, opts.selected(entries[1]))
+}
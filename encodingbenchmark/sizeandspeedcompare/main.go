@@ -15,6 +15,7 @@ var metadata = &model.Metadata{
 	Title:       "The Movie 2",
 	Description: "Sequel of the legendary The Movie",
 	Director:    "Foo Bars",
+	Type:        model.RecordTypeMovie,
 }
 
 var genMetadata = &gen.Metadata{
@@ -22,6 +23,7 @@ var genMetadata = &gen.Metadata{
 	Title:       "The Movie 2",
 	Description: "Sequel of the legendary The Movie",
 	Director:    "Foo Bars",
+	RecordType:  "movie",
 }
 
 func main() {
@@ -35,15 +35,15 @@ func (m *MockmetadataRepository) EXPECT() *MockmetadataRepositoryMockRecorder {
 }
 
 // Get mocks base method
-func (m *MockmetadataRepository) Get(ctx context.Context, id string) (*model.Metadata, error) {
-	ret := m.ctrl.Call(m, "Get", ctx, id)
+func (m *MockmetadataRepository) Get(ctx context.Context, recordType model.RecordType, id string) (*model.Metadata, error) {
+	ret := m.ctrl.Call(m, "Get", ctx, recordType, id)
 	ret0, _ := ret[0].(*model.Metadata)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Get indicates an expected call of Get
-func (mr *MockmetadataRepositoryMockRecorder) Get(ctx, id interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockmetadataRepository)(nil).Get), ctx, id)
+func (mr *MockmetadataRepositoryMockRecorder) Get(ctx, recordType, id interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockmetadataRepository)(nil).Get), ctx, recordType, id)
 }
 
@@ -6,92 +6,329 @@ package repository
 
 import (
 	context "context"
+	reflect "reflect"
+
 	gomock "github.com/golang/mock/gomock"
 	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
-	reflect "reflect"
 )
 
-// MockratingRepository is a mock of ratingRepository interface
+// MockratingRepository is a mock of ratingRepository interface.
 type MockratingRepository struct {
 	ctrl     *gomock.Controller
 	recorder *MockratingRepositoryMockRecorder
 }
 
-// MockratingRepositoryMockRecorder is the mock recorder for MockratingRepository
+// MockratingRepositoryMockRecorder is the mock recorder for MockratingRepository.
 type MockratingRepositoryMockRecorder struct {
 	mock *MockratingRepository
 }
 
-// NewMockratingRepository creates a new mock instance
+// NewMockratingRepository creates a new mock instance.
 func NewMockratingRepository(ctrl *gomock.Controller) *MockratingRepository {
 	mock := &MockratingRepository{ctrl: ctrl}
 	mock.recorder = &MockratingRepositoryMockRecorder{mock}
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
+// EXPECT returns an object that allows the caller to indicate expected use.
 func (m *MockratingRepository) EXPECT() *MockratingRepositoryMockRecorder {
 	return m.recorder
 }
 
-// Get mocks base method
+// Delete mocks base method.
+func (m *MockratingRepository) Delete(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) (*model.Rating, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, recordID, recordType, userID)
+	ret0, _ := ret[0].(*model.Rating)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockratingRepositoryMockRecorder) Delete(ctx, recordID, recordType, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockratingRepository)(nil).Delete), ctx, recordID, recordType, userID)
+}
+
+// DeleteByProvider mocks base method.
+func (m *MockratingRepository) DeleteByProvider(ctx context.Context, providerID string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByProvider", ctx, providerID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteByProvider indicates an expected call of DeleteByProvider.
+func (mr *MockratingRepositoryMockRecorder) DeleteByProvider(ctx, providerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByProvider", reflect.TypeOf((*MockratingRepository)(nil).DeleteByProvider), ctx, providerID)
+}
+
+// ForEach mocks base method.
+func (m *MockratingRepository) ForEach(ctx context.Context, fn func(model.Rating) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForEach", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForEach indicates an expected call of ForEach.
+func (mr *MockratingRepositoryMockRecorder) ForEach(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForEach", reflect.TypeOf((*MockratingRepository)(nil).ForEach), ctx, fn)
+}
+
+// Get mocks base method.
 func (m *MockratingRepository) Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
+	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Get", ctx, recordID, recordType)
 	ret0, _ := ret[0].([]model.Rating)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Get indicates an expected call of Get
+// Get indicates an expected call of Get.
 func (mr *MockratingRepositoryMockRecorder) Get(ctx, recordID, recordType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockratingRepository)(nil).Get), ctx, recordID, recordType)
 }
 
-// Put mocks base method
-func (m *MockratingRepository) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
+// GetUserRating mocks base method.
+func (m *MockratingRepository) GetUserRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) (*model.Rating, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserRating", ctx, recordID, recordType, userID)
+	ret0, _ := ret[0].(*model.Rating)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserRating indicates an expected call of GetUserRating.
+func (mr *MockratingRepositoryMockRecorder) GetUserRating(ctx, recordID, recordType, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserRating", reflect.TypeOf((*MockratingRepository)(nil).GetUserRating), ctx, recordID, recordType, userID)
+}
+
+// ListByProvider mocks base method.
+func (m *MockratingRepository) ListByProvider(ctx context.Context, providerID, pageToken string, pageSize int) ([]model.Rating, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByProvider", ctx, providerID, pageToken, pageSize)
+	ret0, _ := ret[0].([]model.Rating)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListByProvider indicates an expected call of ListByProvider.
+func (mr *MockratingRepositoryMockRecorder) ListByProvider(ctx, providerID, pageToken, pageSize interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByProvider", reflect.TypeOf((*MockratingRepository)(nil).ListByProvider), ctx, providerID, pageToken, pageSize)
+}
+
+// ListUserRatings mocks base method.
+func (m *MockratingRepository) ListUserRatings(ctx context.Context, userID model.UserID, pageToken string, pageSize int) ([]model.Rating, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUserRatings", ctx, userID, pageToken, pageSize)
+	ret0, _ := ret[0].([]model.Rating)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListUserRatings indicates an expected call of ListUserRatings.
+func (mr *MockratingRepositoryMockRecorder) ListUserRatings(ctx, userID, pageToken, pageSize interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUserRatings", reflect.TypeOf((*MockratingRepository)(nil).ListUserRatings), ctx, userID, pageToken, pageSize)
+}
+
+// Put mocks base method.
+func (m *MockratingRepository) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) (*model.Rating, error) {
+	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Put", ctx, recordID, recordType, rating)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(*model.Rating)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// Put indicates an expected call of Put
+// Put indicates an expected call of Put.
 func (mr *MockratingRepositoryMockRecorder) Put(ctx, recordID, recordType, rating interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockratingRepository)(nil).Put), ctx, recordID, recordType, rating)
 }
 
-// MockratingIngester is a mock of ratingIngester interface
+// MockratingIngester is a mock of ratingIngester interface.
 type MockratingIngester struct {
 	ctrl     *gomock.Controller
 	recorder *MockratingIngesterMockRecorder
 }
 
-// MockratingIngesterMockRecorder is the mock recorder for MockratingIngester
+// MockratingIngesterMockRecorder is the mock recorder for MockratingIngester.
 type MockratingIngesterMockRecorder struct {
 	mock *MockratingIngester
 }
 
-// NewMockratingIngester creates a new mock instance
+// NewMockratingIngester creates a new mock instance.
 func NewMockratingIngester(ctrl *gomock.Controller) *MockratingIngester {
 	mock := &MockratingIngester{ctrl: ctrl}
 	mock.recorder = &MockratingIngesterMockRecorder{mock}
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
+// EXPECT returns an object that allows the caller to indicate expected use.
 func (m *MockratingIngester) EXPECT() *MockratingIngesterMockRecorder {
 	return m.recorder
 }
 
-// Ingest mocks base method
+// Commit mocks base method.
+func (m *MockratingIngester) Commit(ctx context.Context, event model.RatingEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Commit", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Commit indicates an expected call of Commit.
+func (mr *MockratingIngesterMockRecorder) Commit(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Commit", reflect.TypeOf((*MockratingIngester)(nil).Commit), ctx, event)
+}
+
+// Ingest mocks base method.
 func (m *MockratingIngester) Ingest(ctx context.Context) (chan model.RatingEvent, error) {
+	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Ingest", ctx)
 	ret0, _ := ret[0].(chan model.RatingEvent)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Ingest indicates an expected call of Ingest
+// Ingest indicates an expected call of Ingest.
 func (mr *MockratingIngesterMockRecorder) Ingest(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ingest", reflect.TypeOf((*MockratingIngester)(nil).Ingest), ctx)
 }
 
+// MockeventValidator is a mock of eventValidator interface.
+type MockeventValidator struct {
+	ctrl     *gomock.Controller
+	recorder *MockeventValidatorMockRecorder
+}
+
+// MockeventValidatorMockRecorder is the mock recorder for MockeventValidator.
+type MockeventValidatorMockRecorder struct {
+	mock *MockeventValidator
+}
+
+// NewMockeventValidator creates a new mock instance.
+func NewMockeventValidator(ctrl *gomock.Controller) *MockeventValidator {
+	mock := &MockeventValidator{ctrl: ctrl}
+	mock.recorder = &MockeventValidatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockeventValidator) EXPECT() *MockeventValidatorMockRecorder {
+	return m.recorder
+}
+
+// Validate mocks base method.
+func (m *MockeventValidator) Validate(e *model.RatingEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Validate", e)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Validate indicates an expected call of Validate.
+func (mr *MockeventValidatorMockRecorder) Validate(e interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockeventValidator)(nil).Validate), e)
+}
+
+// MockrateLimiter is a mock of rateLimiter interface.
+type MockrateLimiter struct {
+	ctrl     *gomock.Controller
+	recorder *MockrateLimiterMockRecorder
+}
+
+// MockrateLimiterMockRecorder is the mock recorder for MockrateLimiter.
+type MockrateLimiterMockRecorder struct {
+	mock *MockrateLimiter
+}
+
+// NewMockrateLimiter creates a new mock instance.
+func NewMockrateLimiter(ctrl *gomock.Controller) *MockrateLimiter {
+	mock := &MockrateLimiter{ctrl: ctrl}
+	mock.recorder = &MockrateLimiterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockrateLimiter) EXPECT() *MockrateLimiterMockRecorder {
+	return m.recorder
+}
+
+// Allow mocks base method.
+func (m *MockrateLimiter) Allow(ctx context.Context, userID, providerID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Allow", ctx, userID, providerID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Allow indicates an expected call of Allow.
+func (mr *MockrateLimiterMockRecorder) Allow(ctx, userID, providerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Allow", reflect.TypeOf((*MockrateLimiter)(nil).Allow), ctx, userID, providerID)
+}
+
+// Wait mocks base method.
+func (m *MockrateLimiter) Wait(ctx context.Context, userID, providerID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Wait", ctx, userID, providerID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Wait indicates an expected call of Wait.
+func (mr *MockrateLimiterMockRecorder) Wait(ctx, userID, providerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Wait", reflect.TypeOf((*MockrateLimiter)(nil).Wait), ctx, userID, providerID)
+}
+
+// MockdeadLetterSink is a mock of deadLetterSink interface.
+type MockdeadLetterSink struct {
+	ctrl     *gomock.Controller
+	recorder *MockdeadLetterSinkMockRecorder
+}
+
+// MockdeadLetterSinkMockRecorder is the mock recorder for MockdeadLetterSink.
+type MockdeadLetterSinkMockRecorder struct {
+	mock *MockdeadLetterSink
+}
+
+// NewMockdeadLetterSink creates a new mock instance.
+func NewMockdeadLetterSink(ctrl *gomock.Controller) *MockdeadLetterSink {
+	mock := &MockdeadLetterSink{ctrl: ctrl}
+	mock.recorder = &MockdeadLetterSinkMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockdeadLetterSink) EXPECT() *MockdeadLetterSinkMockRecorder {
+	return m.recorder
+}
+
+// Write mocks base method.
+func (m *MockdeadLetterSink) Write(ctx context.Context, entry model.DeadLetterEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Write", ctx, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Write indicates an expected call of Write.
+func (mr *MockdeadLetterSinkMockRecorder) Write(ctx, entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockdeadLetterSink)(nil).Write), ctx, entry)
+}
@@ -146,8 +146,20 @@ var MetadataService_ServiceDesc = grpc.ServiceDesc{
 }
 
 const (
-	RatingService_GetAggregatedRating_FullMethodName = "/RatingService/GetAggregatedRating"
-	RatingService_PutRating_FullMethodName           = "/RatingService/PutRating"
+	RatingService_GetAggregatedRating_FullMethodName    = "/RatingService/GetAggregatedRating"
+	RatingService_PutRating_FullMethodName              = "/RatingService/PutRating"
+	RatingService_GetRatingStats_FullMethodName         = "/RatingService/GetRatingStats"
+	RatingService_GetRatingTimeSeries_FullMethodName    = "/RatingService/GetRatingTimeSeries"
+	RatingService_ListProviderRatings_FullMethodName    = "/RatingService/ListProviderRatings"
+	RatingService_PurgeProviderRatings_FullMethodName   = "/RatingService/PurgeProviderRatings"
+	RatingService_ListTopRated_FullMethodName           = "/RatingService/ListTopRated"
+	RatingService_ListTrending_FullMethodName           = "/RatingService/ListTrending"
+	RatingService_GetUserRating_FullMethodName          = "/RatingService/GetUserRating"
+	RatingService_ListUserRatings_FullMethodName        = "/RatingService/ListUserRatings"
+	RatingService_ListQuarantinedRatings_FullMethodName = "/RatingService/ListQuarantinedRatings"
+	RatingService_ModerateRating_FullMethodName         = "/RatingService/ModerateRating"
+	RatingService_ListRecordTypes_FullMethodName        = "/RatingService/ListRecordTypes"
+	RatingService_ExportRatings_FullMethodName          = "/RatingService/ExportRatings"
 )
 
 // RatingServiceClient is the client API for RatingService service.
@@ -156,6 +168,18 @@ const (
 type RatingServiceClient interface {
 	GetAggregatedRating(ctx context.Context, in *GetAggregatedRatingRequest, opts ...grpc.CallOption) (*GetAggregatedRatingResponse, error)
 	PutRating(ctx context.Context, in *PutRatingRequest, opts ...grpc.CallOption) (*PutRatingResponse, error)
+	GetRatingStats(ctx context.Context, in *GetRatingStatsRequest, opts ...grpc.CallOption) (*GetRatingStatsResponse, error)
+	GetRatingTimeSeries(ctx context.Context, in *GetRatingTimeSeriesRequest, opts ...grpc.CallOption) (*GetRatingTimeSeriesResponse, error)
+	ListProviderRatings(ctx context.Context, in *ListProviderRatingsRequest, opts ...grpc.CallOption) (*ListProviderRatingsResponse, error)
+	PurgeProviderRatings(ctx context.Context, in *PurgeProviderRatingsRequest, opts ...grpc.CallOption) (*PurgeProviderRatingsResponse, error)
+	ListTopRated(ctx context.Context, in *ListTopRatedRequest, opts ...grpc.CallOption) (*ListTopRatedResponse, error)
+	ListTrending(ctx context.Context, in *ListTrendingRequest, opts ...grpc.CallOption) (*ListTrendingResponse, error)
+	GetUserRating(ctx context.Context, in *GetUserRatingRequest, opts ...grpc.CallOption) (*GetUserRatingResponse, error)
+	ListUserRatings(ctx context.Context, in *ListUserRatingsRequest, opts ...grpc.CallOption) (*ListUserRatingsResponse, error)
+	ListQuarantinedRatings(ctx context.Context, in *ListQuarantinedRatingsRequest, opts ...grpc.CallOption) (*ListQuarantinedRatingsResponse, error)
+	ModerateRating(ctx context.Context, in *ModerateRatingRequest, opts ...grpc.CallOption) (*ModerateRatingResponse, error)
+	ListRecordTypes(ctx context.Context, in *ListRecordTypesRequest, opts ...grpc.CallOption) (*ListRecordTypesResponse, error)
+	ExportRatings(ctx context.Context, in *ExportRatingsRequest, opts ...grpc.CallOption) (RatingService_ExportRatingsClient, error)
 }
 
 type ratingServiceClient struct {
@@ -184,12 +208,155 @@ func (c *ratingServiceClient) PutRating(ctx context.Context, in *PutRatingReques
 	return out, nil
 }
 
+func (c *ratingServiceClient) GetRatingStats(ctx context.Context, in *GetRatingStatsRequest, opts ...grpc.CallOption) (*GetRatingStatsResponse, error) {
+	out := new(GetRatingStatsResponse)
+	err := c.cc.Invoke(ctx, RatingService_GetRatingStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratingServiceClient) GetRatingTimeSeries(ctx context.Context, in *GetRatingTimeSeriesRequest, opts ...grpc.CallOption) (*GetRatingTimeSeriesResponse, error) {
+	out := new(GetRatingTimeSeriesResponse)
+	err := c.cc.Invoke(ctx, RatingService_GetRatingTimeSeries_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratingServiceClient) ListProviderRatings(ctx context.Context, in *ListProviderRatingsRequest, opts ...grpc.CallOption) (*ListProviderRatingsResponse, error) {
+	out := new(ListProviderRatingsResponse)
+	err := c.cc.Invoke(ctx, RatingService_ListProviderRatings_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratingServiceClient) PurgeProviderRatings(ctx context.Context, in *PurgeProviderRatingsRequest, opts ...grpc.CallOption) (*PurgeProviderRatingsResponse, error) {
+	out := new(PurgeProviderRatingsResponse)
+	err := c.cc.Invoke(ctx, RatingService_PurgeProviderRatings_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratingServiceClient) ListTopRated(ctx context.Context, in *ListTopRatedRequest, opts ...grpc.CallOption) (*ListTopRatedResponse, error) {
+	out := new(ListTopRatedResponse)
+	err := c.cc.Invoke(ctx, RatingService_ListTopRated_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratingServiceClient) ListTrending(ctx context.Context, in *ListTrendingRequest, opts ...grpc.CallOption) (*ListTrendingResponse, error) {
+	out := new(ListTrendingResponse)
+	err := c.cc.Invoke(ctx, RatingService_ListTrending_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratingServiceClient) GetUserRating(ctx context.Context, in *GetUserRatingRequest, opts ...grpc.CallOption) (*GetUserRatingResponse, error) {
+	out := new(GetUserRatingResponse)
+	err := c.cc.Invoke(ctx, RatingService_GetUserRating_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratingServiceClient) ListUserRatings(ctx context.Context, in *ListUserRatingsRequest, opts ...grpc.CallOption) (*ListUserRatingsResponse, error) {
+	out := new(ListUserRatingsResponse)
+	err := c.cc.Invoke(ctx, RatingService_ListUserRatings_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratingServiceClient) ListQuarantinedRatings(ctx context.Context, in *ListQuarantinedRatingsRequest, opts ...grpc.CallOption) (*ListQuarantinedRatingsResponse, error) {
+	out := new(ListQuarantinedRatingsResponse)
+	err := c.cc.Invoke(ctx, RatingService_ListQuarantinedRatings_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratingServiceClient) ModerateRating(ctx context.Context, in *ModerateRatingRequest, opts ...grpc.CallOption) (*ModerateRatingResponse, error) {
+	out := new(ModerateRatingResponse)
+	err := c.cc.Invoke(ctx, RatingService_ModerateRating_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratingServiceClient) ListRecordTypes(ctx context.Context, in *ListRecordTypesRequest, opts ...grpc.CallOption) (*ListRecordTypesResponse, error) {
+	out := new(ListRecordTypesResponse)
+	err := c.cc.Invoke(ctx, RatingService_ListRecordTypes_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratingServiceClient) ExportRatings(ctx context.Context, in *ExportRatingsRequest, opts ...grpc.CallOption) (RatingService_ExportRatingsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &RatingService_ServiceDesc.Streams[0], RatingService_ExportRatings_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &ratingServiceExportRatingsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RatingService_ExportRatingsClient interface {
+	Recv() (*ExportRatingsResponse, error)
+	grpc.ClientStream
+}
+
+type ratingServiceExportRatingsClient struct {
+	grpc.ClientStream
+}
+
+func (x *ratingServiceExportRatingsClient) Recv() (*ExportRatingsResponse, error) {
+	m := new(ExportRatingsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // RatingServiceServer is the server API for RatingService service.
 // All implementations must embed UnimplementedRatingServiceServer
 // for forward compatibility
 type RatingServiceServer interface {
 	GetAggregatedRating(context.Context, *GetAggregatedRatingRequest) (*GetAggregatedRatingResponse, error)
 	PutRating(context.Context, *PutRatingRequest) (*PutRatingResponse, error)
+	GetRatingStats(context.Context, *GetRatingStatsRequest) (*GetRatingStatsResponse, error)
+	GetRatingTimeSeries(context.Context, *GetRatingTimeSeriesRequest) (*GetRatingTimeSeriesResponse, error)
+	ListProviderRatings(context.Context, *ListProviderRatingsRequest) (*ListProviderRatingsResponse, error)
+	PurgeProviderRatings(context.Context, *PurgeProviderRatingsRequest) (*PurgeProviderRatingsResponse, error)
+	ListTopRated(context.Context, *ListTopRatedRequest) (*ListTopRatedResponse, error)
+	ListTrending(context.Context, *ListTrendingRequest) (*ListTrendingResponse, error)
+	GetUserRating(context.Context, *GetUserRatingRequest) (*GetUserRatingResponse, error)
+	ListUserRatings(context.Context, *ListUserRatingsRequest) (*ListUserRatingsResponse, error)
+	ListQuarantinedRatings(context.Context, *ListQuarantinedRatingsRequest) (*ListQuarantinedRatingsResponse, error)
+	ModerateRating(context.Context, *ModerateRatingRequest) (*ModerateRatingResponse, error)
+	ListRecordTypes(context.Context, *ListRecordTypesRequest) (*ListRecordTypesResponse, error)
+	ExportRatings(*ExportRatingsRequest, RatingService_ExportRatingsServer) error
 	mustEmbedUnimplementedRatingServiceServer()
 }
 
@@ -203,6 +370,42 @@ func (UnimplementedRatingServiceServer) GetAggregatedRating(context.Context, *Ge
 func (UnimplementedRatingServiceServer) PutRating(context.Context, *PutRatingRequest) (*PutRatingResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PutRating not implemented")
 }
+func (UnimplementedRatingServiceServer) GetRatingStats(context.Context, *GetRatingStatsRequest) (*GetRatingStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRatingStats not implemented")
+}
+func (UnimplementedRatingServiceServer) GetRatingTimeSeries(context.Context, *GetRatingTimeSeriesRequest) (*GetRatingTimeSeriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRatingTimeSeries not implemented")
+}
+func (UnimplementedRatingServiceServer) ListProviderRatings(context.Context, *ListProviderRatingsRequest) (*ListProviderRatingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListProviderRatings not implemented")
+}
+func (UnimplementedRatingServiceServer) PurgeProviderRatings(context.Context, *PurgeProviderRatingsRequest) (*PurgeProviderRatingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeProviderRatings not implemented")
+}
+func (UnimplementedRatingServiceServer) ListTopRated(context.Context, *ListTopRatedRequest) (*ListTopRatedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTopRated not implemented")
+}
+func (UnimplementedRatingServiceServer) ListTrending(context.Context, *ListTrendingRequest) (*ListTrendingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTrending not implemented")
+}
+func (UnimplementedRatingServiceServer) GetUserRating(context.Context, *GetUserRatingRequest) (*GetUserRatingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserRating not implemented")
+}
+func (UnimplementedRatingServiceServer) ListUserRatings(context.Context, *ListUserRatingsRequest) (*ListUserRatingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUserRatings not implemented")
+}
+func (UnimplementedRatingServiceServer) ListQuarantinedRatings(context.Context, *ListQuarantinedRatingsRequest) (*ListQuarantinedRatingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListQuarantinedRatings not implemented")
+}
+func (UnimplementedRatingServiceServer) ModerateRating(context.Context, *ModerateRatingRequest) (*ModerateRatingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ModerateRating not implemented")
+}
+func (UnimplementedRatingServiceServer) ListRecordTypes(context.Context, *ListRecordTypesRequest) (*ListRecordTypesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRecordTypes not implemented")
+}
+func (UnimplementedRatingServiceServer) ExportRatings(*ExportRatingsRequest, RatingService_ExportRatingsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExportRatings not implemented")
+}
 func (UnimplementedRatingServiceServer) mustEmbedUnimplementedRatingServiceServer() {}
 
 // UnsafeRatingServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -252,6 +455,225 @@ func _RatingService_PutRating_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RatingService_GetRatingStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRatingStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).GetRatingStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_GetRatingStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).GetRatingStats(ctx, req.(*GetRatingStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_GetRatingTimeSeries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRatingTimeSeriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).GetRatingTimeSeries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_GetRatingTimeSeries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).GetRatingTimeSeries(ctx, req.(*GetRatingTimeSeriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_ListProviderRatings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListProviderRatingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).ListProviderRatings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_ListProviderRatings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).ListProviderRatings(ctx, req.(*ListProviderRatingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_PurgeProviderRatings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeProviderRatingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).PurgeProviderRatings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_PurgeProviderRatings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).PurgeProviderRatings(ctx, req.(*PurgeProviderRatingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_ListTopRated_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTopRatedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).ListTopRated(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_ListTopRated_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).ListTopRated(ctx, req.(*ListTopRatedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_ListTrending_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTrendingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).ListTrending(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_ListTrending_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).ListTrending(ctx, req.(*ListTrendingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_GetUserRating_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRatingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).GetUserRating(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_GetUserRating_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).GetUserRating(ctx, req.(*GetUserRatingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_ListUserRatings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUserRatingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).ListUserRatings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_ListUserRatings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).ListUserRatings(ctx, req.(*ListUserRatingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_ListQuarantinedRatings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListQuarantinedRatingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).ListQuarantinedRatings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_ListQuarantinedRatings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).ListQuarantinedRatings(ctx, req.(*ListQuarantinedRatingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_ModerateRating_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerateRatingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).ModerateRating(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_ModerateRating_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).ModerateRating(ctx, req.(*ModerateRatingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_ListRecordTypes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRecordTypesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatingServiceServer).ListRecordTypes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatingService_ListRecordTypes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatingServiceServer).ListRecordTypes(ctx, req.(*ListRecordTypesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatingService_ExportRatings_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRatingsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RatingServiceServer).ExportRatings(m, &ratingServiceExportRatingsServer{stream})
+}
+
+type RatingService_ExportRatingsServer interface {
+	Send(*ExportRatingsResponse) error
+	grpc.ServerStream
+}
+
+type ratingServiceExportRatingsServer struct {
+	grpc.ServerStream
+}
+
+func (x *ratingServiceExportRatingsServer) Send(m *ExportRatingsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // RatingService_ServiceDesc is the grpc.ServiceDesc for RatingService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -267,13 +689,65 @@ var RatingService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PutRating",
 			Handler:    _RatingService_PutRating_Handler,
 		},
+		{
+			MethodName: "GetRatingStats",
+			Handler:    _RatingService_GetRatingStats_Handler,
+		},
+		{
+			MethodName: "GetRatingTimeSeries",
+			Handler:    _RatingService_GetRatingTimeSeries_Handler,
+		},
+		{
+			MethodName: "ListProviderRatings",
+			Handler:    _RatingService_ListProviderRatings_Handler,
+		},
+		{
+			MethodName: "PurgeProviderRatings",
+			Handler:    _RatingService_PurgeProviderRatings_Handler,
+		},
+		{
+			MethodName: "ListTopRated",
+			Handler:    _RatingService_ListTopRated_Handler,
+		},
+		{
+			MethodName: "ListTrending",
+			Handler:    _RatingService_ListTrending_Handler,
+		},
+		{
+			MethodName: "GetUserRating",
+			Handler:    _RatingService_GetUserRating_Handler,
+		},
+		{
+			MethodName: "ListUserRatings",
+			Handler:    _RatingService_ListUserRatings_Handler,
+		},
+		{
+			MethodName: "ListQuarantinedRatings",
+			Handler:    _RatingService_ListQuarantinedRatings_Handler,
+		},
+		{
+			MethodName: "ModerateRating",
+			Handler:    _RatingService_ModerateRating_Handler,
+		},
+		{
+			MethodName: "ListRecordTypes",
+			Handler:    _RatingService_ListRecordTypes_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportRatings",
+			Handler:       _RatingService_ExportRatings_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "movie.proto",
 }
 
 const (
-	MovieService_GetMovieDetails_FullMethodName = "/MovieService/GetMovieDetails"
+	MovieService_GetMovieDetails_FullMethodName    = "/MovieService/GetMovieDetails"
+	MovieService_ListTopRatedMovies_FullMethodName = "/MovieService/ListTopRatedMovies"
+	MovieService_ListTrendingMovies_FullMethodName = "/MovieService/ListTrendingMovies"
 )
 
 // MovieServiceClient is the client API for MovieService service.
@@ -281,6 +755,8 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type MovieServiceClient interface {
 	GetMovieDetails(ctx context.Context, in *GetMovieDetailsRequest, opts ...grpc.CallOption) (*GetMovieDetailsResponse, error)
+	ListTopRatedMovies(ctx context.Context, in *ListTopRatedMoviesRequest, opts ...grpc.CallOption) (*ListTopRatedMoviesResponse, error)
+	ListTrendingMovies(ctx context.Context, in *ListTrendingMoviesRequest, opts ...grpc.CallOption) (*ListTrendingMoviesResponse, error)
 }
 
 type movieServiceClient struct {
@@ -300,11 +776,31 @@ func (c *movieServiceClient) GetMovieDetails(ctx context.Context, in *GetMovieDe
 	return out, nil
 }
 
+func (c *movieServiceClient) ListTopRatedMovies(ctx context.Context, in *ListTopRatedMoviesRequest, opts ...grpc.CallOption) (*ListTopRatedMoviesResponse, error) {
+	out := new(ListTopRatedMoviesResponse)
+	err := c.cc.Invoke(ctx, MovieService_ListTopRatedMovies_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *movieServiceClient) ListTrendingMovies(ctx context.Context, in *ListTrendingMoviesRequest, opts ...grpc.CallOption) (*ListTrendingMoviesResponse, error) {
+	out := new(ListTrendingMoviesResponse)
+	err := c.cc.Invoke(ctx, MovieService_ListTrendingMovies_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MovieServiceServer is the server API for MovieService service.
 // All implementations must embed UnimplementedMovieServiceServer
 // for forward compatibility
 type MovieServiceServer interface {
 	GetMovieDetails(context.Context, *GetMovieDetailsRequest) (*GetMovieDetailsResponse, error)
+	ListTopRatedMovies(context.Context, *ListTopRatedMoviesRequest) (*ListTopRatedMoviesResponse, error)
+	ListTrendingMovies(context.Context, *ListTrendingMoviesRequest) (*ListTrendingMoviesResponse, error)
 	mustEmbedUnimplementedMovieServiceServer()
 }
 
@@ -315,6 +811,12 @@ type UnimplementedMovieServiceServer struct {
 func (UnimplementedMovieServiceServer) GetMovieDetails(context.Context, *GetMovieDetailsRequest) (*GetMovieDetailsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetMovieDetails not implemented")
 }
+func (UnimplementedMovieServiceServer) ListTopRatedMovies(context.Context, *ListTopRatedMoviesRequest) (*ListTopRatedMoviesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTopRatedMovies not implemented")
+}
+func (UnimplementedMovieServiceServer) ListTrendingMovies(context.Context, *ListTrendingMoviesRequest) (*ListTrendingMoviesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTrendingMovies not implemented")
+}
 func (UnimplementedMovieServiceServer) mustEmbedUnimplementedMovieServiceServer() {}
 
 // UnsafeMovieServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -346,6 +848,42 @@ func _MovieService_GetMovieDetails_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MovieService_ListTopRatedMovies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTopRatedMoviesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieServiceServer).ListTopRatedMovies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MovieService_ListTopRatedMovies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieServiceServer).ListTopRatedMovies(ctx, req.(*ListTopRatedMoviesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MovieService_ListTrendingMovies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTrendingMoviesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieServiceServer).ListTrendingMovies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MovieService_ListTrendingMovies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieServiceServer).ListTrendingMovies(ctx, req.(*ListTrendingMoviesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MovieService_ServiceDesc is the grpc.ServiceDesc for MovieService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -357,6 +895,14 @@ var MovieService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetMovieDetails",
 			Handler:    _MovieService_GetMovieDetails_Handler,
 		},
+		{
+			MethodName: "ListTopRatedMovies",
+			Handler:    _MovieService_ListTopRatedMovies_Handler,
+		},
+		{
+			MethodName: "ListTrendingMovies",
+			Handler:    _MovieService_ListTrendingMovies_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "movie.proto",
@@ -2,21 +2,50 @@ package grpcutil
 
 import (
 	"context"
+	"fmt"
 	"math/rand"
+	"time"
 
 	"github.com/ugurcancaykara/odd-service/pkg/discovery"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
+	"github.com/ugurcancaykara/odd-service/pkg/metrics"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
-// ServiceConnection attempts to select a random service
-// instance and returns a gRPC connection to it.
-
 // ServiceConnection attempts to select a random service instance and returns a gRPC connection to it.
 func ServiceConnection(ctx context.Context, serviceName string, registry discovery.Registry) (*grpc.ClientConn, error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "registry.ServiceAddresses", attribute.String("service", serviceName))
 	addrs, err := registry.ServiceAddresses(ctx, serviceName)
+	tracing.End(span, err)
+	metrics.ObserveRegistryLookup(serviceName, start, err)
 	if err != nil {
 		return nil, err
 	}
-	return grpc.Dial(addrs[rand.Intn(len(addrs))], grpc.WithTransportCredentials(insecure.NewCredentials()))
+	return grpc.Dial(addrs[rand.Intn(len(addrs))],
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(logging.UnaryClientInterceptor(), metrics.UnaryClientInterceptor()),
+		tracing.DialOption(),
+	)
+}
+
+// CheckHealth checks that an instance of a service is reachable and serving, using the gRPC health protocol.
+func CheckHealth(ctx context.Context, serviceName string, registry discovery.Registry) error {
+	conn, err := ServiceConnection(ctx, serviceName, registry)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("%s service is %v", serviceName, resp.Status)
+	}
+	return nil
 }
@@ -1,9 +1,42 @@
 package main
 
+import (
+	"time"
+
+	"github.com/ugurcancaykara/odd-service/pkg/health"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
+	"github.com/ugurcancaykara/odd-service/pkg/service"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
+)
+
 type serviceConfig struct {
-	API apiConfig `yaml:"api"`
+	API       service.APIConfig       `yaml:"api"`
+	Advertise service.AdvertiseConfig `yaml:"advertise"`
+	HTTP      service.ListenConfig    `yaml:"http"`
+	Admin     service.ListenConfig    `yaml:"admin"`
+	Registry  service.RegistryConfig  `yaml:"registry"`
+	Health    health.Config           `yaml:"health"`
+	Logging   logging.Config          `yaml:"logging"`
+	Tracing   tracing.Config          `yaml:"tracing"`
+	MySQL     mysqlConfig             `yaml:"mysql"`
+
+	// ReloadInterval defines how often the config is reloaded, zero disables reloading.
+	// No setting of the metadata service is applied at runtime yet.
+	ReloadInterval time.Duration `yaml:"reloadInterval"`
+	// ShutdownTimeout defines how long in-flight requests may take to drain on shutdown.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+}
+
+// Validate checks the settings required by the selected registry.
+func (c *serviceConfig) Validate() error {
+	return c.Registry.Validate()
+}
+
+// runnerConfig returns the config of the runner of the service.
+func (c *serviceConfig) runnerConfig() service.Config {
+	return service.Config{API: c.API, Advertise: c.Advertise, HTTP: c.HTTP, Admin: c.Admin, Health: c.Health, ShutdownTimeout: c.ShutdownTimeout}
 }
 
-type apiConfig struct {
-	Port string `yaml:"port"`
+type mysqlConfig struct {
+	DSN string `yaml:"dsn" validate:"required"`
 }
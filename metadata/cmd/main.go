@@ -2,90 +2,65 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
-	"net"
+	"log/slog"
+	"net/http"
 	"os"
-	"os/signal"
-	"sync"
-	"syscall"
-	"time"
 
 	"github.com/ugurcancaykara/odd-service/gen"
 	"github.com/ugurcancaykara/odd-service/metadata/internal/controller/metadata"
 	grpchandler "github.com/ugurcancaykara/odd-service/metadata/internal/handler/grpc"
+	httphandler "github.com/ugurcancaykara/odd-service/metadata/internal/handler/http"
 	"github.com/ugurcancaykara/odd-service/metadata/internal/repository/mysql"
-	"github.com/ugurcancaykara/odd-service/pkg/discovery"
-	"github.com/ugurcancaykara/odd-service/pkg/discovery/consul"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
-	"gopkg.in/yaml.v3"
+	"github.com/ugurcancaykara/odd-service/pkg/config"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
+	"github.com/ugurcancaykara/odd-service/pkg/service"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
 )
 
 const serviceName = "metadata"
 
 func main() {
-
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		configPath = "configs/base.yaml"
-	}
-	f, err := os.Open(configPath)
+	watcher, err := config.Watch[serviceConfig](config.NewLoader(serviceName), os.Args[1:])
 	if err != nil {
-		panic(err)
+		slog.Error("Failed to load config", "error", err)
+		os.Exit(1)
 	}
-	defer f.Close()
-	var cfg serviceConfig
-	if err = yaml.NewDecoder(f).Decode(&cfg); err != nil {
-		panic(err)
+	cfg := watcher.Config()
+	logger, err := logging.New(cfg.Logging, os.Stderr)
+	if err != nil {
+		slog.Error("Failed to set up logging", "error", err)
+		os.Exit(1)
 	}
-	port := cfg.API.Port
-	log.Printf("Starting the metadata service on port %v", port)
-	registry, err := consul.NewRegistry("localhost:8500")
+	slog.SetDefault(logger)
+	shutdownTracing, err := tracing.Init(context.Background(), serviceName, cfg.Tracing)
 	if err != nil {
-		panic(err)
+		logger.Error("Failed to set up tracing", "error", err)
+		os.Exit(1)
 	}
-	ctx, cancel := context.WithCancel(context.Background())
-
-	instanceID := discovery.GenerateInstanceID(serviceName)
-	if err := registry.Register(ctx, instanceID, serviceName, fmt.Sprintf("localhost:%s", port)); err != nil {
+	registry, err := service.NewRegistry(cfg.Registry, logger)
+	if err != nil {
 		panic(err)
 	}
-	go func() {
-		for {
-			if err := registry.ReportHealthyState(instanceID, serviceName); err != nil {
-				log.Println("Failed to report healthy state: " + err.Error())
-			}
-			time.Sleep(1 * time.Second)
-		}
-	}()
-	defer registry.Deregister(ctx, instanceID, serviceName)
-	repo, err := mysql.New()
+	repo, err := mysql.New(cfg.MySQL.DSN)
 	if err != nil {
 		panic(err)
 	}
-	ctrl := metadata.New(repo)
-	h := grpchandler.New(ctrl)
-	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%s", port))
-	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+	ctrl := metadata.New(repo, logger)
+
+	svc := service.New(serviceName, cfg.runnerConfig(), registry, logger)
+	svc.OnShutdown("tracing", shutdownTracing)
+	svc.Health().Add("mysql", repo.Ping)
+	gen.RegisterMetadataServiceServer(svc.GRPCServer(), grpchandler.New(ctrl))
+	svc.HandleHTTP("/metadata", http.HandlerFunc(httphandler.New(ctrl).GetMetadata))
+	svc.HandleAdmin("/config", watcher)
+	if interval := cfg.ReloadInterval; interval > 0 {
+		svc.Go("config reload", func(ctx context.Context) error {
+			watcher.Run(ctx, interval)
+			return nil
+		})
 	}
-	srv := grpc.NewServer()
-	reflection.Register(srv)
-	gen.RegisterMetadataServiceServer(srv, h)
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGKILL)
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		s := <-sigChan
-		cancel()
-		log.Printf("Received signal %v, attempting graceful shutdown...", s)
-		srv.GracefulStop()
-		log.Println("Gracefully stopped gRPC server")
-	}()
-	if err := srv.Serve(lis); err != nil {
-		panic(err)
+	if err := svc.Run(context.Background()); err != nil {
+		logger.Error("Metadata service stopped", "error", err)
+		os.Exit(1)
 	}
 }
@@ -3,44 +3,79 @@ package metadata
 import (
 	"context"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 
 	"github.com/ugurcancaykara/odd-service/metadata/internal/repository"
 	model "github.com/ugurcancaykara/odd-service/metadata/pkg/model"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
 )
 
 // ErrNotFound is returned when a requested record is not found.
 var ErrNotFound = errors.New("not found")
 
+// ErrUnsupportedRecordType is returned when a record type is not supported by the metadata service.
+var ErrUnsupportedRecordType = errors.New("unsupported record type")
+
+// ErrInvalidMetadata is returned when metadata lacks the fields required by its record type.
+var ErrInvalidMetadata = errors.New("invalid metadata")
+
 type metadataRepository interface {
-	Get(ctx context.Context, id string) (*model.Metadata, error)
+	Get(ctx context.Context, recordType model.RecordType, id string) (*model.Metadata, error)
 	Put(ctx context.Context, id string, m *model.Metadata) error
 }
 
 // Controller defines a metadata service controller.
 type Controller struct {
-	repo metadataRepository
+	repo   metadataRepository
+	logger *slog.Logger
 }
 
-// New creates a metadata service controller.
-func New(repo metadataRepository) *Controller {
-	return &Controller{repo}
-
+// New creates a metadata service controller. A nil logger logs to slog.Default().
+func New(repo metadataRepository, logger *slog.Logger) *Controller {
+	return &Controller{repo, logging.OrDefault(logger)}
 }
 
-// Get returns movie metadata by id
-func (c *Controller) Get(ctx context.Context, id string) (*model.Metadata, error) {
-	res, err := c.repo.Get(ctx, id)
+// Get returns the metadata of a record by type and id, an empty type selects movies.
+func (c *Controller) Get(ctx context.Context, recordType model.RecordType, id string) (*model.Metadata, error) {
+	if recordType == "" {
+		recordType = model.RecordTypeMovie
+	}
+	if !recordType.Supported() {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedRecordType, recordType)
+	}
+	res, err := c.repo.Get(ctx, recordType, id)
 	if err != nil && errors.Is(err, repository.ErrNotFound) {
 		return nil, ErrNotFound
 	} else if err != nil {
-		log.Printf("Failed to get metadata for %v: %v", id, err)
-
+		c.logger.ErrorContext(ctx, "Failed to get metadata", "record_type", recordType, "id", id, "error", err)
 	}
 	return res, err
 }
 
-// Put writes movie metadata to repository.
+// Put writes record metadata to repository, an empty type is stored as a movie.
 func (c *Controller) Put(ctx context.Context, m *model.Metadata) error {
+	if m.Type == "" {
+		m.Type = model.RecordTypeMovie
+	}
+	if err := validate(m); err != nil {
+		return err
+	}
 	return c.repo.Put(ctx, m.ID, m)
 }
+
+// validate checks that metadata only holds the fields of its record type.
+func validate(m *model.Metadata) error {
+	if !m.Type.Supported() {
+		return fmt.Errorf("%w: %q", ErrUnsupportedRecordType, m.Type)
+	}
+	switch {
+	case m.Type != model.RecordTypeSeries && m.Series != nil:
+		return fmt.Errorf("%w: series details on %v metadata", ErrInvalidMetadata, m.Type)
+	case m.Type != model.RecordTypeEpisode && m.Episode != nil:
+		return fmt.Errorf("%w: episode details on %v metadata", ErrInvalidMetadata, m.Type)
+	case m.Type == model.RecordTypeEpisode && (m.Episode == nil || m.Episode.SeriesID == ""):
+		return fmt.Errorf("%w: episode without series id", ErrInvalidMetadata)
+	}
+	return nil
+}
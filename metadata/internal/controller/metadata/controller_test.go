@@ -0,0 +1,91 @@
+package metadata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/metadata/internal/repository/memory"
+	model "github.com/ugurcancaykara/odd-service/metadata/pkg/model"
+)
+
+func TestController_Put(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata *model.Metadata
+		wantType model.RecordType
+		wantErr  error
+	}{
+		{
+			name:     "movie by default",
+			metadata: &model.Metadata{ID: "1", Title: "The Movie", Director: "Mr. D"},
+			wantType: model.RecordTypeMovie,
+		},
+		{
+			name:     "series",
+			metadata: &model.Metadata{ID: "1", Type: model.RecordTypeSeries, Series: &model.SeriesDetails{Creator: "Mr. D", Seasons: 2, FirstYear: 2020}},
+			wantType: model.RecordTypeSeries,
+		},
+		{
+			name:     "episode",
+			metadata: &model.Metadata{ID: "1", Type: model.RecordTypeEpisode, Episode: &model.EpisodeDetails{SeriesID: "1", Season: 1, Number: 3}},
+			wantType: model.RecordTypeEpisode,
+		},
+		{
+			name:     "unsupported record type",
+			metadata: &model.Metadata{ID: "1", Type: "book"},
+			wantErr:  ErrUnsupportedRecordType,
+		},
+		{
+			name:     "series details on a movie",
+			metadata: &model.Metadata{ID: "1", Series: &model.SeriesDetails{Seasons: 1}},
+			wantErr:  ErrInvalidMetadata,
+		},
+		{
+			name:     "episode details on a series",
+			metadata: &model.Metadata{ID: "1", Type: model.RecordTypeSeries, Episode: &model.EpisodeDetails{SeriesID: "1"}},
+			wantErr:  ErrInvalidMetadata,
+		},
+		{
+			name:     "episode without details",
+			metadata: &model.Metadata{ID: "1", Type: model.RecordTypeEpisode},
+			wantErr:  ErrInvalidMetadata,
+		},
+		{
+			name:     "episode without series id",
+			metadata: &model.Metadata{ID: "1", Type: model.RecordTypeEpisode, Episode: &model.EpisodeDetails{Season: 1, Number: 1}},
+			wantErr:  ErrInvalidMetadata,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := New(memory.New(), nil)
+			err := c.Put(ctx, tt.metadata)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			got, err := c.Get(ctx, tt.wantType, tt.metadata.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.metadata, got)
+		})
+	}
+}
+
+func TestController_Get(t *testing.T) {
+	ctx := context.Background()
+	c := New(memory.New(), nil)
+	movie := &model.Metadata{ID: "1", Type: model.RecordTypeMovie, Title: "The Movie"}
+	require.NoError(t, c.Put(ctx, movie))
+
+	got, err := c.Get(ctx, "", "1")
+	require.NoError(t, err)
+	assert.Equal(t, movie, got, "an empty record type selects movies")
+	_, err = c.Get(ctx, model.RecordTypeSeries, "1")
+	assert.ErrorIs(t, err, ErrNotFound, "records of different types don't share ids")
+	_, err = c.Get(ctx, "book", "1")
+	assert.ErrorIs(t, err, ErrUnsupportedRecordType)
+}
@@ -22,26 +22,31 @@ func New(ctrl *metadata.Controller) *Handler {
 	return &Handler{ctrl: ctrl}
 }
 
-// GetMetadata returns movie metadata.
+// GetMetadata returns record metadata, movie metadata unless a record type is set.
 func (h *Handler) GetMetadata(ctx context.Context, req *gen.GetMetadataRequest) (*gen.GetMetadataResponse, error) {
 	if req == nil || req.MovieId == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
 	}
-	m, err := h.ctrl.Get(ctx, req.MovieId)
+	m, err := h.ctrl.Get(ctx, model.RecordType(req.RecordType), req.MovieId)
 	if err != nil && errors.Is(err, metadata.ErrNotFound) {
 		return nil, status.Errorf(codes.NotFound, err.Error())
+	} else if err != nil && errors.Is(err, metadata.ErrUnsupportedRecordType) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
 	} else if err != nil {
 		return nil, status.Errorf(codes.Internal, err.Error())
 	}
 	return &gen.GetMetadataResponse{Metadata: model.MetadataToProto(m)}, nil
 }
 
-// PutMetadata puts movie metadata to repository.
+// PutMetadata puts record metadata to repository.
 func (h *Handler) PutMetadata(ctx context.Context, req *gen.PutMetadataRequest) (*gen.PutMetadataResponse, error) {
 	if req == nil || req.Metadata == nil {
 		return nil, status.Errorf(codes.InvalidArgument, "nil req or metadata")
 	}
-	if err := h.ctrl.Put(ctx, model.MetadataFromProto(req.Metadata)); err != nil {
+	err := h.ctrl.Put(ctx, model.MetadataFromProto(req.Metadata))
+	if err != nil && (errors.Is(err, metadata.ErrUnsupportedRecordType) || errors.Is(err, metadata.ErrInvalidMetadata)) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	} else if err != nil {
 		return nil, status.Errorf(codes.Internal, err.Error())
 	}
 	return &gen.PutMetadataResponse{}, nil
@@ -3,11 +3,11 @@ package http
 import (
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 
 	metadata "github.com/ugurcancaykara/odd-service/metadata/internal/controller/metadata"
-	"github.com/ugurcancaykara/odd-service/metadata/internal/repository"
+	model "github.com/ugurcancaykara/odd-service/metadata/pkg/model"
 )
 
 // Handler defines a movie metadata HTTP handler.
@@ -20,7 +20,7 @@ func New(ctrl *metadata.Controller) *Handler {
 	return &Handler{ctrl}
 }
 
-// GetMetadata handles GET /metadata requests
+// GetMetadata handles GET /metadata requests, the optional type parameter selects the record type.
 func (h *Handler) GetMetadata(w http.ResponseWriter, r *http.Request) {
 	id := r.FormValue("id")
 	if id == "" {
@@ -29,17 +29,20 @@ func (h *Handler) GetMetadata(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	m, err := h.ctrl.Get(ctx, id)
-	if err != nil && errors.Is(err, repository.ErrNotFound) {
+	m, err := h.ctrl.Get(ctx, model.RecordType(r.FormValue("type")), id)
+	if err != nil && errors.Is(err, metadata.ErrNotFound) {
 		w.WriteHeader(http.StatusNotFound)
 		return
+	} else if err != nil && errors.Is(err, metadata.ErrUnsupportedRecordType) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
 	} else if err != nil {
-		log.Printf("Repository get error: %v\n", err)
+		slog.ErrorContext(ctx, "Failed to get metadata", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	if err := json.NewEncoder(w).Encode(m); err != nil {
-		log.Printf("Response encode error :%\n", err)
+		slog.ErrorContext(ctx, "Failed to encode response", "error", err)
 	}
 }
@@ -8,35 +8,40 @@ import (
 	model "github.com/ugurcancaykara/odd-service/metadata/pkg/model"
 )
 
-// Repository defines a memory movie metadata repository
+type key struct {
+	recordType model.RecordType
+	id         string
+}
+
+// Repository defines a memory record metadata repository
 type Repository struct {
 	sync.RWMutex
-	data map[string]*model.Metadata
+	data map[key]*model.Metadata
 }
 
 // New creates a new memory repository
 func New() *Repository {
-	return &Repository{data: map[string]*model.Metadata{}}
+	return &Repository{data: map[key]*model.Metadata{}}
 }
 
-// Get retrieves movie metadata for by movie id
-func (r *Repository) Get(_ context.Context, id string) (*model.Metadata, error) {
+// Get retrieves record metadata by record type and id
+func (r *Repository) Get(_ context.Context, recordType model.RecordType, id string) (*model.Metadata, error) {
 
 	r.RLock()
 	defer r.RUnlock()
 
-	m, ok := r.data[id]
+	m, ok := r.data[key{recordType, id}]
 	if !ok {
 		return nil, repository.ErrNotFound
 	}
 	return m, nil
 }
 
-// Put adds movie metadata for a given movie id
+// Put adds record metadata for a given record id
 func (r *Repository) Put(_ context.Context, id string, metadata *model.Metadata) error {
 	r.Lock()
 	defer r.Unlock()
 
-	r.data[id] = metadata
+	r.data[key{metadata.Type, id}] = metadata
 	return nil
 }
@@ -3,47 +3,82 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/ugurcancaykara/odd-service/metadata/internal/repository"
 	"github.com/ugurcancaykara/odd-service/metadata/pkg/model"
+	"github.com/ugurcancaykara/odd-service/pkg/metrics"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// Repository defines a MySQL-based movie matadata repository.
+// Repository defines a MySQL-based record matadata repository.
 type Repository struct {
 	db *sql.DB
 }
 
-// New creates a new MySQL-based repository.
-func New() (*Repository, error) {
-	db, err := sql.Open("mysql", "root:password@/movie")
+// New creates a new MySQL-based repository connecting to a data source name, e.g. root:password@/movie.
+func New(dsn string) (*Repository, error) {
+	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		return nil, err
 	}
 	return &Repository{db}, nil
 }
 
-// Get retrieves movie metadata for by movie id.
-func (r *Repository) Get(ctx context.Context, id string) (*model.Metadata, error) {
+// Ping checks that the database is reachable.
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// details defines the type-specific metadata fields stored as JSON.
+type details struct {
+	Series  *model.SeriesDetails  `json:"series,omitempty"`
+	Episode *model.EpisodeDetails `json:"episode,omitempty"`
+}
+
+// Get retrieves record metadata by record type and id.
+func (r *Repository) Get(ctx context.Context, recordType model.RecordType, id string) (_ *model.Metadata, err error) {
+	defer metrics.TimeQuery("metadata", "get")()
+	ctx, done := tracing.Trace(ctx, "metadata repository get", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
 	var title, description, director string
-	row := r.db.QueryRowContext(ctx, "SELECT title, description, director FROM movies WHERE id = ?", id)
-	if err := row.Scan(&title, &description, &director); err != nil {
+	var raw []byte
+	row := r.db.QueryRowContext(ctx, "SELECT title, description, director, details FROM metadata WHERE record_type = ? AND id = ?", recordType, id)
+	if err := row.Scan(&title, &description, &director, &raw); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, repository.ErrNotFound
 		}
 		return nil, err
 	}
+	var d details
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &d); err != nil {
+			return nil, err
+		}
+	}
 	return &model.Metadata{
 		ID:          id,
+		Type:        recordType,
 		Title:       title,
 		Description: description,
 		Director:    director,
+		Series:      d.Series,
+		Episode:     d.Episode,
 	}, nil
 }
 
-// Put adds movie metadata for a given movie id.
-func (r *Repository) Put(ctx context.Context, id string, metadata *model.Metadata) error {
-	_, err := r.db.ExecContext(ctx, "INSERT INTO movies (id, title, description, director) VALUES (?, ?, ?, ?)",
-		id, metadata.Title, metadata.Description, metadata.Director)
+// Put adds record metadata for a given record id, replacing existing metadata of the record.
+func (r *Repository) Put(ctx context.Context, id string, metadata *model.Metadata) (err error) {
+	defer metrics.TimeQuery("metadata", "put")()
+	ctx, done := tracing.Trace(ctx, "metadata repository put", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	raw, err := json.Marshal(details{Series: metadata.Series, Episode: metadata.Episode})
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, "INSERT INTO metadata (record_type, id, title, description, director, details) VALUES (?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE title = VALUES(title), description = VALUES(description), director = VALUES(director), details = VALUES(details)",
+		metadata.Type, id, metadata.Title, metadata.Description, metadata.Director, raw)
 	return err
 }
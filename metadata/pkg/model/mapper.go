@@ -4,20 +4,54 @@ import "github.com/ugurcancaykara/odd-service/gen"
 
 // MetadataToProto converts a Metadata struct into a generated proto counterpart.
 func MetadataToProto(m *Metadata) *gen.Metadata {
-	return &gen.Metadata{
+	p := &gen.Metadata{
 		Id:          m.ID,
+		RecordType:  string(m.Type),
 		Title:       m.Title,
 		Description: m.Description,
 		Director:    m.Director,
 	}
+	if m.Series != nil {
+		p.Series = &gen.SeriesDetails{
+			Creator:   m.Series.Creator,
+			Seasons:   int32(m.Series.Seasons),
+			FirstYear: int32(m.Series.FirstYear),
+			LastYear:  int32(m.Series.LastYear),
+		}
+	}
+	if m.Episode != nil {
+		p.Episode = &gen.EpisodeDetails{
+			SeriesId: m.Episode.SeriesID,
+			Season:   int32(m.Episode.Season),
+			Number:   int32(m.Episode.Number),
+		}
+	}
+	return p
 }
 
 // MetadataFromProto converts a generated proto counterpart into a Metadata struct.
 func MetadataFromProto(m *gen.Metadata) *Metadata {
-	return &Metadata{
+	res := &Metadata{
 		ID:          m.Id,
+		Type:        RecordType(m.RecordType),
 		Title:       m.Title,
 		Description: m.Description,
 		Director:    m.Director,
 	}
+	if m.Series != nil {
+		res.Series = &SeriesDetails{
+			Creator:   m.Series.Creator,
+			Seasons:   int(m.Series.Seasons),
+			FirstYear: int(m.Series.FirstYear),
+			LastYear:  int(m.Series.LastYear),
+		}
+	}
+	if m.Episode != nil {
+		res.Episode = &EpisodeDetails{
+			SeriesID: m.Episode.SeriesId,
+			Season:   int(m.Episode.Season),
+			Number:   int(m.Episode.Number),
+		}
+	}
+	return res
 }
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataProto(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata *Metadata
+	}{
+		{
+			name:     "movie",
+			metadata: &Metadata{ID: "1", Type: RecordTypeMovie, Title: "The Movie", Description: "A movie", Director: "Mr. D"},
+		},
+		{
+			name:     "series",
+			metadata: &Metadata{ID: "2", Type: RecordTypeSeries, Title: "The Series", Series: &SeriesDetails{Creator: "Mr. D", Seasons: 2, FirstYear: 2020, LastYear: 2022}},
+		},
+		{
+			name:     "episode",
+			metadata: &Metadata{ID: "3", Type: RecordTypeEpisode, Title: "Pilot", Episode: &EpisodeDetails{SeriesID: "2", Season: 1, Number: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := MetadataToProto(tt.metadata)
+			assert.Equal(t, string(tt.metadata.Type), p.RecordType)
+			assert.Equal(t, tt.metadata.Series != nil, p.Series != nil)
+			assert.Equal(t, tt.metadata.Episode != nil, p.Episode != nil)
+			assert.Equal(t, tt.metadata, MetadataFromProto(p))
+		})
+	}
+}
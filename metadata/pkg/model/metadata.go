@@ -1,9 +1,51 @@
 package model
 
-// Metadata defines the movie metadata.
+// RecordType defines the type of a record described by metadata.
+type RecordType string
+
+// Existing record types.
+const (
+	RecordTypeMovie   = RecordType("movie")
+	RecordTypeSeries  = RecordType("series")
+	RecordTypeEpisode = RecordType("episode")
+)
+
+// RecordTypes defines the record types supported by the metadata service.
+var RecordTypes = []RecordType{RecordTypeMovie, RecordTypeSeries, RecordTypeEpisode}
+
+// Supported reports whether the metadata service supports a record type.
+func (t RecordType) Supported() bool {
+	for _, s := range RecordTypes {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Metadata defines the metadata of a movie, a series or an episode. Director is set for movies,
+// Series and Episode hold the fields specific to series and episodes.
 type Metadata struct {
-	ID          string `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Director    string `json:"director"`
+	ID          string          `json:"id"`
+	Type        RecordType      `json:"type"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Director    string          `json:"director"`
+	Series      *SeriesDetails  `json:"series,omitempty"`
+	Episode     *EpisodeDetails `json:"episode,omitempty"`
+}
+
+// SeriesDetails defines the metadata specific to a TV series.
+type SeriesDetails struct {
+	Creator   string `json:"creator"`
+	Seasons   int    `json:"seasons"`
+	FirstYear int    `json:"firstYear"`
+	LastYear  int    `json:"lastYear,omitempty"`
+}
+
+// EpisodeDetails defines the metadata specific to an episode of a TV series.
+type EpisodeDetails struct {
+	SeriesID string `json:"seriesId"`
+	Season   int    `json:"season"`
+	Number   int    `json:"number"`
 }
@@ -1,9 +1,70 @@
 package main
 
+import (
+	"time"
+
+	"github.com/ugurcancaykara/odd-service/movie/internal/gateway"
+	"github.com/ugurcancaykara/odd-service/pkg/health"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
+	"github.com/ugurcancaykara/odd-service/pkg/service"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
+)
+
 type serviceConfig struct {
-	API apiConfig `yaml:"api"`
+	API       service.APIConfig       `yaml:"api"`
+	Advertise service.AdvertiseConfig `yaml:"advertise"`
+	HTTP      service.ListenConfig    `yaml:"http"`
+	Admin     service.ListenConfig    `yaml:"admin"`
+	Registry  service.RegistryConfig  `yaml:"registry"`
+	Health    health.Config           `yaml:"health"`
+	Logging   logging.Config          `yaml:"logging"`
+	Tracing   tracing.Config          `yaml:"tracing"`
+	Gateway   gatewayConfig           `yaml:"gateway"`
+
+	// ReloadInterval defines how often the config is reloaded, zero disables reloading.
+	// Only the gateway retry budget is applied at runtime.
+	ReloadInterval time.Duration `yaml:"reloadInterval"`
+	// ShutdownTimeout defines how long in-flight requests may take to drain on shutdown.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+}
+
+// Validate checks the settings required by the selected registry.
+func (c *serviceConfig) Validate() error {
+	return c.Registry.Validate()
+}
+
+// runnerConfig returns the config of the runner of the service.
+func (c *serviceConfig) runnerConfig() service.Config {
+	return service.Config{API: c.API, Advertise: c.Advertise, HTTP: c.HTTP, Admin: c.Admin, Health: c.Health, ShutdownTimeout: c.ShutdownTimeout}
+}
+
+// gatewayConfig defines the calls of the metadata and rating gateways.
+type gatewayConfig struct {
+	Retry retryConfig `yaml:"retry"`
+}
+
+// retryConfig defines the timeout of a gateway call attempt and the backoff between attempts.
+// Zero values use the defaults.
+type retryConfig struct {
+	Timeout         time.Duration `yaml:"timeout"`
+	InitialInterval time.Duration `yaml:"initialInterval"`
+	MaxInterval     time.Duration `yaml:"maxInterval"`
+	MaxElapsedTime  time.Duration `yaml:"maxElapsedTime"`
 }
 
-type apiConfig struct {
-	Port string `yaml:"port"`
+func retryPolicy(cfg retryConfig) gateway.RetryConfig {
+	res := gateway.DefaultRetryConfig
+	if cfg.Timeout > 0 {
+		res.Timeout = cfg.Timeout
+	}
+	if cfg.InitialInterval > 0 {
+		res.InitialInterval = cfg.InitialInterval
+	}
+	if cfg.MaxInterval > 0 {
+		res.MaxInterval = cfg.MaxInterval
+	}
+	if cfg.MaxElapsedTime > 0 {
+		res.MaxElapsedTime = cfg.MaxElapsedTime
+	}
+	return res
 }
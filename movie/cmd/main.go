@@ -2,88 +2,70 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
-	"net"
+	"log/slog"
+	"net/http"
 	"os"
-	"os/signal"
-	"sync"
-	"syscall"
-	"time"
 
 	"github.com/ugurcancaykara/odd-service/gen"
 	"github.com/ugurcancaykara/odd-service/movie/internal/controller/movie"
+	"github.com/ugurcancaykara/odd-service/movie/internal/gateway"
 	metadatagateway "github.com/ugurcancaykara/odd-service/movie/internal/gateway/metadata/grpc"
 	ratinggateway "github.com/ugurcancaykara/odd-service/movie/internal/gateway/rating/grpc"
 	grpchandler "github.com/ugurcancaykara/odd-service/movie/internal/handler/grpc"
-	"github.com/ugurcancaykara/odd-service/pkg/discovery"
-	"github.com/ugurcancaykara/odd-service/pkg/discovery/consul"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
-	"gopkg.in/yaml.v3"
+	httphandler "github.com/ugurcancaykara/odd-service/movie/internal/handler/http"
+	"github.com/ugurcancaykara/odd-service/pkg/config"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
+	"github.com/ugurcancaykara/odd-service/pkg/service"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
 )
 
 const serviceName = "movie"
 
 func main() {
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		configPath = "configs/base.yaml"
+	watcher, err := config.Watch[serviceConfig](config.NewLoader(serviceName), os.Args[1:])
+	if err != nil {
+		slog.Error("Failed to load config", "error", err)
+		os.Exit(1)
 	}
-	f, err := os.Open(configPath)
+	cfg := watcher.Config()
+	logger, err := logging.New(cfg.Logging, os.Stderr)
 	if err != nil {
-		panic(err)
+		slog.Error("Failed to set up logging", "error", err)
+		os.Exit(1)
 	}
-	defer f.Close()
-	var cfg serviceConfig
-	if err := yaml.NewDecoder(f).Decode(&cfg); err != nil {
-		panic(err)
+	slog.SetDefault(logger)
+	shutdownTracing, err := tracing.Init(context.Background(), serviceName, cfg.Tracing)
+	if err != nil {
+		logger.Error("Failed to set up tracing", "error", err)
+		os.Exit(1)
 	}
-	port := cfg.API.Port
-	log.Printf("Starting the movie service on port %s", port)
-	registry, err := consul.NewRegistry("localhost:8500")
+	registry, err := service.NewRegistry(cfg.Registry, logger)
 	if err != nil {
 		panic(err)
 	}
-	ctx, cancel := context.WithCancel(context.Background())
+	retry := gateway.NewRetry(retryPolicy(cfg.Gateway.Retry))
+	watcher.OnReload(func(cfg *serviceConfig) {
+		retry.Set(retryPolicy(cfg.Gateway.Retry))
+	})
+	metadataGateway := metadatagateway.New(registry, retry, logger)
+	ratingGateway := ratinggateway.New(registry, retry, logger)
+	ctrl := movie.New(ratingGateway, metadataGateway, logger)
 
-	instanceID := discovery.GenerateInstanceID(serviceName)
-	if err := registry.Register(ctx, instanceID, serviceName, fmt.Sprintf("localhost:%s", port)); err != nil {
-		panic(err)
-	}
-	go func() {
-		for {
-			if err := registry.ReportHealthyState(instanceID, serviceName); err != nil {
-				log.Println("Failed to report healthy state: " + err.Error())
-			}
-			time.Sleep(1 * time.Second)
-		}
-	}()
-	defer registry.Deregister(ctx, instanceID, serviceName)
-	metadataGateway := metadatagateway.New(registry)
-	ratingGateway := ratinggateway.New(registry)
-	ctrl := movie.New(ratingGateway, metadataGateway)
-	h := grpchandler.New(ctrl)
-	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%s", port))
-	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+	svc := service.New(serviceName, cfg.runnerConfig(), registry, logger)
+	svc.OnShutdown("tracing", shutdownTracing)
+	svc.Health().Add("metadata", metadataGateway.Ping)
+	svc.Health().Add("rating", ratingGateway.Ping)
+	gen.RegisterMovieServiceServer(svc.GRPCServer(), grpchandler.New(ctrl))
+	svc.HandleHTTP("/movie", http.HandlerFunc(httphandler.New(ctrl).GetMovieDetails))
+	svc.HandleAdmin("/config", watcher)
+	if interval := cfg.ReloadInterval; interval > 0 {
+		svc.Go("config reload", func(ctx context.Context) error {
+			watcher.Run(ctx, interval)
+			return nil
+		})
 	}
-	srv := grpc.NewServer()
-	reflection.Register(srv)
-	gen.RegisterMovieServiceServer(srv, h)
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGKILL)
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		s := <-sigChan
-		cancel()
-		log.Printf("Received signal %v, attempting graceful shutdown...", s)
-		srv.GracefulStop()
-		log.Println("Gracefully stopped gRPC server")
-	}()
-	if err := srv.Serve(lis); err != nil {
-		panic(err)
+	if err := svc.Run(context.Background()); err != nil {
+		logger.Error("Movie service stopped", "error", err)
+		os.Exit(1)
 	}
 }
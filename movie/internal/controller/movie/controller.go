@@ -3,51 +3,152 @@ package movie
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"time"
 
 	metadatamodel "github.com/ugurcancaykara/odd-service/metadata/pkg/model"
 	"github.com/ugurcancaykara/odd-service/movie/internal/gateway"
 	"github.com/ugurcancaykara/odd-service/movie/pkg/model"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
 	ratingmodel "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+	"golang.org/x/sync/errgroup"
 )
 
 // ErrNotFound is returned when the movie metadata is not found.
 var ErrNotFound = errors.New("movie metadata not found")
 
+// ErrUnsupportedRecordType is returned when the metadata service doesn't support a record type.
+var ErrUnsupportedRecordType = errors.New("unsupported record type")
+
+// metadataConcurrency bounds the concurrent metadata requests of a listing.
+const metadataConcurrency = 8
+
 type ratingGateway interface {
 	GetAggregatedRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType) (float64, error)
+	ListTopRated(ctx context.Context, recordType ratingmodel.RecordType, strategy ratingmodel.AggregationStrategy, minVotes int, limit int) ([]ratingmodel.RankedRecord, error)
+	ListTrending(ctx context.Context, recordType ratingmodel.RecordType, window time.Duration, limit int) ([]ratingmodel.RankedRecord, error)
+	GetUserRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType, userID ratingmodel.UserID) (*ratingmodel.Rating, error)
 }
 
 type metadataGateway interface {
-	Get(ctx context.Context, id string) (*metadatamodel.Metadata, error)
+	Get(ctx context.Context, recordType metadatamodel.RecordType, id string) (*metadatamodel.Metadata, error)
 }
 
 // Controller defines a movie service controller.
 type Controller struct {
 	ratingGateway   ratingGateway
 	metadataGateway metadataGateway
+	logger          *slog.Logger
 }
 
-// New creates a new movie service controller.
-func New(ratingGateway ratingGateway, metadataGateway metadataGateway) *Controller {
-	return &Controller{ratingGateway, metadataGateway}
+// New creates a new movie service controller. A nil logger logs to slog.Default().
+func New(ratingGateway ratingGateway, metadataGateway metadataGateway, logger *slog.Logger) *Controller {
+	return &Controller{ratingGateway, metadataGateway, logging.OrDefault(logger)}
 }
 
-// Get returns the movie details including the aggregated rating and movie metadata.
-func (c *Controller) Get(ctx context.Context, id string) (*model.MovieDetails, error) {
-	metadata, err := c.metadataGateway.Get(ctx, id)
+// Get returns the details of a record including its aggregated rating and metadata, an empty record type selects movies.
+// If userID is not empty, the details also include the rating of that user.
+func (c *Controller) Get(ctx context.Context, recordType ratingmodel.RecordType, id string, userID ratingmodel.UserID) (*model.MovieDetails, error) {
+	recordType, err := supported(recordType)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := c.metadataGateway.Get(ctx, metadatamodel.RecordType(recordType), id)
 	if err != nil && errors.Is(err, gateway.ErrNotFound) {
 		return nil, ErrNotFound
 	} else if err != nil {
 		return nil, err
 	}
 	details := &model.MovieDetails{Metadata: *metadata}
-	rating, err := c.ratingGateway.GetAggregatedRating(ctx, ratingmodel.RecordID(id), ratingmodel.RecordTypeMovie)
-	if err != nil && !errors.Is(err, gateway.ErrNotFound) {
+	rating, err := c.ratingGateway.GetAggregatedRating(ctx, ratingmodel.RecordID(id), recordType)
+	if err != nil && errors.Is(err, gateway.ErrNotFound) {
 		// Just proceed in this case, it's ok not to have ratings yet.
 	} else if err != nil {
 		return nil, err
 	} else {
 		details.Rating = &rating
 	}
+	if userID == "" {
+		return details, nil
+	}
+	userRating, err := c.ratingGateway.GetUserRating(ctx, ratingmodel.RecordID(id), recordType, userID)
+	if err != nil && errors.Is(err, gateway.ErrNotFound) {
+		// The user didn't rate the movie.
+	} else if err != nil {
+		return nil, err
+	} else {
+		details.UserRating = &userRating.Value
+	}
 	return details, nil
 }
+
+// ListTopRated returns the best rated records of a type with their metadata, an empty record type selects movies.
+func (c *Controller) ListTopRated(ctx context.Context, recordType ratingmodel.RecordType, strategy ratingmodel.AggregationStrategy, minVotes int, limit int) ([]model.RankedMovie, error) {
+	recordType, err := supported(recordType)
+	if err != nil {
+		return nil, err
+	}
+	records, err := c.ratingGateway.ListTopRated(ctx, recordType, strategy, minVotes, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.rankedMovies(ctx, recordType, records)
+}
+
+// ListTrending returns the records of a type rated most often within a window with their metadata,
+// an empty record type selects movies.
+func (c *Controller) ListTrending(ctx context.Context, recordType ratingmodel.RecordType, window time.Duration, limit int) ([]model.RankedMovie, error) {
+	recordType, err := supported(recordType)
+	if err != nil {
+		return nil, err
+	}
+	records, err := c.ratingGateway.ListTrending(ctx, recordType, window, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.rankedMovies(ctx, recordType, records)
+}
+
+// supported defaults an empty record type to movies and checks that the metadata service supports it.
+func supported(recordType ratingmodel.RecordType) (ratingmodel.RecordType, error) {
+	if recordType == "" {
+		return ratingmodel.RecordTypeMovie, nil
+	}
+	if !metadatamodel.RecordType(recordType).Supported() {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedRecordType, recordType)
+	}
+	return recordType, nil
+}
+
+// rankedMovies joins ranked records with their metadata, keeping their order. The metadata is requested concurrently.
+// Records without metadata are skipped.
+func (c *Controller) rankedMovies(ctx context.Context, recordType ratingmodel.RecordType, records []ratingmodel.RankedRecord) ([]model.RankedMovie, error) {
+	metadata := make([]*metadatamodel.Metadata, len(records))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(metadataConcurrency)
+	for i, r := range records {
+		i, r := i, r
+		g.Go(func() error {
+			m, err := c.metadataGateway.Get(gctx, metadatamodel.RecordType(recordType), string(r.RecordID))
+			if err != nil && errors.Is(err, gateway.ErrNotFound) {
+				c.logger.WarnContext(ctx, "Skipping ranked record without metadata", "record_type", recordType, "id", r.RecordID)
+				return nil
+			} else if err != nil {
+				return err
+			}
+			metadata[i] = m
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	res := make([]model.RankedMovie, 0, len(records))
+	for i, r := range records {
+		if metadata[i] != nil {
+			res = append(res, model.RankedMovie{Metadata: *metadata[i], Score: r.Score, Votes: r.Votes})
+		}
+	}
+	return res, nil
+}
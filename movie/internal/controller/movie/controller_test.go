@@ -0,0 +1,242 @@
+package movie
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metadatamodel "github.com/ugurcancaykara/odd-service/metadata/pkg/model"
+	"github.com/ugurcancaykara/odd-service/movie/internal/gateway"
+	"github.com/ugurcancaykara/odd-service/movie/pkg/model"
+	ratingmodel "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// fakeRatingGateway returns fixed ranked records and ratings.
+type fakeRatingGateway struct {
+	ranked      []ratingmodel.RankedRecord
+	rating      map[ratingmodel.RecordID]float64
+	userRatings map[ratingmodel.UserID]ratingmodel.RatingValue
+}
+
+func (g *fakeRatingGateway) GetAggregatedRating(_ context.Context, recordID ratingmodel.RecordID, _ ratingmodel.RecordType) (float64, error) {
+	r, ok := g.rating[recordID]
+	if !ok {
+		return 0, gateway.ErrNotFound
+	}
+	return r, nil
+}
+
+func (g *fakeRatingGateway) ListTopRated(context.Context, ratingmodel.RecordType, ratingmodel.AggregationStrategy, int, int) ([]ratingmodel.RankedRecord, error) {
+	return g.ranked, nil
+}
+
+func (g *fakeRatingGateway) ListTrending(context.Context, ratingmodel.RecordType, time.Duration, int) ([]ratingmodel.RankedRecord, error) {
+	return g.ranked, nil
+}
+
+func (g *fakeRatingGateway) GetUserRating(_ context.Context, _ ratingmodel.RecordID, _ ratingmodel.RecordType, userID ratingmodel.UserID) (*ratingmodel.Rating, error) {
+	v, ok := g.userRatings[userID]
+	if !ok {
+		return nil, gateway.ErrNotFound
+	}
+	return &ratingmodel.Rating{UserID: userID, Value: v}, nil
+}
+
+// fakeMetadataGateway returns metadata titled after its id, blocking every request until release is closed
+// if it is set. It records the highest number of concurrent requests.
+type fakeMetadataGateway struct {
+	missing map[string]bool
+	err     error
+	release chan struct{}
+
+	active     atomic.Int32
+	concurrent atomic.Int32
+}
+
+func (g *fakeMetadataGateway) Get(_ context.Context, recordType metadatamodel.RecordType, id string) (*metadatamodel.Metadata, error) {
+	n := g.active.Add(1)
+	defer g.active.Add(-1)
+	for {
+		max := g.concurrent.Load()
+		if n <= max || g.concurrent.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	if g.release != nil {
+		<-g.release
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+	if g.missing[id] {
+		return nil, gateway.ErrNotFound
+	}
+	return &metadatamodel.Metadata{ID: id, Type: recordType, Title: "title " + id}, nil
+}
+
+func ranked(ids ...string) []ratingmodel.RankedRecord {
+	res := make([]ratingmodel.RankedRecord, 0, len(ids))
+	for i, id := range ids {
+		res = append(res, ratingmodel.RankedRecord{RecordID: ratingmodel.RecordID(id), Score: float64(len(ids) - i), Votes: i + 1})
+	}
+	return res
+}
+
+func TestController_Get(t *testing.T) {
+	ratings := &fakeRatingGateway{
+		rating:      map[ratingmodel.RecordID]float64{"1": 4.5},
+		userRatings: map[ratingmodel.UserID]ratingmodel.RatingValue{"user1": 4},
+	}
+	rating, userRating := 4.5, ratingmodel.RatingValue(4)
+	tests := []struct {
+		name       string
+		recordType ratingmodel.RecordType
+		id         string
+		userID     ratingmodel.UserID
+		metadata   *fakeMetadataGateway
+		want       *model.MovieDetails
+		wantErr    error
+	}{
+		{
+			name:     "movie by default",
+			id:       "1",
+			metadata: &fakeMetadataGateway{},
+			want:     &model.MovieDetails{Rating: &rating, Metadata: metadatamodel.Metadata{ID: "1", Type: metadatamodel.RecordTypeMovie, Title: "title 1"}},
+		},
+		{
+			name:       "series with the rating of a user",
+			recordType: ratingmodel.RecordTypeSeries,
+			id:         "1",
+			userID:     "user1",
+			metadata:   &fakeMetadataGateway{},
+			want: &model.MovieDetails{Rating: &rating, UserRating: &userRating,
+				Metadata: metadatamodel.Metadata{ID: "1", Type: metadatamodel.RecordTypeSeries, Title: "title 1"}},
+		},
+		{
+			name:       "episode without ratings",
+			recordType: ratingmodel.RecordTypeEpisode,
+			id:         "2",
+			userID:     "user2",
+			metadata:   &fakeMetadataGateway{},
+			want:       &model.MovieDetails{Metadata: metadatamodel.Metadata{ID: "2", Type: metadatamodel.RecordTypeEpisode, Title: "title 2"}},
+		},
+		{
+			name:     "metadata not found",
+			id:       "1",
+			metadata: &fakeMetadataGateway{missing: map[string]bool{"1": true}},
+			wantErr:  ErrNotFound,
+		},
+		{
+			name:       "unsupported record type",
+			recordType: "book",
+			id:         "1",
+			metadata:   &fakeMetadataGateway{},
+			wantErr:    ErrUnsupportedRecordType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(ratings, tt.metadata, nil).Get(context.Background(), tt.recordType, tt.id, tt.userID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestController_ListTopRated(t *testing.T) {
+	tests := []struct {
+		name       string
+		recordType ratingmodel.RecordType
+		metadata   *fakeMetadataGateway
+		wantIDs    []string
+		wantType   metadatamodel.RecordType
+		wantErr    error
+	}{
+		{
+			name:     "movies by default",
+			metadata: &fakeMetadataGateway{},
+			wantIDs:  []string{"1", "2", "3", "4"},
+			wantType: metadatamodel.RecordTypeMovie,
+		},
+		{
+			name:       "series",
+			recordType: ratingmodel.RecordTypeSeries,
+			metadata:   &fakeMetadataGateway{},
+			wantIDs:    []string{"1", "2", "3", "4"},
+			wantType:   metadatamodel.RecordTypeSeries,
+		},
+		{
+			name:     "records without metadata are skipped",
+			metadata: &fakeMetadataGateway{missing: map[string]bool{"2": true}},
+			wantIDs:  []string{"1", "3", "4"},
+			wantType: metadatamodel.RecordTypeMovie,
+		},
+		{
+			name:     "metadata error",
+			metadata: &fakeMetadataGateway{err: errors.New("unavailable")},
+			wantErr:  errors.New("unavailable"),
+		},
+		{
+			name:       "unsupported record type",
+			recordType: "book",
+			metadata:   &fakeMetadataGateway{},
+			wantErr:    ErrUnsupportedRecordType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(&fakeRatingGateway{ranked: ranked("1", "2", "3", "4")}, tt.metadata, nil)
+			got, err := c.ListTopRated(context.Background(), tt.recordType, "", 0, 10)
+			if tt.wantErr != nil {
+				if errors.Is(tt.wantErr, ErrUnsupportedRecordType) {
+					assert.ErrorIs(t, err, tt.wantErr)
+				} else {
+					assert.EqualError(t, err, tt.wantErr.Error())
+				}
+				return
+			}
+			require.NoError(t, err)
+			var ids []string
+			for _, m := range got {
+				ids = append(ids, m.Metadata.ID)
+				assert.Equal(t, tt.wantType, m.Metadata.Type)
+			}
+			assert.Equal(t, tt.wantIDs, ids, "the order of the leaderboard is kept")
+		})
+	}
+}
+
+func TestController_ListTrending_Concurrent(t *testing.T) {
+	ids := make([]string, 2*metadataConcurrency)
+	for i := range ids {
+		ids[i] = string(rune('a' + i))
+	}
+	metadata := &fakeMetadataGateway{release: make(chan struct{})}
+	c := New(&fakeRatingGateway{ranked: ranked(ids...)}, metadata, nil)
+
+	done := make(chan []model.RankedMovie)
+	go func() {
+		got, err := c.ListTrending(context.Background(), "", time.Hour, 0)
+		assert.NoError(t, err)
+		done <- got
+	}()
+	require.Eventually(t, func() bool {
+		return metadata.active.Load() == metadataConcurrency
+	}, time.Second, time.Millisecond, "the metadata is requested concurrently")
+	close(metadata.release)
+	got := <-done
+	assert.Equal(t, int32(metadataConcurrency), metadata.concurrent.Load(), "concurrent requests are bounded")
+	require.Len(t, got, len(ids))
+	for i, m := range got {
+		assert.Equal(t, ids[i], m.Metadata.ID)
+		assert.Equal(t, i+1, m.Votes)
+	}
+}
@@ -3,25 +3,39 @@ package grpc
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"time"
 
 	"github.com/cenkalti/backoff"
 	"github.com/ugurcancaykara/odd-service/gen"
 	"github.com/ugurcancaykara/odd-service/internal/grpcutil"
 	"github.com/ugurcancaykara/odd-service/metadata/pkg/model"
+	"github.com/ugurcancaykara/odd-service/movie/internal/gateway"
 	"github.com/ugurcancaykara/odd-service/pkg/discovery"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
+	"github.com/ugurcancaykara/odd-service/pkg/metrics"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-// Gateway defines a movie metadata gRPC gateway.
+// Gateway defines a record metadata gRPC gateway.
 type Gateway struct {
 	registry discovery.Registry
+	retry    *gateway.Retry
+	logger   *slog.Logger
 }
 
-// New creates a new gRPC gateway for a movie metadata service.
-func New(registry discovery.Registry) *Gateway {
-	return &Gateway{registry}
+// New creates a new gRPC gateway for a movie metadata service retrying calls within a retry budget,
+// gateway.DefaultRetryConfig if retry is nil. A nil logger logs to slog.Default().
+func New(registry discovery.Registry, retry *gateway.Retry, logger *slog.Logger) *Gateway {
+	return &Gateway{registry, retry, logging.OrDefault(logger)}
+}
+
+// Ping checks that an instance of the metadata service is reachable and serving.
+func (g *Gateway) Ping(ctx context.Context) error {
+	return grpcutil.CheckHealth(ctx, "metadata", g.registry)
 }
 
 func shouldRetry(err error) bool {
@@ -32,8 +46,8 @@ func shouldRetry(err error) bool {
 	return e.Code() == codes.DeadlineExceeded || e.Code() == codes.Unavailable || e.Code() == codes.ResourceExhausted
 }
 
-// Get returns movie metadata by a movie id.
-func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
+// Get returns the metadata of a record by type and id or ErrNotFound if there is none.
+func (g *Gateway) Get(ctx context.Context, recordType model.RecordType, id string) (*model.Metadata, error) {
 	conn, err := grpcutil.ServiceConnection(ctx, "metadata", g.registry)
 	if err != nil {
 		return nil, err
@@ -42,20 +56,20 @@ func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
 	client := gen.NewMetadataServiceClient(conn)
 
 	// Create an exponential backoff with jittering strategy.
-	expBackoff := backoff.NewExponentialBackOff()
-	expBackoff.InitialInterval = 100 * time.Millisecond // Initial retry interval
-	expBackoff.MaxInterval = 5 * time.Second            // Maximum retry interval
-	expBackoff.MaxElapsedTime = 60 * time.Second        // Maximum total time for retries
-	expBackoff.RandomizationFactor = 0.5                // jittering for randomization
+	retry := g.retry.Config()
+	expBackoff := retry.Backoff()
 
 	// Create a retry operation with the exponential backoff strategy.
 	var resp *gen.GetMetadataResponse
+	attempt := 0
 	operation := func() error {
 		var err error
-		const timeout = 10 * time.Second
-		ctx, cancel := context.WithTimeout(ctx, timeout)
+		attempt++
+		ctx, span := tracing.Start(ctx, "metadata gateway attempt", attribute.Int("attempt", attempt))
+		ctx, cancel := context.WithTimeout(ctx, retry.Timeout)
 		defer cancel()
-		resp, err = client.GetMetadata(ctx, &gen.GetMetadataRequest{MovieId: id})
+		resp, err = client.GetMetadata(ctx, &gen.GetMetadataRequest{MovieId: id, RecordType: string(recordType)})
+		tracing.End(span, err)
 		if err != nil {
 			if shouldRetry(err) {
 				return err // Retry
@@ -66,7 +80,12 @@ func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
 	}
 
 	// Use the backoff.Retry function to perform the retry logic.
-	if err := backoff.Retry(operation, expBackoff); err != nil {
+	if err := backoff.RetryNotify(operation, expBackoff, func(err error, wait time.Duration) {
+		metrics.GatewayRetry("metadata")
+		g.logger.DebugContext(ctx, "Retrying metadata service call", "wait", wait, "error", err)
+	}); err != nil && status.Code(err) == codes.NotFound {
+		return nil, gateway.ErrNotFound
+	} else if err != nil {
 		return nil, err
 	}
 
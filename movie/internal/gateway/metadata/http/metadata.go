@@ -4,33 +4,36 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
 	"net/http"
 
 	"github.com/ugurcancaykara/odd-service/metadata/pkg/model"
 	"github.com/ugurcancaykara/odd-service/movie/internal/gateway"
 	"github.com/ugurcancaykara/odd-service/pkg/discovery"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
 )
 
-// Gateway defines a movie metadata HTTP gateway.
+// Gateway defines a record metadata HTTP gateway.
 type Gateway struct {
 	registry discovery.Registry
+	logger   *slog.Logger
 }
 
 // New creates a new HTTP gateway for a movie metadata service.
-func New(registry discovery.Registry) *Gateway {
-	return &Gateway{registry}
+// A nil logger logs to slog.Default().
+func New(registry discovery.Registry, logger *slog.Logger) *Gateway {
+	return &Gateway{registry, logging.OrDefault(logger)}
 }
 
-// Get gets movie metadata by a movie id.
-func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
+// Get gets the metadata of a record by type and id.
+func (g *Gateway) Get(ctx context.Context, recordType model.RecordType, id string) (*model.Metadata, error) {
 	addrs, err := g.registry.ServiceAddresses(ctx, "metadata")
 	if err != nil {
 		return nil, err
 	}
 	url := "http://" + addrs[rand.Intn(len(addrs))] + "/metadata"
-	log.Printf("Calling metadata service. Request: GET " + url)
+	g.logger.DebugContext(ctx, "Calling metadata service", "method", http.MethodGet, "url", url)
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
@@ -38,6 +41,7 @@ func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
 	req = req.WithContext(ctx)
 	values := req.URL.Query()
 	values.Add("id", id)
+	values.Add("type", string(recordType))
 	req.URL.RawQuery = values.Encode()
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -3,69 +3,162 @@ package grpc
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"time"
 
 	"github.com/cenkalti/backoff"
 	"github.com/ugurcancaykara/odd-service/gen"
 	"github.com/ugurcancaykara/odd-service/internal/grpcutil"
+	"github.com/ugurcancaykara/odd-service/movie/internal/gateway"
 	"github.com/ugurcancaykara/odd-service/pkg/discovery"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
+	"github.com/ugurcancaykara/odd-service/pkg/metrics"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
 	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 // Gateway defines an gRPC gateway for a rating service.
 type Gateway struct {
 	registry discovery.Registry
+	retry    *gateway.Retry
+	logger   *slog.Logger
 }
 
-// New creates a new gRPC gateway for a rating service.
-func New(registry discovery.Registry) *Gateway {
-	return &Gateway{registry}
+// New creates a new gRPC gateway for a rating service retrying calls within a retry budget,
+// gateway.DefaultRetryConfig if retry is nil. A nil logger logs to slog.Default().
+func New(registry discovery.Registry, retry *gateway.Retry, logger *slog.Logger) *Gateway {
+	return &Gateway{registry, retry, logging.OrDefault(logger)}
+}
+
+// Ping checks that an instance of the rating service is reachable and serving.
+func (g *Gateway) Ping(ctx context.Context) error {
+	return grpcutil.CheckHealth(ctx, "rating", g.registry)
 }
 
 // GetAggregatedRating returns the aggregated rating for a record or ErrNotFound if there are no ratings for it.
 func (g *Gateway) GetAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (float64, error) {
+	var resp *gen.GetAggregatedRatingResponse
+	err := g.call(ctx, func(ctx context.Context, client gen.RatingServiceClient) error {
+		var err error
+		resp, err = client.GetAggregatedRating(ctx, &gen.GetAggregatedRatingRequest{RecordId: string(recordID), RecordType: string(recordType)})
+		return err
+	})
+	if err != nil && status.Code(err) == codes.NotFound {
+		return 0, gateway.ErrNotFound
+	} else if err != nil {
+		return 0, err
+	}
+	return resp.RatingValue, nil
+}
+
+// ListTopRated returns up to limit best rated records of a type with at least minVotes ratings.
+func (g *Gateway) ListTopRated(ctx context.Context, recordType model.RecordType, strategy model.AggregationStrategy, minVotes int, limit int) ([]model.RankedRecord, error) {
+	var resp *gen.ListTopRatedResponse
+	err := g.call(ctx, func(ctx context.Context, client gen.RatingServiceClient) error {
+		var err error
+		resp, err = client.ListTopRated(ctx, &gen.ListTopRatedRequest{
+			RecordType: string(recordType),
+			Strategy:   string(strategy),
+			MinVotes:   int64(minVotes),
+			Limit:      int32(limit),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rankedRecords(resp.Records), nil
+}
+
+// ListTrending returns up to limit records of a type rated most often within a window.
+func (g *Gateway) ListTrending(ctx context.Context, recordType model.RecordType, window time.Duration, limit int) ([]model.RankedRecord, error) {
+	var resp *gen.ListTrendingResponse
+	err := g.call(ctx, func(ctx context.Context, client gen.RatingServiceClient) error {
+		req := &gen.ListTrendingRequest{RecordType: string(recordType), Limit: int32(limit)}
+		if window > 0 {
+			req.Window = durationpb.New(window)
+		}
+		var err error
+		resp, err = client.ListTrending(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rankedRecords(resp.Records), nil
+}
+
+// GetUserRating returns the rating of a user for a record or ErrNotFound if the user didn't rate it.
+func (g *Gateway) GetUserRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) (*model.Rating, error) {
+	var resp *gen.GetUserRatingResponse
+	err := g.call(ctx, func(ctx context.Context, client gen.RatingServiceClient) error {
+		var err error
+		resp, err = client.GetUserRating(ctx, &gen.GetUserRatingRequest{UserId: string(userID), RecordId: string(recordID), RecordType: string(recordType)})
+		return err
+	})
+	if err != nil && status.Code(err) == codes.NotFound {
+		return nil, gateway.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return model.RatingFromProto(resp.Rating), nil
+}
+
+func rankedRecords(records []*gen.RankedRecord) []model.RankedRecord {
+	res := make([]model.RankedRecord, 0, len(records))
+	for _, r := range records {
+		res = append(res, *model.RankedRecordFromProto(r))
+	}
+	return res
+}
+
+// call runs a rating service request, retrying it with an exponential backoff on retriable errors.
+func (g *Gateway) call(ctx context.Context, request func(ctx context.Context, client gen.RatingServiceClient) error) error {
 	conn, err := grpcutil.ServiceConnection(ctx, "rating", g.registry)
 	if err != nil {
-		return 0, err
+		return err
 	}
 	defer conn.Close()
 	client := gen.NewRatingServiceClient(conn)
-	expBackoff := backoff.NewExponentialBackOff()
-	expBackoff.InitialInterval = 100 * time.Millisecond
-	expBackoff.MaxInterval = 5 * time.Second
-	expBackoff.MaxElapsedTime = 60 * time.Second
-	expBackoff.RandomizationFactor = 0.5
+	retry := g.retry.Config()
+	expBackoff := retry.Backoff()
 
 	// Create a retry operation with the exponential backoff strategy.
-	var resp *gen.GetAggregatedRatingResponse
+	called := false
+	attempt := 0
 	operation := func() error {
-		var err error
-		const timeout = 10 * time.Second
-		ctx, cancel := context.WithTimeout(ctx, timeout)
+		attempt++
+		ctx, span := tracing.Start(ctx, "rating gateway attempt", attribute.Int("attempt", attempt))
+		ctx, cancel := context.WithTimeout(ctx, retry.Timeout)
 		defer cancel()
-		resp, err = client.GetAggregatedRating(ctx, &gen.GetAggregatedRatingRequest{RecordId: string(recordID), RecordType: string(recordType)})
-
+		err := request(ctx, client)
+		tracing.End(span, err)
 		if err != nil {
 			if shouldRetry(err) {
 				return err // Retry
 			}
 			return backoff.Permanent(err) // Stop retrying on non-retriable error
 		}
+		called = true
 		return nil // Success, stop retrying
 	}
 	// Use the backoff.Retry function to perform the retry logic.
-	if err := backoff.Retry(operation, expBackoff); err != nil {
-		return 0, err
+	if err := backoff.RetryNotify(operation, expBackoff, func(err error, wait time.Duration) {
+		metrics.GatewayRetry("rating")
+		g.logger.DebugContext(ctx, "Retrying rating service call", "wait", wait, "error", err)
+	}); err != nil {
+		return err
 	}
 
 	// If retries are exhausted and no successful response, return an error.
-	if resp == nil {
-		return 0, errors.New("no response received after retries")
+	if !called {
+		return errors.New("no response received after retries")
 	}
-
-	return resp.RatingValue, nil
+	return nil
 }
 
 func shouldRetry(err error) bool {
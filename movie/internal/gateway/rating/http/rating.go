@@ -4,23 +4,26 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
 	"net/http"
 
 	"github.com/ugurcancaykara/odd-service/movie/internal/gateway"
 	"github.com/ugurcancaykara/odd-service/pkg/discovery"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
 	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
 )
 
 // Gateway defines an HTTP gateway for a rating service.
 type Gateway struct {
 	registry discovery.Registry
+	logger   *slog.Logger
 }
 
 // New creates a new HTTP gateway for a rating service.
-func New(registry discovery.Registry) *Gateway {
-	return &Gateway{registry}
+// A nil logger logs to slog.Default().
+func New(registry discovery.Registry, logger *slog.Logger) *Gateway {
+	return &Gateway{registry, logging.OrDefault(logger)}
 }
 
 // GetAggregatedRating returns the aggregated rating for a record or ErrNotFound if there are no ratings for it.
@@ -30,7 +33,7 @@ func (g *Gateway) GetAggregatedRating(ctx context.Context, recordID model.Record
 		return 0, err
 	}
 	url := "http://" + addrs[rand.Intn(len(addrs))] + "/rating"
-	log.Printf("Calling rating service. Request: GET " + url)
+	g.logger.DebugContext(ctx, "Calling rating service", "method", http.MethodGet, "url", url)
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return 0, err
@@ -64,7 +67,7 @@ func (g *Gateway) PutRating(ctx context.Context, recordID model.RecordID, record
 		return err
 	}
 	url := "http://" + addrs[rand.Intn(len(addrs))] + "/ratng"
-	log.Printf("Calling rating service. Request: PUT " + url)
+	g.logger.DebugContext(ctx, "Calling rating service", "method", http.MethodPut, "url", url)
 	req, err := http.NewRequest(http.MethodPut, addrs[0]+"/rating", nil)
 	if err != nil {
 		return err
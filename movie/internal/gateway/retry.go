@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/cenkalti/backoff"
+)
+
+// RetryConfig defines the retry and timeout budget of gateway calls.
+type RetryConfig struct {
+	// Timeout bounds a single attempt.
+	Timeout         time.Duration
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	// MaxElapsedTime bounds all attempts of a call.
+	MaxElapsedTime time.Duration
+}
+
+// DefaultRetryConfig is the retry budget of gateways created without one.
+var DefaultRetryConfig = RetryConfig{
+	Timeout:         10 * time.Second,
+	InitialInterval: 100 * time.Millisecond,
+	MaxInterval:     5 * time.Second,
+	MaxElapsedTime:  60 * time.Second,
+}
+
+// Retry holds a retry budget which can be replaced while calls are in flight.
+type Retry struct {
+	cfg atomic.Pointer[RetryConfig]
+}
+
+// NewRetry creates a new retry budget.
+func NewRetry(cfg RetryConfig) *Retry {
+	r := &Retry{}
+	r.Set(cfg)
+	return r
+}
+
+// Set replaces the retry budget, calls in flight keep the budget they started with.
+func (r *Retry) Set(cfg RetryConfig) {
+	r.cfg.Store(&cfg)
+}
+
+// Config returns the current retry budget, DefaultRetryConfig if r is nil.
+func (r *Retry) Config() RetryConfig {
+	if r == nil {
+		return DefaultRetryConfig
+	}
+	return *r.cfg.Load()
+}
+
+// Backoff returns an exponential backoff with jittering following a retry budget.
+func (cfg RetryConfig) Backoff() *backoff.ExponentialBackOff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = cfg.InitialInterval
+	b.MaxInterval = cfg.MaxInterval
+	b.MaxElapsedTime = cfg.MaxElapsedTime
+	b.RandomizationFactor = 0.5
+	b.Reset()
+	return b
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/ugurcancaykara/odd-service/gen"
 	"github.com/ugurcancaykara/odd-service/metadata/pkg/model"
 	"github.com/ugurcancaykara/odd-service/movie/internal/controller/movie"
+	moviemodel "github.com/ugurcancaykara/odd-service/movie/pkg/model"
+	ratingmodel "github.com/ugurcancaykara/odd-service/rating/pkg/model"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -22,14 +24,16 @@ func New(ctrl *movie.Controller) *Handler {
 	return &Handler{ctrl: ctrl}
 }
 
-// GetMovieDetails returns moviie details by id.
+// GetMovieDetails returns the details of a movie, or a record of another type, by id.
 func (h *Handler) GetMovieDetails(ctx context.Context, req *gen.GetMovieDetailsRequest) (*gen.GetMovieDetailsResponse, error) {
 	if req == nil || req.MovieId == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
 	}
-	m, err := h.ctrl.Get(ctx, req.MovieId)
+	m, err := h.ctrl.Get(ctx, ratingmodel.RecordType(req.RecordType), req.MovieId, ratingmodel.UserID(req.UserId))
 	if err != nil && errors.Is(err, movie.ErrNotFound) {
 		return nil, status.Errorf(codes.NotFound, err.Error())
+	} else if err != nil && errors.Is(err, movie.ErrUnsupportedRecordType) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
 	} else if err != nil {
 		return nil, status.Errorf(codes.Internal, err.Error())
 	}
@@ -38,10 +42,53 @@ func (h *Handler) GetMovieDetails(ctx context.Context, req *gen.GetMovieDetailsR
 	if m.Rating != nil {
 		rating = *m.Rating
 	}
-	return &gen.GetMovieDetailsResponse{
-		MovieDetails: &gen.MovieDetails{
-			Metadata: model.MetadataToProto(&m.Metadata),
-			Rating:   rating,
-		},
-	}, nil
+	details := &gen.MovieDetails{
+		Metadata: model.MetadataToProto(&m.Metadata),
+		Rating:   rating,
+	}
+	if m.UserRating != nil {
+		userRating := int32(*m.UserRating)
+		details.UserRating = &userRating
+	}
+	return &gen.GetMovieDetailsResponse{MovieDetails: details}, nil
+}
+
+// ListTopRatedMovies returns the best rated movies.
+func (h *Handler) ListTopRatedMovies(ctx context.Context, req *gen.ListTopRatedMoviesRequest) (*gen.ListTopRatedMoviesResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	movies, err := h.ctrl.ListTopRated(ctx, ratingmodel.RecordType(req.RecordType), ratingmodel.AggregationStrategy(req.Strategy), int(req.MinVotes), int(req.Limit))
+	if err != nil && errors.Is(err, movie.ErrUnsupportedRecordType) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	} else if err != nil {
+		return nil, status.Errorf(status.Code(err), err.Error())
+	}
+	return &gen.ListTopRatedMoviesResponse{Movies: rankedMoviesToProto(movies)}, nil
+}
+
+// ListTrendingMovies returns the movies rated most often within a window.
+func (h *Handler) ListTrendingMovies(ctx context.Context, req *gen.ListTrendingMoviesRequest) (*gen.ListTrendingMoviesResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	movies, err := h.ctrl.ListTrending(ctx, ratingmodel.RecordType(req.RecordType), req.Window.AsDuration(), int(req.Limit))
+	if err != nil && errors.Is(err, movie.ErrUnsupportedRecordType) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	} else if err != nil {
+		return nil, status.Errorf(status.Code(err), err.Error())
+	}
+	return &gen.ListTrendingMoviesResponse{Movies: rankedMoviesToProto(movies)}, nil
+}
+
+func rankedMoviesToProto(movies []moviemodel.RankedMovie) []*gen.RankedMovie {
+	res := make([]*gen.RankedMovie, 0, len(movies))
+	for i := range movies {
+		res = append(res, &gen.RankedMovie{
+			Metadata: model.MetadataToProto(&movies[i].Metadata),
+			Score:    movies[i].Score,
+			Votes:    int64(movies[i].Votes),
+		})
+	}
+	return res
 }
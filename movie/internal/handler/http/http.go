@@ -3,10 +3,11 @@ package http
 import (
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/ugurcancaykara/odd-service/movie/internal/controller/movie"
+	ratingmodel "github.com/ugurcancaykara/odd-service/rating/pkg/model"
 )
 
 // Handler defines a movie handler.
@@ -19,19 +20,24 @@ func New(ctrl *movie.Controller) *Handler {
 	return &Handler{ctrl}
 }
 
-// GetMovieDetails handles GET /movie requests.
+// GetMovieDetails handles GET /movie requests, the optional type parameter selects the record type.
 func (h *Handler) GetMovieDetails(w http.ResponseWriter, req *http.Request) {
 	id := req.FormValue("id")
-	details, err := h.ctrl.Get(req.Context(), id)
+	userID := ratingmodel.UserID(req.FormValue("userId"))
+	recordType := ratingmodel.RecordType(req.FormValue("type"))
+	details, err := h.ctrl.Get(req.Context(), recordType, id, userID)
 	if err != nil && errors.Is(err, movie.ErrNotFound) {
 		w.WriteHeader(http.StatusNotFound)
 		return
+	} else if err != nil && errors.Is(err, movie.ErrUnsupportedRecordType) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
 	} else if err != nil {
-		log.Printf("Get error: %v\n", err)
+		slog.ErrorContext(req.Context(), "Failed to get details", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 	if err := json.NewEncoder(w).Encode(details); err != nil {
-		log.Printf("Encode error: %v\n", err)
+		slog.ErrorContext(req.Context(), "Failed to encode response", "error", err)
 	}
 }
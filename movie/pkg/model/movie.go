@@ -1,9 +1,21 @@
 package model
 
-import "github.com/ugurcancaykara/odd-service/metadata/pkg/model"
+import (
+	"github.com/ugurcancaykara/odd-service/metadata/pkg/model"
+	ratingmodel "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
 
-// MovieDetails includes movie metadata its aggregated rating.
+// MovieDetails includes the metadata of a movie, or a record of another type, and its aggregated rating.
+// UserRating is the rating of the requesting user, if any.
 type MovieDetails struct {
-	Rating   *float64       `json:"rating,omitempty"`
+	Rating     *float64                 `json:"rating,omitempty"`
+	UserRating *ratingmodel.RatingValue `json:"userRating,omitempty"`
+	Metadata   model.Metadata           `json:"metadata"`
+}
+
+// RankedMovie defines a movie, or a record of another type, in a top rated or trending leaderboard with its score and number of votes.
+type RankedMovie struct {
 	Metadata model.Metadata `json:"metadata"`
+	Score    float64        `json:"score"`
+	Votes    int            `json:"votes"`
 }
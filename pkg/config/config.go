@@ -0,0 +1,365 @@
+// Package config loads service configs in layers, each layer overriding the values set by the previous ones:
+//
+//  1. the base file, configs/base.yaml unless set with the -config flag or the CONFIG_PATH variable,
+//  2. the overlay of an environment, <env>.yaml next to the base file, selected with the -env flag or
+//     the <SERVICE>_ENV or APP_ENV variables,
+//  3. the keys under a Consul KV prefix, selected with the -kv-prefix flag or the <SERVICE>_KV_PREFIX variable,
+//     named after the path of a value, e.g. <prefix>/rateLimit/user/rate for rateLimit.user.rate,
+//  4. environment variables named after the service and the path of a value, e.g. RATING_API_PORT for api.port
+//     or RATING_INGESTER_KAFKA_GROUP_ID for ingester.kafka.groupId,
+//  5. command-line flags named after the path of a value, e.g. -api.port or -ingester.kafka.groupId.
+//
+// Values of maps can only be set in files. Lists are set from comma-separated values.
+// Fields tagged with `validate:"required"` must be set by one of the layers.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath defines the base config file used unless another one is selected.
+const DefaultPath = "configs/base.yaml"
+
+// ErrInvalid is returned when a loaded config misses required values or fails its validation.
+var ErrInvalid = errors.New("invalid config")
+
+// validator is implemented by configs with rules beyond required values.
+type validator interface {
+	Validate() error
+}
+
+// Loader defines a loader of the config of a service.
+type Loader struct {
+	service string
+	// lookupEnv reads environment variables and readKV the keys under a Consul KV prefix,
+	// they are replaced in tests.
+	lookupEnv func(string) (string, bool)
+	readKV    func(addr string, prefix string) (map[string]string, error)
+}
+
+// NewLoader creates a new config loader for a service.
+func NewLoader(service string) *Loader {
+	l := &Loader{service: service, lookupEnv: os.LookupEnv}
+	l.readKV = newConsulKV().read
+	return l
+}
+
+// Load loads the config of a service into cfg, a pointer to a struct with yaml tags, from its config files,
+// environment variables and command-line arguments.
+func Load(service string, cfg any, args []string) error {
+	return NewLoader(service).Load(cfg, args)
+}
+
+// Load loads a config into cfg, a pointer to a struct with yaml tags, from its config files,
+// environment variables and command-line arguments.
+func (l *Loader) Load(cfg any, args []string) error {
+	root := reflect.ValueOf(cfg)
+	if root.Kind() != reflect.Pointer || root.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config must be a pointer to a struct, got %T", cfg)
+	}
+
+	fs := flag.NewFlagSet(l.service, flag.ContinueOnError)
+	path := fs.String("config", l.env("CONFIG_PATH", DefaultPath), "base config file")
+	env := fs.String("env", l.env(strings.ToUpper(l.service)+"_ENV", l.env("APP_ENV", "")), "environment whose config overlay is applied")
+	kvPrefix := fs.String("kv-prefix", l.env(strings.ToUpper(l.service)+"_KV_PREFIX", ""), "Consul KV prefix whose keys override the config files")
+	kvAddr := fs.String("kv-addr", l.env("CONSUL_HTTP_ADDR", "localhost:8500"), "Consul agent holding the KV prefix")
+	var overrides []override
+	err := walk(root.Elem(), nil, func(p []string, v reflect.Value) error {
+		name := strings.Join(p, ".")
+		fs.Var(&flagValue{name: name, isBool: v.Kind() == reflect.Bool, overrides: &overrides}, name, "overrides "+name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := decodeFile(*path, cfg); err != nil {
+		return err
+	}
+	if *env != "" {
+		if err := decodeFile(filepath.Join(filepath.Dir(*path), *env+".yaml"), cfg); err != nil {
+			return err
+		}
+	}
+	if *kvPrefix != "" {
+		pairs, err := l.readKV(*kvAddr, *kvPrefix)
+		if err != nil {
+			return fmt.Errorf("read Consul KV prefix %s: %w", *kvPrefix, err)
+		}
+		for key, value := range pairs {
+			v, ok := lookup(root.Elem(), key)
+			if !ok {
+				return fmt.Errorf("consul KV key %s/%s: unknown config value", *kvPrefix, key)
+			}
+			if err := set(v, value); err != nil {
+				return fmt.Errorf("consul KV key %s/%s: %w", *kvPrefix, key, err)
+			}
+		}
+	}
+	err = walk(root.Elem(), nil, func(p []string, v reflect.Value) error {
+		name := l.envName(p...)
+		s, ok := l.lookupEnv(name)
+		if !ok {
+			return nil
+		}
+		if err := set(v, s); err != nil {
+			return fmt.Errorf("environment variable %s: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, o := range overrides {
+		v, _ := lookup(root.Elem(), o.path)
+		if err := set(v, o.value); err != nil {
+			return fmt.Errorf("flag -%s: %w", o.path, err)
+		}
+	}
+	return validate(root.Elem(), cfg)
+}
+
+func (l *Loader) env(name string, fallback string) string {
+	if v, ok := l.lookupEnv(name); ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+// envName returns the environment variable overriding the value at a path, e.g. RATING_INGESTER_KAFKA_GROUP_ID.
+func (l *Loader) envName(path ...string) string {
+	parts := []string{strings.ToUpper(l.service)}
+	for _, p := range path {
+		parts = append(parts, screamingSnake(p))
+	}
+	return strings.Join(parts, "_")
+}
+
+func screamingSnake(s string) string {
+	var b strings.Builder
+	prev := ' '
+	for _, r := range s {
+		if unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+		prev = r
+	}
+	return b.String()
+}
+
+func decodeFile(path string, cfg any) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := yaml.NewDecoder(f).Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// override defines a value set with a command-line flag, applied after the environment variables.
+type override struct {
+	path  string
+	value string
+}
+
+type flagValue struct {
+	name      string
+	isBool    bool
+	overrides *[]override
+}
+
+func (f *flagValue) String() string {
+	return ""
+}
+
+func (f *flagValue) Set(s string) error {
+	*f.overrides = append(*f.overrides, override{f.name, s})
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// walk calls fn for every value of a config struct which can be set from a string, with its yaml path.
+func walk(v reflect.Value, path []string, fn func(path []string, v reflect.Value) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := yamlName(sf)
+		if name == "" {
+			continue
+		}
+		fv := v.Field(i)
+		p := append(path[:len(path):len(path)], name)
+		switch {
+		case fv.Kind() == reflect.Struct:
+			if err := walk(fv, p, fn); err != nil {
+				return err
+			}
+		case settable(fv.Type()):
+			if err := fn(p, fv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func yamlName(sf reflect.StructField) string {
+	if !sf.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return strings.ToLower(sf.Name)
+	}
+	return name
+}
+
+func settable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return settable(t.Elem())
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// lookup returns the value at a dotted yaml path of a config struct if it can be set from a string.
+func lookup(v reflect.Value, path string) (reflect.Value, bool) {
+	for _, name := range strings.Split(path, ".") {
+		if v.Kind() != reflect.Struct {
+			return v, false
+		}
+		found := false
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if yamlName(t.Field(i)) == name {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return v, false
+		}
+	}
+	return v, v.Kind() != reflect.Struct && settable(v.Type())
+}
+
+// set sets a value from its string representation.
+func set(v reflect.Value, s string) error {
+	switch {
+	case v.Kind() == reflect.Pointer:
+		p := reflect.New(v.Type().Elem())
+		if err := set(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.CanInt():
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case v.CanUint():
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case v.CanFloat():
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
+
+// validate checks that the required values are set and runs the validation of the config, if any.
+func validate(v reflect.Value, cfg any) error {
+	var missing []string
+	var check func(v reflect.Value, path []string)
+	check = func(v reflect.Value, path []string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name := yamlName(sf)
+			if name == "" {
+				continue
+			}
+			p := append(path[:len(path):len(path)], name)
+			fv := v.Field(i)
+			if sf.Tag.Get("validate") == "required" && fv.IsZero() {
+				missing = append(missing, strings.Join(p, "."))
+			}
+			if fv.Kind() == reflect.Struct && fv.Type() != durationType {
+				check(fv, p)
+			}
+		}
+	}
+	check(v, nil)
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing %s", ErrInvalid, strings.Join(missing, ", "))
+	}
+	if c, ok := cfg.(validator); ok {
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	}
+	return nil
+}
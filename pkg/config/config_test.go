@@ -0,0 +1,209 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	API struct {
+		Host string `yaml:"host"`
+		Port string `yaml:"port" validate:"required"`
+	} `yaml:"api"`
+	Kafka struct {
+		Addr    string        `yaml:"addr"`
+		GroupID string        `yaml:"groupId"`
+		Topics  []string      `yaml:"topics"`
+		Timeout time.Duration `yaml:"timeout"`
+		Enabled bool          `yaml:"enabled"`
+	} `yaml:"kafka"`
+	Weight  *float64           `yaml:"weight"`
+	Weights map[string]float64 `yaml:"weights"`
+}
+
+func writeConfigs(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return filepath.Join(dir, "base.yaml")
+}
+
+func newTestLoader(env map[string]string) *Loader {
+	l := NewLoader("test")
+	l.lookupEnv = func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	return l
+}
+
+func TestLoader_Load(t *testing.T) {
+	base := writeConfigs(t, map[string]string{
+		"base.yaml": `
+api:
+  host: localhost
+  port: "8080"
+kafka:
+  addr: localhost
+  groupId: base
+  topics: [ratings]
+  timeout: 1s
+weights:
+  a: 1
+  b: 2
+`,
+		"prod.yaml": `
+kafka:
+  addr: kafka.prod
+weights:
+  b: 3
+`,
+	})
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		expected func(cfg *testConfig)
+	}{
+		{
+			name: "Base file",
+			env:  map[string]string{"CONFIG_PATH": base},
+		},
+		{
+			name: "Environment overlay",
+			env:  map[string]string{"CONFIG_PATH": base, "TEST_ENV": "prod"},
+			expected: func(cfg *testConfig) {
+				cfg.Kafka.Addr = "kafka.prod"
+				cfg.Weights["b"] = 3
+			},
+		},
+		{
+			name: "Environment variables",
+			env: map[string]string{
+				"CONFIG_PATH":           base,
+				"TEST_KAFKA_GROUP_ID":   "env",
+				"TEST_KAFKA_TOPICS":     "ratings, ratings-dlq",
+				"TEST_KAFKA_TIMEOUT":    "5s",
+				"TEST_KAFKA_ENABLED":    "true",
+				"TEST_WEIGHT":           "0.5",
+				"TEST_WEIGHTS":          "ignored",
+				"TEST_UNRELATED_CONFIG": "ignored",
+			},
+			expected: func(cfg *testConfig) {
+				cfg.Kafka.GroupID = "env"
+				cfg.Kafka.Topics = []string{"ratings", "ratings-dlq"}
+				cfg.Kafka.Timeout = 5 * time.Second
+				cfg.Kafka.Enabled = true
+				weight := 0.5
+				cfg.Weight = &weight
+			},
+		},
+		{
+			name: "Flags override everything",
+			env:  map[string]string{"TEST_API_PORT": "9090", "TEST_KAFKA_GROUP_ID": "env"},
+			args: []string{"-config", base, "-env", "prod", "-api.port", "7070", "-kafka.enabled", "-kafka.addr=flag"},
+			expected: func(cfg *testConfig) {
+				cfg.API.Port = "7070"
+				cfg.Kafka.Addr = "flag"
+				cfg.Kafka.GroupID = "env"
+				cfg.Kafka.Enabled = true
+				cfg.Weights["b"] = 3
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want testConfig
+			want.API.Host = "localhost"
+			want.API.Port = "8080"
+			want.Kafka.Addr = "localhost"
+			want.Kafka.GroupID = "base"
+			want.Kafka.Topics = []string{"ratings"}
+			want.Kafka.Timeout = time.Second
+			want.Weights = map[string]float64{"a": 1, "b": 2}
+			if tt.expected != nil {
+				tt.expected(&want)
+			}
+			var got testConfig
+			require.NoError(t, newTestLoader(tt.env).Load(&got, tt.args))
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestLoader_LoadErrors(t *testing.T) {
+	base := writeConfigs(t, map[string]string{"base.yaml": "api:\n  host: localhost\n"})
+
+	var cfg testConfig
+	err := newTestLoader(nil).Load(&cfg, []string{"-config", base})
+	assert.ErrorIs(t, err, ErrInvalid)
+	assert.ErrorContains(t, err, "missing api.port")
+
+	assert.NoError(t, newTestLoader(map[string]string{"TEST_API_PORT": "8080"}).Load(&cfg, []string{"-config", base}))
+
+	err = newTestLoader(map[string]string{"TEST_API_PORT": "8080", "TEST_KAFKA_TIMEOUT": "soon"}).Load(&cfg, []string{"-config", base})
+	assert.ErrorContains(t, err, "TEST_KAFKA_TIMEOUT")
+
+	err = newTestLoader(nil).Load(&cfg, []string{"-config", base, "-env", "missing"})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLoader_LoadKV(t *testing.T) {
+	base := writeConfigs(t, map[string]string{"base.yaml": "api:\n  port: \"8080\"\nkafka:\n  addr: localhost\n"})
+	l := newTestLoader(map[string]string{"TEST_KV_PREFIX": "test/config", "TEST_API_PORT": "9090"})
+	kv := map[string]string{"kafka.addr": "kafka.kv", "api.port": "7070"}
+	l.readKV = func(addr string, prefix string) (map[string]string, error) {
+		assert.Equal(t, "localhost:8500", addr)
+		assert.Equal(t, "test/config", prefix)
+		return kv, nil
+	}
+
+	var cfg testConfig
+	require.NoError(t, l.Load(&cfg, []string{"-config", base}))
+	assert.Equal(t, "kafka.kv", cfg.Kafka.Addr)
+	// Environment variables override Consul KV.
+	assert.Equal(t, "9090", cfg.API.Port)
+
+	kv["kafka.unknown"] = "x"
+	assert.ErrorContains(t, l.Load(&cfg, []string{"-config", base}), "unknown config value")
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	base := writeConfigs(t, map[string]string{"base.yaml": "api:\n  port: \"8080\"\n"})
+	w, err := Watch[testConfig](newTestLoader(nil), []string{"-config", base})
+	require.NoError(t, err)
+	v1 := w.Version()
+	assert.Equal(t, 1, v1.Generation)
+	var reloaded []string
+	w.OnReload(func(cfg *testConfig) {
+		reloaded = append(reloaded, cfg.API.Port)
+	})
+
+	changed, err := w.Reload()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	require.NoError(t, os.WriteFile(base, []byte("api:\n  port: \"9090\"\n"), 0o644))
+	changed, err = w.Reload()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []string{"9090"}, reloaded)
+	assert.Equal(t, "9090", w.Config().API.Port)
+	assert.Equal(t, 2, w.Version().Generation)
+	assert.NotEqual(t, v1.ID, w.Version().ID)
+
+	// Invalid configs are not applied.
+	require.NoError(t, os.WriteFile(base, []byte("api:\n  host: localhost\n"), 0o644))
+	_, err = w.Reload()
+	assert.ErrorIs(t, err, ErrInvalid)
+	assert.Equal(t, "9090", w.Config().API.Port)
+	assert.Equal(t, []string{"9090"}, reloaded)
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"strings"
+	"sync"
+
+	consul "github.com/hashicorp/consul/api"
+)
+
+// consulKV reads config values from Consul KV, reusing a client per agent address.
+type consulKV struct {
+	mu      sync.Mutex
+	clients map[string]*consul.Client
+}
+
+func newConsulKV() *consulKV {
+	return &consulKV{clients: map[string]*consul.Client{}}
+}
+
+// read returns the values under a prefix keyed by their dotted config path, e.g. rateLimit.user.rate
+// for <prefix>/rateLimit/user/rate.
+func (c *consulKV) read(addr string, prefix string) (map[string]string, error) {
+	client, err := c.client(addr)
+	if err != nil {
+		return nil, err
+	}
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+	pairs, _, err := client.KV().List(prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]string{}
+	for _, p := range pairs {
+		key := strings.Trim(strings.TrimPrefix(p.Key, prefix), "/")
+		if key == "" || strings.HasSuffix(p.Key, "/") {
+			// Folders hold no values.
+			continue
+		}
+		res[strings.ReplaceAll(key, "/", ".")] = string(p.Value)
+	}
+	return res, nil
+}
+
+func (c *consulKV) client(addr string) (*consul.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.clients[addr]; ok {
+		return client, nil
+	}
+	cfg := consul.DefaultConfig()
+	cfg.Address = addr
+	client, err := consul.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.clients[addr] = client
+	return client, nil
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Version defines a version of a loaded config.
+type Version struct {
+	// ID is derived from the loaded values, it changes whenever a value changes.
+	ID string `json:"id"`
+	// Generation counts the loaded versions, starting at 1.
+	Generation int       `json:"generation"`
+	LoadedAt   time.Time `json:"loadedAt"`
+}
+
+// Watcher defines a config reloaded whenever the values of its layers change.
+// Only the settings applied by the reload handlers change at runtime, others need a restart.
+type Watcher[T any] struct {
+	loader *Loader
+	args   []string
+
+	mu       sync.RWMutex
+	cfg      *T
+	version  Version
+	handlers []func(*T)
+}
+
+// Watch loads a config and creates a watcher reloading it with the same command-line arguments.
+func Watch[T any](loader *Loader, args []string) (*Watcher[T], error) {
+	w := &Watcher[T]{loader: loader, args: args}
+	cfg, id, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+	w.cfg = cfg
+	w.version = Version{ID: id, Generation: 1, LoadedAt: time.Now()}
+	return w, nil
+}
+
+// Config returns the current config, which must not be modified.
+func (w *Watcher[T]) Config() *T {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.cfg
+}
+
+// Version returns the version of the current config.
+func (w *Watcher[T]) Version() Version {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.version
+}
+
+// OnReload registers a handler applying the settings of a reloaded config.
+func (w *Watcher[T]) OnReload(fn func(cfg *T)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers = append(w.handlers, fn)
+}
+
+// Reload loads the config again and, if a value changed, replaces the current config and calls the reload handlers.
+// It reports whether the config changed. An invalid config is not applied.
+func (w *Watcher[T]) Reload() (bool, error) {
+	cfg, id, err := w.load()
+	if err != nil {
+		return false, err
+	}
+	w.mu.Lock()
+	if id == w.version.ID {
+		w.mu.Unlock()
+		return false, nil
+	}
+	prev := w.version
+	w.cfg = cfg
+	w.version = Version{ID: id, Generation: prev.Generation + 1, LoadedAt: time.Now()}
+	handlers := append([]func(*T){}, w.handlers...)
+	w.mu.Unlock()
+	for _, fn := range handlers {
+		fn(cfg)
+	}
+	slog.Info("Reloaded config", "service", w.loader.service, "version", id, "generation", prev.Generation+1, "previous_version", prev.ID)
+	return true, nil
+}
+
+// Run reloads the config every interval until the context is done.
+func (w *Watcher[T]) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := w.Reload(); err != nil {
+				slog.Error("Failed to reload config", "service", w.loader.service, "version", w.Version().ID, "error", err)
+			}
+		}
+	}
+}
+
+// ServeHTTP handles admin requests for the version of the current config.
+func (w *Watcher[T]) ServeHTTP(rw http.ResponseWriter, _ *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(w.Version()); err != nil {
+		slog.Error("Failed to encode config version", "error", err)
+	}
+}
+
+func (w *Watcher[T]) load() (*T, string, error) {
+	cfg := new(T)
+	if err := w.loader.Load(cfg, w.args); err != nil {
+		return nil, "", err
+	}
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(b)
+	return cfg, hex.EncodeToString(sum[:6]), nil
+}
@@ -2,10 +2,9 @@ package consul
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"net"
 	"strconv"
-	"strings"
 
 	consul "github.com/hashicorp/consul/api"
 	"github.com/ugurcancaykara/odd-service/pkg/discovery"
@@ -27,22 +26,35 @@ func NewRegistry(addr string) (*Registry, error) {
 	return &Registry{client: client}, nil
 }
 
-// Register creates a service record in the registry.
-func (r *Registry) Register(ctx context.Context, instanceID string, serviceName string, hostPort string) error {
-	parts := strings.Split(hostPort, ":")
-	if len(parts) != 2 {
-		return errors.New("hostPort must be in a form of <host>:<port>, example: localhost:8081")
+// Register creates a service record in the registry. The additional ports are recorded as tagged addresses
+// of the instance, e.g. http and metrics.
+func (r *Registry) Register(ctx context.Context, instanceID string, serviceName string, hostPort string, ports ...discovery.Port) error {
+	host, p, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return fmt.Errorf("hostPort must be in a form of <host>:<port>, example: localhost:8081 or [::1]:8081: %w", err)
 	}
-	port, err := strconv.Atoi(parts[1])
+	port, err := strconv.Atoi(p)
 	if err != nil {
 		return err
 	}
+	var tagged map[string]consul.ServiceAddress
+	if len(ports) > 0 {
+		tagged = make(map[string]consul.ServiceAddress, len(ports))
+		for _, p := range ports {
+			addr := consul.ServiceAddress{Address: p.Host, Port: p.Port}
+			if addr.Address == "" {
+				addr.Address = host
+			}
+			tagged[p.Name] = addr
+		}
+	}
 	return r.client.Agent().ServiceRegister(&consul.AgentServiceRegistration{
-		Address: parts[0],
-		ID:      instanceID,
-		Name:    serviceName,
-		Port:    port,
-		Check:   &consul.AgentServiceCheck{CheckID: instanceID, TTL: "5s"},
+		Address:         host,
+		ID:              instanceID,
+		Name:            serviceName,
+		Port:            port,
+		TaggedAddresses: tagged,
+		Check:           &consul.AgentServiceCheck{CheckID: instanceID, TTL: "5s"},
 	})
 }
 
@@ -61,7 +73,12 @@ func (r *Registry) ServiceAddresses(ctx context.Context, serviceName string) ([]
 	}
 	var res []string
 	for _, e := range entries {
-		res = append(res, fmt.Sprintf("%s:%d", e.Service.Address, e.Service.Port))
+		// Consul leaves the service address empty for services registered without one: they listen on the node address.
+		host := e.Service.Address
+		if host == "" && e.Node != nil {
+			host = e.Node.Address
+		}
+		res = append(res, net.JoinHostPort(host, strconv.Itoa(e.Service.Port)))
 	}
 	return res, nil
 }
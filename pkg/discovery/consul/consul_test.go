@@ -0,0 +1,96 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	consul "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/pkg/discovery"
+)
+
+func TestRegistry_Register(t *testing.T) {
+	tests := []struct {
+		name       string
+		hostPort   string
+		ports      []discovery.Port
+		wantHost   string
+		wantPort   int
+		wantTagged map[string]consul.ServiceAddress
+		wantErr    bool
+	}{
+		{
+			name:     "ipv4",
+			hostPort: "10.1.0.7:8082",
+			ports:    []discovery.Port{{Name: discovery.PortHTTP, Port: 8080}, {Name: discovery.PortMetrics, Host: "127.0.0.1", Port: 9082}},
+			wantHost: "10.1.0.7",
+			wantPort: 8082,
+			wantTagged: map[string]consul.ServiceAddress{
+				discovery.PortHTTP:    {Address: "10.1.0.7", Port: 8080},
+				discovery.PortMetrics: {Address: "127.0.0.1", Port: 9082},
+			},
+		},
+		{
+			name:     "ipv6",
+			hostPort: "[::1]:8081",
+			ports:    []discovery.Port{{Name: discovery.PortMetrics, Port: 9081}},
+			wantHost: "::1",
+			wantPort: 8081,
+			wantTagged: map[string]consul.ServiceAddress{
+				discovery.PortMetrics: {Address: "::1", Port: 9081},
+			},
+		},
+		{name: "empty host", hostPort: ":8081", wantPort: 8081},
+		{name: "bare host", hostPort: "localhost", wantErr: true},
+		{name: "invalid port", hostPort: "localhost:grpc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *consul.AgentServiceRegistration
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/agent/service/register", r.URL.Path)
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+			}))
+			defer srv.Close()
+			r, err := NewRegistry(strings.TrimPrefix(srv.URL, "http://"))
+			require.NoError(t, err)
+
+			err = r.Register(context.Background(), "rating-1", "rating", tt.hostPort, tt.ports...)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got, "invalid addresses aren't registered")
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, got)
+			assert.Equal(t, "rating-1", got.ID)
+			assert.Equal(t, "rating", got.Name)
+			assert.Equal(t, tt.wantHost, got.Address)
+			assert.Equal(t, tt.wantPort, got.Port)
+			assert.Equal(t, tt.wantTagged, got.TaggedAddresses)
+		})
+	}
+}
+
+func TestRegistry_ServiceAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/health/service/rating", r.URL.Path)
+		require.NoError(t, json.NewEncoder(w).Encode([]*consul.ServiceEntry{
+			{Node: &consul.Node{Address: "10.1.0.7"}, Service: &consul.AgentService{Address: "10.1.0.8", Port: 8082}},
+			{Node: &consul.Node{Address: "10.1.0.9"}, Service: &consul.AgentService{Port: 8081}},
+			{Node: &consul.Node{Address: "::1"}, Service: &consul.AgentService{Port: 8083}},
+		}))
+	}))
+	defer srv.Close()
+	r, err := NewRegistry(strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(t, err)
+
+	got, err := r.ServiceAddresses(context.Background(), "rating")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.1.0.8:8082", "10.1.0.9:8081", "[::1]:8083"}, got)
+}
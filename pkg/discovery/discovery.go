@@ -10,8 +10,9 @@ import (
 
 // Registry defines a service registry.
 type Registry interface {
-	// Register creates a service instance record in the registry.
-	Register(ctx context.Context, instanceID string, serviceName string, hostPort string) error
+	// Register creates a service instance record in the registry. hostPort is the address of the gRPC API
+	// of the instance, ports are the additional ports it serves, e.g. http or metrics.
+	Register(ctx context.Context, instanceID string, serviceName string, hostPort string, ports ...Port) error
 	// Deregister removes a service insttance record from the registry.
 	Deregister(ctx context.Context, instanceID string, serviceName string) error
 	// ServiceAddresses returns the list of addresses of active instances of the given service.
@@ -20,6 +21,20 @@ type Registry interface {
 	ReportHealthyState(instanceID string, serviceName string) error
 }
 
+// Port defines a named port served by a service instance besides its gRPC API.
+type Port struct {
+	Name string
+	// Host is the host the port is reached at, the host of the gRPC API if empty.
+	Host string
+	Port int
+}
+
+// Names of the additional ports of service instances.
+const (
+	PortHTTP    = "http"
+	PortMetrics = "metrics"
+)
+
 // ErrNotFound is returned when no service addresses are found.
 var ErrNotFound = errors.New("no service addresses found")
 
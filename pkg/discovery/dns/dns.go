@@ -0,0 +1,272 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/ugurcancaykara/odd-service/pkg/discovery"
+	"github.com/ugurcancaykara/odd-service/pkg/metrics"
+)
+
+// resolvConf contains the name servers and search domains of the host.
+const resolvConf = "/etc/resolv.conf"
+
+// udpSize defines the EDNS0 buffer size advertised to name servers, large enough for the SRV records
+// of a few dozen instances along with their addresses.
+const udpSize = 4096
+
+// resolver looks up SRV records, replaced in tests.
+type resolver interface {
+	// lookupSRV returns the SRV records of a name and how long they may be cached.
+	lookupSRV(ctx context.Context, name string) ([]*dns.SRV, time.Duration, error)
+}
+
+// cacheEntry defines the cached addresses of a service.
+type cacheEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// Registry defines a DNS-based service registry resolving the addresses of a service
+// from the `_grpc._tcp.<service>` SRV records. Resolved addresses are cached for the TTL of the records.
+// Instances are registered with the DNS server by other means, so registration and health reports are no-ops.
+type Registry struct {
+	resolver resolver
+	domain   string
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// NewRegistry creates a new DNS-based service registry querying a name server, e.g. localhost:8600.
+// If server is empty, the name servers and search domains of /etc/resolv.conf are used.
+// A non-empty domain is appended to the SRV names, e.g. service.consul or movies.svc.cluster.local.
+func NewRegistry(server string, domain string) (*Registry, error) {
+	var config *dns.ClientConfig
+	if server == "" {
+		c, err := dns.ClientConfigFromFile(resolvConf)
+		if err != nil {
+			return nil, err
+		}
+		config = c
+	} else {
+		host, port, err := net.SplitHostPort(server)
+		if err != nil {
+			return nil, fmt.Errorf("server must be in a form of <host>:<port>, example: localhost:8600: %w", err)
+		}
+		config = &dns.ClientConfig{Servers: []string{host}, Port: port, Ndots: 1, Timeout: 5, Attempts: 2}
+	}
+	return newRegistry(&dnsResolver{client: &dns.Client{}, tcpClient: &dns.Client{Net: "tcp"}, config: config}, domain), nil
+}
+
+func newRegistry(r resolver, domain string) *Registry {
+	return &Registry{resolver: r, domain: strings.Trim(domain, "."), now: time.Now, cache: map[string]cacheEntry{}}
+}
+
+// Register is a no-op, SRV records are maintained by the DNS server.
+func (r *Registry) Register(context.Context, string, string, string, ...discovery.Port) error {
+	return nil
+}
+
+// Deregister is a no-op, SRV records are maintained by the DNS server.
+func (r *Registry) Deregister(context.Context, string, string) error {
+	return nil
+}
+
+// ReportHealthyState is a no-op, SRV records are maintained by the DNS server.
+func (r *Registry) ReportHealthyState(string, string) error {
+	return nil
+}
+
+// ServiceAddresses returns the addresses of the SRV records of the given service with the lowest priority.
+func (r *Registry) ServiceAddresses(ctx context.Context, serviceName string) ([]string, error) {
+	r.mu.Lock()
+	e, ok := r.cache[serviceName]
+	r.mu.Unlock()
+	hit := ok && r.now().Before(e.expires)
+	metrics.CacheLookup("dns", hit)
+	if hit {
+		return append([]string(nil), e.addrs...), nil
+	}
+	records, ttl, err := r.resolver.lookupSRV(ctx, r.name(serviceName))
+	if err != nil {
+		return nil, err
+	}
+	addrs := lowestPriority(records)
+	if len(addrs) == 0 {
+		return nil, discovery.ErrNotFound
+	}
+	r.mu.Lock()
+	if ttl > 0 {
+		r.cache[serviceName] = cacheEntry{addrs: addrs, expires: r.now().Add(ttl)}
+	} else {
+		delete(r.cache, serviceName)
+	}
+	r.mu.Unlock()
+	return append([]string(nil), addrs...), nil
+}
+
+// name returns the SRV name of a service.
+func (r *Registry) name(serviceName string) string {
+	name := "_grpc._tcp." + serviceName
+	if r.domain != "" {
+		name += "." + r.domain
+	}
+	return name
+}
+
+// lowestPriority returns the addresses of the records with the lowest priority value,
+// the others are only meant to be used when those are unreachable.
+func lowestPriority(records []*dns.SRV) []string {
+	var res []string
+	var priority uint16
+	for _, rr := range records {
+		if res != nil && rr.Priority > priority {
+			continue
+		}
+		if res == nil || rr.Priority < priority {
+			res, priority = []string{}, rr.Priority
+		}
+		res = append(res, net.JoinHostPort(strings.TrimSuffix(rr.Target, "."), strconv.Itoa(int(rr.Port))))
+	}
+	return res
+}
+
+// dnsResolver looks up SRV records from name servers.
+type dnsResolver struct {
+	client    *dns.Client
+	tcpClient *dns.Client
+	config    *dns.ClientConfig
+}
+
+// lookupSRV queries the name servers for the name, qualified with the search domains, until one answers.
+// The targets of the records are replaced with their addresses and the records may be cached for the lowest
+// TTL of the SRV and address records.
+func (r *dnsResolver) lookupSRV(ctx context.Context, name string) ([]*dns.SRV, time.Duration, error) {
+	err := discovery.ErrNotFound
+	for _, fqdn := range r.config.NameList(name) {
+		for _, server := range r.config.Servers {
+			addr := net.JoinHostPort(server, r.config.Port)
+			in, exchangeErr := r.exchange(ctx, addr, fqdn, dns.TypeSRV)
+			if exchangeErr != nil {
+				err = exchangeErr
+				continue
+			}
+			if in.Rcode == dns.RcodeNameError {
+				break
+			}
+			if in.Rcode != dns.RcodeSuccess {
+				err = fmt.Errorf("lookup %s: %s", fqdn, dns.RcodeToString[in.Rcode])
+				continue
+			}
+			var records []*dns.SRV
+			var ttl uint32
+			for _, rr := range in.Answer {
+				if srv, ok := rr.(*dns.SRV); ok {
+					if len(records) == 0 || srv.Hdr.Ttl < ttl {
+						ttl = srv.Hdr.Ttl
+					}
+					records = append(records, srv)
+				}
+			}
+			records, ttl, resolveErr := r.resolve(ctx, addr, records, in.Extra, ttl)
+			if resolveErr != nil {
+				err = resolveErr
+				continue
+			}
+			if len(records) == 0 {
+				break
+			}
+			return records, time.Duration(ttl) * time.Second, nil
+		}
+	}
+	return nil, 0, err
+}
+
+// resolve returns a record per address of the targets of SRV records, taken from the additional records
+// of the answer or looked up on the same name server. Targets without addresses are skipped. The returned TTL
+// is the lowest of ttl and the TTLs of the address records.
+func (r *dnsResolver) resolve(ctx context.Context, server string, records []*dns.SRV, extra []dns.RR, ttl uint32) ([]*dns.SRV, uint32, error) {
+	hosts := map[string][]dns.RR{}
+	for _, rr := range extra {
+		if _, ok := address(rr); ok {
+			name := strings.ToLower(rr.Header().Name)
+			hosts[name] = append(hosts[name], rr)
+		}
+	}
+	var res []*dns.SRV
+	for _, srv := range records {
+		if net.ParseIP(strings.TrimSuffix(srv.Target, ".")) != nil {
+			res = append(res, srv)
+			continue
+		}
+		target := strings.ToLower(srv.Target)
+		addrs, ok := hosts[target]
+		if !ok {
+			var err error
+			if addrs, err = r.lookupHost(ctx, server, target); err != nil {
+				return nil, 0, err
+			}
+			hosts[target] = addrs
+		}
+		for _, rr := range addrs {
+			ip, _ := address(rr)
+			ttl = min(ttl, rr.Header().Ttl)
+			resolved := *srv
+			resolved.Target = ip
+			res = append(res, &resolved)
+		}
+	}
+	return res, ttl, nil
+}
+
+// lookupHost returns the A and AAAA records of a host on a name server.
+func (r *dnsResolver) lookupHost(ctx context.Context, server string, host string) ([]dns.RR, error) {
+	var res []dns.RR
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		in, err := r.exchange(ctx, server, host, qtype)
+		if err != nil {
+			return nil, err
+		}
+		if in.Rcode != dns.RcodeSuccess && in.Rcode != dns.RcodeNameError {
+			return nil, fmt.Errorf("lookup %s: %s", host, dns.RcodeToString[in.Rcode])
+		}
+		for _, rr := range in.Answer {
+			if _, ok := address(rr); ok {
+				res = append(res, rr)
+			}
+		}
+	}
+	return res, nil
+}
+
+// exchange queries a name server over UDP, advertising the EDNS0 buffer size, and again over TCP
+// if the answer is truncated anyway.
+func (r *dnsResolver) exchange(ctx context.Context, server string, name string, qtype uint16) (*dns.Msg, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+	msg.SetEdns0(udpSize, false)
+	in, _, err := r.client.ExchangeContext(ctx, msg, server)
+	if err == nil && in.Truncated {
+		in, _, err = r.tcpClient.ExchangeContext(ctx, msg, server)
+	}
+	return in, err
+}
+
+// address returns the IP address of an A or AAAA record.
+func address(rr dns.RR) (string, bool) {
+	switch rr := rr.(type) {
+	case *dns.A:
+		return rr.A.String(), true
+	case *dns.AAAA:
+		return rr.AAAA.String(), true
+	}
+	return "", false
+}
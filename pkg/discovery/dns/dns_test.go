@@ -0,0 +1,182 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/pkg/discovery"
+)
+
+// fakeResolver returns fixed records and counts the lookups.
+type fakeResolver struct {
+	records map[string][]*dns.SRV
+	ttl     time.Duration
+	lookups int
+}
+
+func (r *fakeResolver) lookupSRV(_ context.Context, name string) ([]*dns.SRV, time.Duration, error) {
+	r.lookups++
+	records, ok := r.records[name]
+	if !ok {
+		return nil, 0, discovery.ErrNotFound
+	}
+	return records, r.ttl, nil
+}
+
+func srv(target string, port uint16, priority uint16) *dns.SRV {
+	return &dns.SRV{Target: target, Port: port, Priority: priority}
+}
+
+func TestServiceAddresses(t *testing.T) {
+	resolver := &fakeResolver{records: map[string][]*dns.SRV{
+		"_grpc._tcp.rating.service.consul": {
+			srv("rating-1.node.consul.", 8082, 1),
+			srv("fd00::7", 8082, 1),
+			srv("rating-backup.node.consul.", 8082, 10),
+		},
+		"_grpc._tcp.metadata.service.consul": {},
+	}}
+	r := newRegistry(resolver, "service.consul.")
+
+	tests := []struct {
+		service string
+		want    []string
+		wantErr error
+	}{
+		{service: "rating", want: []string{"rating-1.node.consul:8082", "[fd00::7]:8082"}},
+		{service: "metadata", wantErr: discovery.ErrNotFound},
+		{service: "movie", wantErr: discovery.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			got, err := r.ServiceAddresses(context.Background(), tt.service)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestServiceAddresses_Cache(t *testing.T) {
+	resolver := &fakeResolver{
+		records: map[string][]*dns.SRV{"_grpc._tcp.rating": {srv("10.1.0.7", 8082, 0)}},
+		ttl:     30 * time.Second,
+	}
+	r := newRegistry(resolver, "")
+	now := time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		got, err := r.ServiceAddresses(ctx, "rating")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.1.0.7:8082"}, got)
+	}
+	assert.Equal(t, 1, resolver.lookups, "records are cached for their TTL")
+
+	resolver.records["_grpc._tcp.rating"] = []*dns.SRV{srv("10.1.0.8", 8082, 0)}
+	now = now.Add(30 * time.Second)
+	got, err := r.ServiceAddresses(ctx, "rating")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.1.0.8:8082"}, got)
+	assert.Equal(t, 2, resolver.lookups, "expired records are resolved again")
+
+	resolver.ttl = 0
+	now = now.Add(time.Minute)
+	_, err = r.ServiceAddresses(ctx, "rating")
+	require.NoError(t, err)
+	_, err = r.ServiceAddresses(ctx, "rating")
+	require.NoError(t, err)
+	assert.Equal(t, 4, resolver.lookups, "records with a zero TTL aren't cached")
+}
+
+func TestRegistry_NameServer(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	require.NoError(t, err)
+	header := func(name string, rrtype uint16, ttl uint32) dns.RR_Header {
+		return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: ttl}
+	}
+	var mu sync.Mutex
+	var edns0, tcp int
+	mux := dns.NewServeMux()
+	mux.HandleFunc("rating.service.consul.", func(w dns.ResponseWriter, req *dns.Msg) {
+		mu.Lock()
+		if req.IsEdns0() != nil {
+			edns0++
+		}
+		mu.Unlock()
+		m := new(dns.Msg)
+		m.SetReply(req)
+		for i, ttl := range []uint32{60, 15} {
+			m.Answer = append(m.Answer, &dns.SRV{
+				Hdr:    header(req.Question[0].Name, dns.TypeSRV, ttl),
+				Target: []string{"rating-1.node.consul.", "rating-2.node.consul."}[i],
+				Port:   8082,
+			})
+		}
+		// Only the address of rating-1 is added, rating-2 is looked up.
+		m.Extra = append(m.Extra, &dns.A{Hdr: header("rating-1.node.consul.", dns.TypeA, 30), A: net.ParseIP("10.1.0.7")})
+		_ = w.WriteMsg(m)
+	})
+	mux.HandleFunc("metadata.service.consul.", func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		if w.RemoteAddr().Network() == "udp" {
+			m.Truncated = true
+		} else {
+			mu.Lock()
+			tcp++
+			mu.Unlock()
+			m.Answer = append(m.Answer, &dns.SRV{Hdr: header(req.Question[0].Name, dns.TypeSRV, 60), Target: "10.1.0.9.", Port: 8081})
+		}
+		_ = w.WriteMsg(m)
+	})
+	mux.HandleFunc("rating-2.node.consul.", func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		if req.Question[0].Qtype == dns.TypeAAAA {
+			m.Answer = append(m.Answer, &dns.AAAA{Hdr: header(req.Question[0].Name, dns.TypeAAAA, 10), AAAA: net.ParseIP("fd00::8")})
+		}
+		_ = w.WriteMsg(m)
+	})
+	mux.HandleFunc(".", func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(req, dns.RcodeNameError)
+		_ = w.WriteMsg(m)
+	})
+	for _, server := range []*dns.Server{{PacketConn: pc, Handler: mux}, {Listener: l, Handler: mux}} {
+		server := server
+		go func() { _ = server.ActivateAndServe() }()
+		t.Cleanup(func() { _ = server.Shutdown() })
+	}
+
+	r, err := NewRegistry(pc.LocalAddr().String(), "service.consul")
+	require.NoError(t, err)
+	resolver := r.resolver.(*dnsResolver)
+
+	records, ttl, err := resolver.lookupSRV(context.Background(), r.name("rating"))
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, 10*time.Second, ttl, "records are cached for the lowest TTL of the SRV and address records")
+
+	got, err := r.ServiceAddresses(context.Background(), "rating")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.1.0.7:8082", "[fd00::8]:8082"}, got, "targets are resolved to their addresses")
+	got, err = r.ServiceAddresses(context.Background(), "metadata")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.1.0.9:8081"}, got)
+	_, err = r.ServiceAddresses(context.Background(), "movie")
+	assert.ErrorIs(t, err, discovery.ErrNotFound)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, edns0, "queries advertise the EDNS0 buffer size")
+	assert.Equal(t, 1, tcp, "truncated answers are queried again over TCP")
+}
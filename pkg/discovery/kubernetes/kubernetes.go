@@ -0,0 +1,236 @@
+package kubernetes
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ugurcancaykara/odd-service/pkg/discovery"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+// DefaultPortName defines the name of the Kubernetes service port serving the gRPC API.
+const DefaultPortName = "grpc"
+
+// namespaceFile contains the namespace of the pod in clusters.
+const namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Registry defines a Kubernetes-based service registry resolving the addresses of a service
+// from the EndpointSlices of the Kubernetes service with the same name.
+// Kubernetes tracks the instances and their readiness itself, so registration and health reports are no-ops.
+type Registry struct {
+	client    kubernetes.Interface
+	namespace string
+	portName  string
+
+	mu sync.RWMutex
+	// sliceLister and endpointsLister list from the cache of Watch once it is synced, at most one is set.
+	sliceLister     discoverylisters.EndpointSliceNamespaceLister
+	endpointsLister corelisters.EndpointsNamespaceLister
+}
+
+// NewRegistry creates a new Kubernetes-based service registry resolving services in a namespace
+// with a Kubernetes API client. Addresses use the service port named portName, DefaultPortName if empty.
+func NewRegistry(client kubernetes.Interface, namespace string, portName string) *Registry {
+	if portName == "" {
+		portName = DefaultPortName
+	}
+	return &Registry{client: client, namespace: namespace, portName: portName}
+}
+
+// NewInClusterRegistry creates a new Kubernetes-based service registry connecting to the API server
+// with the service account of the pod. An empty namespace selects the namespace of the pod.
+func NewInClusterRegistry(namespace string, portName string) (*Registry, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		b, err := os.ReadFile(namespaceFile)
+		if err != nil {
+			return nil, err
+		}
+		namespace = strings.TrimSpace(string(b))
+	}
+	return NewRegistry(client, namespace, portName), nil
+}
+
+// Register is a no-op, Kubernetes adds the pods selected by a service to its endpoints.
+func (r *Registry) Register(context.Context, string, string, string, ...discovery.Port) error {
+	return nil
+}
+
+// Deregister is a no-op, Kubernetes removes terminating pods from the endpoints of their service.
+func (r *Registry) Deregister(context.Context, string, string) error {
+	return nil
+}
+
+// ReportHealthyState is a no-op, Kubernetes tracks the readiness of pods with their readiness probes.
+func (r *Registry) ReportHealthyState(string, string) error {
+	return nil
+}
+
+// Watch caches the EndpointSlices of the services in the namespace, or their Endpoints if the API server
+// doesn't serve EndpointSlices, until the context is done. Addresses are listed from the API server
+// until the cache is synced.
+func (r *Registry) Watch(ctx context.Context) error {
+	_, err := r.client.DiscoveryV1().EndpointSlices(r.namespace).List(ctx, metav1.ListOptions{Limit: 1})
+	var factory informers.SharedInformerFactory
+	var informer cache.SharedIndexInformer
+	var set func()
+	switch {
+	case apierrors.IsNotFound(err):
+		factory = informers.NewSharedInformerFactoryWithOptions(r.client, 0, informers.WithNamespace(r.namespace))
+		endpoints := factory.Core().V1().Endpoints()
+		informer = endpoints.Informer()
+		set = func() { r.endpointsLister = endpoints.Lister().Endpoints(r.namespace) }
+	case err != nil:
+		return err
+	default:
+		// Only the EndpointSlices of services are cached.
+		factory = informers.NewSharedInformerFactoryWithOptions(r.client, 0, informers.WithNamespace(r.namespace),
+			informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+				o.LabelSelector = discoveryv1.LabelServiceName
+			}))
+		slices := factory.Discovery().V1().EndpointSlices()
+		informer = slices.Informer()
+		set = func() { r.sliceLister = slices.Lister().EndpointSlices(r.namespace) }
+	}
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+	if cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		r.mu.Lock()
+		set()
+		r.mu.Unlock()
+	}
+	<-ctx.Done()
+	r.mu.Lock()
+	r.sliceLister, r.endpointsLister = nil, nil
+	r.mu.Unlock()
+	return nil
+}
+
+// ServiceAddresses returns the addresses of the ready endpoints of the given service. It falls back
+// to the Endpoints of the service if the API server doesn't serve EndpointSlices.
+func (r *Registry) ServiceAddresses(ctx context.Context, serviceName string) ([]string, error) {
+	r.mu.RLock()
+	sliceLister, endpointsLister := r.sliceLister, r.endpointsLister
+	r.mu.RUnlock()
+	var res []string
+	var err error
+	if endpointsLister != nil {
+		res, err = r.endpointsAddresses(ctx, endpointsLister, serviceName)
+	} else {
+		var slices []*discoveryv1.EndpointSlice
+		slices, err = r.endpointSlices(ctx, sliceLister, serviceName)
+		if apierrors.IsNotFound(err) {
+			res, err = r.endpointsAddresses(ctx, nil, serviceName)
+		}
+		for _, s := range slices {
+			res = append(res, r.sliceAddresses(s)...)
+		}
+	}
+	if err != nil {
+		return nil, err
+	} else if len(res) == 0 {
+		return nil, discovery.ErrNotFound
+	}
+	return res, nil
+}
+
+// endpointSlices returns the EndpointSlices of a service, from the lister if it isn't nil.
+func (r *Registry) endpointSlices(ctx context.Context, lister discoverylisters.EndpointSliceNamespaceLister, serviceName string) ([]*discoveryv1.EndpointSlice, error) {
+	if lister != nil {
+		return lister.List(labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: serviceName}))
+	}
+	list, err := r.client.DiscoveryV1().EndpointSlices(r.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + serviceName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*discoveryv1.EndpointSlice, len(list.Items))
+	for i := range list.Items {
+		res[i] = &list.Items[i]
+	}
+	return res, nil
+}
+
+// sliceAddresses returns the addresses of the ready endpoints of an EndpointSlice.
+func (r *Registry) sliceAddresses(s *discoveryv1.EndpointSlice) []string {
+	var port *int32
+	for _, p := range s.Ports {
+		if (p.Name != nil && *p.Name == r.portName) || len(s.Ports) == 1 {
+			port = p.Port
+			break
+		}
+	}
+	if port == nil {
+		return nil
+	}
+	var res []string
+	for _, e := range s.Endpoints {
+		// A nil ready condition means ready, terminating endpoints are never ready.
+		if e.Conditions.Ready != nil && !*e.Conditions.Ready {
+			continue
+		}
+		for _, addr := range e.Addresses {
+			res = append(res, net.JoinHostPort(addr, strconv.Itoa(int(*port))))
+		}
+	}
+	return res
+}
+
+// endpointsAddresses returns the ready addresses of the Endpoints of a service, from the lister if it isn't nil.
+func (r *Registry) endpointsAddresses(ctx context.Context, lister corelisters.EndpointsNamespaceLister, serviceName string) ([]string, error) {
+	var endpoints *corev1.Endpoints
+	var err error
+	if lister != nil {
+		endpoints, err = lister.Get(serviceName)
+	} else {
+		endpoints, err = r.client.CoreV1().Endpoints(r.namespace).Get(ctx, serviceName, metav1.GetOptions{})
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, discovery.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, s := range endpoints.Subsets {
+		port, ok := r.subsetPort(s)
+		if !ok {
+			continue
+		}
+		// Addresses only contains ready endpoints, NotReadyAddresses the others.
+		for _, a := range s.Addresses {
+			res = append(res, net.JoinHostPort(a.IP, strconv.Itoa(int(port))))
+		}
+	}
+	return res, nil
+}
+
+func (r *Registry) subsetPort(s corev1.EndpointSubset) (int32, bool) {
+	for _, p := range s.Ports {
+		if p.Name == r.portName || len(s.Ports) == 1 {
+			return p.Port, true
+		}
+	}
+	return 0, false
+}
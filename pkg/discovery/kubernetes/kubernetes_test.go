@@ -0,0 +1,174 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/pkg/discovery"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func endpointSlice(name string, service string, ports []discoveryv1.EndpointPort, endpoints ...discoveryv1.Endpoint) *discoveryv1.EndpointSlice {
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "movies",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: service},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Ports:       ports,
+		Endpoints:   endpoints,
+	}
+}
+
+func endpoint(ready *bool, addrs ...string) discoveryv1.Endpoint {
+	return discoveryv1.Endpoint{Addresses: addrs, Conditions: discoveryv1.EndpointConditions{Ready: ready}}
+}
+
+func TestServiceAddresses(t *testing.T) {
+	grpcAndMetrics := []discoveryv1.EndpointPort{
+		{Name: ptr("metrics"), Port: ptr[int32](9082)},
+		{Name: ptr("grpc"), Port: ptr[int32](8082)},
+	}
+	client := fake.NewSimpleClientset(
+		endpointSlice("rating-abc", "rating", grpcAndMetrics,
+			endpoint(ptr(true), "10.1.0.7"),
+			endpoint(ptr(false), "10.1.0.8"),
+			endpoint(nil, "10.1.0.9"),
+		),
+		endpointSlice("rating-def", "rating", grpcAndMetrics, endpoint(ptr(true), "fd00::7")),
+		endpointSlice("metadata-abc", "metadata", []discoveryv1.EndpointPort{{Name: ptr("http"), Port: ptr[int32](8081)}},
+			endpoint(ptr(true), "10.1.0.10"),
+		),
+		endpointSlice("movie-abc", "movie", grpcAndMetrics, endpoint(ptr(false), "10.1.0.11")),
+	)
+	r := NewRegistry(client, "movies", "")
+
+	tests := []struct {
+		service string
+		want    []string
+		wantErr error
+	}{
+		{service: "rating", want: []string{"10.1.0.7:8082", "10.1.0.9:8082", "[fd00::7]:8082"}},
+		{service: "metadata", want: []string{"10.1.0.10:8081"}},
+		{service: "movie", wantErr: discovery.ErrNotFound},
+		{service: "unknown", wantErr: discovery.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			got, err := r.ServiceAddresses(context.Background(), tt.service)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func TestServiceAddresses_Endpoints(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "rating", Namespace: "movies"},
+		Subsets: []corev1.EndpointSubset{{
+			Addresses:         []corev1.EndpointAddress{{IP: "10.1.0.7"}},
+			NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.1.0.8"}},
+			Ports:             []corev1.EndpointPort{{Name: "grpc", Port: 8082}, {Name: "metrics", Port: 9082}},
+		}},
+	})
+	client.PrependReactor("list", "endpointslices", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(schema.GroupResource{Group: "discovery.k8s.io", Resource: "endpointslices"}, "")
+	})
+	r := NewRegistry(client, "movies", "grpc")
+
+	got, err := r.ServiceAddresses(context.Background(), "rating")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.1.0.7:8082"}, got)
+	_, err = r.ServiceAddresses(context.Background(), "movie")
+	assert.ErrorIs(t, err, discovery.ErrNotFound)
+}
+
+func TestRegistry_NoOps(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	r := NewRegistry(client, "movies", "")
+	ctx := context.Background()
+	require.NoError(t, r.Register(ctx, "rating-1", "rating", "10.1.0.7:8082", discovery.Port{Name: discovery.PortMetrics, Port: 9082}))
+	require.NoError(t, r.ReportHealthyState("rating-1", "rating"))
+	require.NoError(t, r.Deregister(ctx, "rating-1", "rating"))
+	assert.Empty(t, client.Actions(), "registration doesn't call the API server")
+}
+
+// watch runs the watch of a registry until the test ends and waits for its cache to sync.
+func watch(t *testing.T, r *Registry) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Watch(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+	require.Eventually(t, func() bool {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return r.sliceLister != nil || r.endpointsLister != nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRegistry_Watch(t *testing.T) {
+	grpc := []discoveryv1.EndpointPort{{Name: ptr("grpc"), Port: ptr[int32](8082)}}
+	client := fake.NewSimpleClientset(endpointSlice("rating-abc", "rating", grpc, endpoint(ptr(true), "10.1.0.7")))
+	r := NewRegistry(client, "movies", "")
+	watch(t, r)
+	ctx := context.Background()
+
+	actions := len(client.Actions())
+	got, err := r.ServiceAddresses(ctx, "rating")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.1.0.7:8082"}, got)
+	assert.Len(t, client.Actions(), actions, "addresses are listed from the cache")
+
+	_, err = client.DiscoveryV1().EndpointSlices("movies").Create(ctx,
+		endpointSlice("rating-def", "rating", grpc, endpoint(ptr(true), "10.1.0.8")), metav1.CreateOptions{})
+	require.NoError(t, err)
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		got, err := r.ServiceAddresses(ctx, "rating")
+		assert.NoError(c, err)
+		assert.ElementsMatch(c, []string{"10.1.0.7:8082", "10.1.0.8:8082"}, got)
+	}, time.Second, 10*time.Millisecond, "the cache is updated from the watch")
+	_, err = r.ServiceAddresses(ctx, "movie")
+	assert.ErrorIs(t, err, discovery.ErrNotFound)
+}
+
+func TestRegistry_WatchEndpoints(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "rating", Namespace: "movies"},
+		Subsets: []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{{IP: "10.1.0.7"}},
+			Ports:     []corev1.EndpointPort{{Name: "grpc", Port: 8082}},
+		}},
+	})
+	client.PrependReactor("list", "endpointslices", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(schema.GroupResource{Group: "discovery.k8s.io", Resource: "endpointslices"}, "")
+	})
+	r := NewRegistry(client, "movies", "")
+	watch(t, r)
+
+	actions := len(client.Actions())
+	got, err := r.ServiceAddresses(context.Background(), "rating")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.1.0.7:8082"}, got)
+	assert.Len(t, client.Actions(), actions, "addresses are read from the cache")
+	_, err = r.ServiceAddresses(context.Background(), "movie")
+	assert.ErrorIs(t, err, discovery.ErrNotFound)
+}
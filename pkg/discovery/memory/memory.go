@@ -26,8 +26,8 @@ func NewRegistry() *Registry {
 	return &Registry{serviceAddrs: map[string]map[string]*serviceInstance{}}
 }
 
-// Register creates a service record in the registry.
-func (r *Registry) Register(ctx context.Context, instanceID string, serviceName string, hostPort string) error {
+// Register creates a service record in the registry. The additional ports are not recorded.
+func (r *Registry) Register(ctx context.Context, instanceID string, serviceName string, hostPort string, _ ...discovery.Port) error {
 	r.Lock()
 	defer r.Unlock()
 	if _, ok := r.serviceAddrs[serviceName]; !ok {
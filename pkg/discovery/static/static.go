@@ -0,0 +1,136 @@
+package static
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ugurcancaykara/odd-service/pkg/discovery"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultInterval defines how often the file is checked for changes if no interval is set.
+const DefaultInterval = 5 * time.Second
+
+// Registry defines a static service registry reading the addresses of services from a YAML file
+// mapping service names to addresses:
+//
+//	rating:
+//	  - localhost:8082
+//	  - "[::1]:8092"
+//
+// The file is watched for changes while Watch runs. Instances are listed in the file,
+// so registration and health reports are no-ops.
+type Registry struct {
+	path     string
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu       sync.RWMutex
+	content  []byte
+	services map[string][]string
+}
+
+// NewRegistry creates a new static service registry reading the file at path, which is checked
+// for changes every interval, DefaultInterval if zero. A nil logger logs to slog.Default().
+func NewRegistry(path string, interval time.Duration, logger *slog.Logger) (*Registry, error) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	r := &Registry{path: path, interval: interval, logger: logging.OrDefault(logger)}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Register is a no-op, instances are listed in the file.
+func (r *Registry) Register(context.Context, string, string, string, ...discovery.Port) error {
+	return nil
+}
+
+// Deregister is a no-op, instances are listed in the file.
+func (r *Registry) Deregister(context.Context, string, string) error {
+	return nil
+}
+
+// ReportHealthyState is a no-op, instances are listed in the file.
+func (r *Registry) ReportHealthyState(string, string) error {
+	return nil
+}
+
+// ServiceAddresses returns the addresses of the given service listed in the file.
+func (r *Registry) ServiceAddresses(_ context.Context, serviceName string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	addrs := r.services[serviceName]
+	if len(addrs) == 0 {
+		return nil, discovery.ErrNotFound
+	}
+	return append([]string(nil), addrs...), nil
+}
+
+// Reload reads the file again and, if it changed, replaces the addresses of the services.
+// It reports whether the file changed. An invalid file is not applied.
+func (r *Registry) Reload() (bool, error) {
+	b, err := os.ReadFile(r.path)
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	unchanged := r.services != nil && bytes.Equal(b, r.content)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	services, err := parse(b)
+	if err != nil {
+		return false, fmt.Errorf("decode %s: %w", r.path, err)
+	}
+	r.mu.Lock()
+	r.content = b
+	r.services = services
+	r.mu.Unlock()
+	return true, nil
+}
+
+// Watch reloads the file every interval until the context is done.
+func (r *Registry) Watch(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			changed, err := r.Reload()
+			if err != nil {
+				r.logger.Error("Failed to reload service addresses", "path", r.path, "error", err)
+			} else if changed {
+				r.logger.Info("Reloaded service addresses", "path", r.path)
+			}
+		}
+	}
+}
+
+// parse decodes the addresses of the services and checks they are in a form of <host>:<port>.
+func parse(b []byte) (map[string][]string, error) {
+	services := map[string][]string{}
+	if err := yaml.Unmarshal(b, &services); err != nil {
+		return nil, err
+	}
+	for name, addrs := range services {
+		for _, addr := range addrs {
+			if _, _, err := net.SplitHostPort(addr); err != nil {
+				return nil, fmt.Errorf("service %s: %w", name, err)
+			}
+		}
+	}
+	return services, nil
+}
@@ -0,0 +1,80 @@
+package static
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/pkg/discovery"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestNewRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "valid", content: "rating:\n  - localhost:8082\n  - \"[::1]:8092\"\n"},
+		{name: "empty", content: ""},
+		{name: "missing port", content: "rating:\n  - localhost\n", wantErr: true},
+		{name: "not a map", content: "- localhost:8082\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "services.yaml")
+			writeFile(t, path, tt.content)
+			_, err := NewRegistry(path, 0, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+	_, err := NewRegistry(filepath.Join(t.TempDir(), "missing.yaml"), 0, nil)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRegistry_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services.yaml")
+	writeFile(t, path, "rating:\n  - localhost:8082\n")
+	r, err := NewRegistry(path, 10*time.Millisecond, nil)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Watch(ctx) }()
+	defer func() {
+		cancel()
+		require.NoError(t, <-done)
+	}()
+
+	got, err := r.ServiceAddresses(ctx, "rating")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost:8082"}, got)
+	_, err = r.ServiceAddresses(ctx, "metadata")
+	assert.ErrorIs(t, err, discovery.ErrNotFound)
+
+	writeFile(t, path, "rating:\n  - localhost:8082\n  - localhost:8092\nmetadata:\n  - localhost:8081\n")
+	assert.Eventually(t, func() bool {
+		addrs, err := r.ServiceAddresses(ctx, "metadata")
+		return err == nil && len(addrs) == 1
+	}, time.Second, 10*time.Millisecond)
+	got, err = r.ServiceAddresses(ctx, "rating")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost:8082", "localhost:8092"}, got)
+
+	writeFile(t, path, "rating: [localhost]\n")
+	time.Sleep(50 * time.Millisecond)
+	got, err = r.ServiceAddresses(ctx, "rating")
+	require.NoError(t, err)
+	assert.Len(t, got, 2, "invalid files aren't applied")
+}
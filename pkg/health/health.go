@@ -0,0 +1,197 @@
+// Package health checks the dependencies of a service and reports its health over the gRPC health protocol
+// and HTTP probe endpoints.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Config defines how often and how long the dependencies of a service are checked.
+type Config struct {
+	// Interval defines how often the dependencies are checked, 5s if zero.
+	Interval time.Duration `yaml:"interval"`
+	// Timeout defines how long a single check may take, 2s if zero.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Check checks a dependency of a service, returning an error if it is unavailable.
+type Check func(ctx context.Context) error
+
+// Statuses of a service or a dependency in a report.
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Report defines the result of checking the dependencies of a service.
+type Report struct {
+	Status string `json:"status"`
+	// Checks contains "ok" or the error of every dependency by name.
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Checker periodically checks the dependencies of a service. The service is healthy
+// if all of them passed their last check.
+type Checker struct {
+	cfg    Config
+	server *health.Server
+
+	mu      sync.Mutex
+	names   []string
+	checks  map[string]Check
+	report  Report
+	stopped bool
+}
+
+// New creates a checker of the dependencies of a service. The service isn't healthy until
+// its dependencies are checked for the first time.
+func New(cfg Config) *Checker {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	server := health.NewServer()
+	server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	return &Checker{
+		cfg:    cfg,
+		server: server,
+		checks: map[string]Check{},
+		report: Report{Status: StatusUnavailable},
+	}
+}
+
+// Add adds a check of a dependency. It must be called before the checker runs.
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+// Check checks all dependencies concurrently and updates the health of the service.
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.Lock()
+	names := append([]string{}, c.names...)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.Unlock()
+
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+			defer cancel()
+			errs[i] = check(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]string, len(names))}
+	for i, name := range names {
+		if errs[i] != nil {
+			report.Status = StatusUnavailable
+			report.Checks[name] = errs[i].Error()
+			slog.WarnContext(ctx, "Dependency check failed", "dependency", name, "error", errs[i])
+			continue
+		}
+		report.Checks[name] = StatusOK
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return c.report
+	}
+	c.report = report
+	status := healthpb.HealthCheckResponse_SERVING
+	if report.Status != StatusOK {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	c.server.SetServingStatus("", status)
+	return report
+}
+
+// Run checks the dependencies right away and then every interval until the context is done.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		c.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Healthy reports whether all dependencies passed their last check and the checker isn't shut down.
+func (c *Checker) Healthy() bool {
+	return c.Report().Status == StatusOK
+}
+
+// Report returns the result of the last check.
+func (c *Checker) Report() Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.report
+}
+
+// Shutdown marks the service as not serving, e.g. before it stops accepting requests,
+// regardless of later checks.
+func (c *Checker) Shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+	c.report = Report{Status: StatusUnavailable}
+	c.server.Shutdown()
+}
+
+// Register registers the grpc.health.v1.Health service reporting the health of the service on a gRPC server.
+func (c *Checker) Register(srv *grpc.Server) {
+	healthpb.RegisterHealthServer(srv, c.server)
+}
+
+// Liveness returns the handler of liveness probes, e.g. /healthz. The service is alive as long as it
+// responds, failing dependencies don't fail liveness, so they don't restart the service.
+func (c *Checker) Liveness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeReport(w, Report{Status: StatusOK})
+	})
+}
+
+// Readiness returns the handler of readiness probes, e.g. /readyz, responding with the result
+// of the last check, 503 Service Unavailable if the service isn't healthy.
+func (c *Checker) Readiness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeReport(w, c.Report())
+	})
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("Failed to encode health report", "error", err)
+	}
+}
@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestChecker(t *testing.T) {
+	c := New(Config{Timeout: 10 * time.Millisecond})
+	var dbErr error
+	c.Add("mysql", func(context.Context) error { return dbErr })
+	c.Add("kafka", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.False(t, c.Healthy(), "unhealthy until checked")
+
+	report := c.Check(context.Background())
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["mysql"])
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["kafka"])
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, c))
+
+	c.Add("kafka", func(context.Context) error { return nil })
+	assert.Equal(t, StatusOK, c.Check(context.Background()).Status)
+	assert.True(t, c.Healthy())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, c))
+
+	dbErr = errors.New("connection refused")
+	c.Check(context.Background())
+	assert.False(t, c.Healthy())
+	assert.Equal(t, "connection refused", c.Report().Checks["mysql"])
+
+	dbErr = nil
+	c.Check(context.Background())
+	c.Shutdown()
+	c.Check(context.Background())
+	assert.False(t, c.Healthy(), "checks don't revive a shut down service")
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, c))
+}
+
+func TestHandlers(t *testing.T) {
+	c := New(Config{})
+	var dbErr error
+	c.Add("mysql", func(context.Context) error { return dbErr })
+
+	tests := []struct {
+		name       string
+		dbErr      error
+		handler    http.Handler
+		wantCode   int
+		wantStatus string
+	}{
+		{name: "ready", handler: c.Readiness(), wantCode: http.StatusOK, wantStatus: StatusOK},
+		{name: "not ready", dbErr: errors.New("down"), handler: c.Readiness(), wantCode: http.StatusServiceUnavailable, wantStatus: StatusUnavailable},
+		{name: "alive with failing dependencies", dbErr: errors.New("down"), handler: c.Liveness(), wantCode: http.StatusOK, wantStatus: StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbErr = tt.dbErr
+			c.Check(context.Background())
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, tt.wantCode, rec.Code)
+			var report Report
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+			assert.Equal(t, tt.wantStatus, report.Status)
+		})
+	}
+}
+
+func servingStatus(t *testing.T, c *Checker) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	resp, err := c.server.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	return resp.Status
+}
@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "defaults", cfg: Config{}},
+		{name: "json debug", cfg: Config{Level: "debug", Format: "json"}},
+		{name: "unknown level", cfg: Config{Level: "verbose"}, wantErr: true},
+		{name: "unknown format", cfg: Config{Format: "logfmt"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg, &bytes.Buffer{})
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestNew_RequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(Config{Level: "info", Format: "json"}, &buf)
+	require.NoError(t, err)
+
+	logger.DebugContext(context.Background(), "dropped")
+	logger.With("service", "rating").InfoContext(WithRequestID(context.Background(), "abc"), "Handled RPC")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "Handled RPC", record["msg"])
+	assert.Equal(t, "abc", record["request_id"])
+	assert.Equal(t, "rating", record["service"])
+}
+
+func TestSample(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sampled := Sample(logger, SamplingConfig{Initial: 2, Thereafter: 3, Tick: time.Second})
+	sampled.Handler().(*samplingHandler).now = func() time.Time { return now }
+
+	for i := 0; i < 8; i++ {
+		sampled.Info("event")
+	}
+	sampled.Error("failed")
+	assert.Equal(t, 4, strings.Count(buf.String(), "msg=event"), "first 2, then the 5th and 8th")
+	assert.Equal(t, 1, strings.Count(buf.String(), "msg=failed"))
+
+	buf.Reset()
+	now = now.Add(time.Second)
+	sampled.With("id", 1).Info("event")
+	assert.Equal(t, 1, strings.Count(buf.String(), "msg=event"), "counters reset every tick")
+
+	assert.Same(t, logger, Sample(logger, SamplingConfig{}))
+}
+
+func TestInterceptors(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(Config{Level: "debug", Format: "json"}, &buf)
+	require.NoError(t, err)
+	info := &grpc.UnaryServerInfo{FullMethod: "/RatingService/GetAggregatedRating"}
+
+	var gotID string
+	handler := func(ctx context.Context, _ any) (any, error) {
+		gotID = RequestID(ctx)
+		return nil, nil
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDKey, "abc"))
+	_, err = UnaryServerInterceptor(logger)(ctx, nil, info, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", gotID)
+	assert.Contains(t, buf.String(), `"request_id":"abc"`)
+	assert.Contains(t, buf.String(), `"code":"OK"`)
+
+	_, err = UnaryServerInterceptor(logger)(context.Background(), nil, info, handler)
+	require.NoError(t, err)
+	assert.Len(t, gotID, 16, "a request id is generated if the caller sent none")
+
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	err = UnaryClientInterceptor()(WithRequestID(context.Background(), "abc"), "/MetadataService/GetMetadata", nil, nil, nil, invoker)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"abc"}, outgoing.Get(RequestIDKey))
+}
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SamplingConfig defines the sampling of records logged on hot paths: of the records with the same message
+// logged within a tick, the first Initial records are logged and then every Thereafter-th record.
+// Records of level error and above are never dropped. Sampling is disabled if Initial is zero.
+type SamplingConfig struct {
+	Initial    int           `yaml:"initial"`
+	Thereafter int           `yaml:"thereafter"`
+	Tick       time.Duration `yaml:"tick"`
+}
+
+// Sample returns a logger dropping records of l following a sampling config.
+// It is meant for hot paths, e.g. logging per ingested event.
+func Sample(l *slog.Logger, cfg SamplingConfig) *slog.Logger {
+	if cfg.Initial <= 0 {
+		return l
+	}
+	if cfg.Tick <= 0 {
+		cfg.Tick = time.Second
+	}
+	return slog.New(&samplingHandler{Handler: l.Handler(), cfg: cfg, counters: &counters{n: map[string]int{}}, now: time.Now})
+}
+
+// counters counts the records per message logged within the current tick.
+type counters struct {
+	mu   sync.Mutex
+	tick time.Time
+	n    map[string]int
+}
+
+type samplingHandler struct {
+	slog.Handler
+	cfg      SamplingConfig
+	counters *counters
+	now      func() time.Time
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelError || h.sample(r.Message) {
+		return h.Handler.Handle(ctx, r)
+	}
+	return nil
+}
+
+// sample counts a record with a message and reports whether it is logged.
+func (h *samplingHandler) sample(msg string) bool {
+	tick := h.now().Truncate(h.cfg.Tick)
+	c := h.counters
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.tick.Equal(tick) {
+		c.tick = tick
+		clear(c.n)
+	}
+	c.n[msg]++
+	n := c.n[msg]
+	if n <= h.cfg.Initial {
+		return true
+	}
+	return h.cfg.Thereafter > 0 && (n-h.cfg.Initial)%h.cfg.Thereafter == 0
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithAttrs(attrs), cfg: h.cfg, counters: h.counters, now: h.now}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithGroup(name), cfg: h.cfg, counters: h.counters, now: h.now}
+}
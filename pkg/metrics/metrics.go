@@ -0,0 +1,74 @@
+// Package metrics defines the Prometheus metrics shared by the services and serves them on /metrics.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	gatewayRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_retries_total",
+		Help: "Number of retried gateway calls by gateway.",
+	}, []string{"gateway"})
+	registryLookupDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "registry_lookup_duration_seconds",
+		Help:    "Latency of service address lookups in the service registry by service and result.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "result"})
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "repository_query_duration_seconds",
+		Help:    "Latency of repository queries by repository and operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"repository", "operation"})
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_requests_total",
+		Help: "Number of cache lookups by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+)
+
+// Handler returns the handler serving the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// GatewayRetry counts a retried call of a gateway.
+func GatewayRetry(gateway string) {
+	gatewayRetries.WithLabelValues(gateway).Inc()
+}
+
+// ObserveRegistryLookup records the latency of a lookup of the addresses of a service started at start.
+func ObserveRegistryLookup(service string, start time.Time, err error) {
+	registryLookupDuration.WithLabelValues(service, result(err)).Observe(time.Since(start).Seconds())
+}
+
+// TimeQuery starts timing a repository query, the returned function records its latency:
+//
+//	defer metrics.TimeQuery("rating", "get")()
+func TimeQuery(repository string, operation string) func() {
+	start := time.Now()
+	return func() {
+		queryDuration.WithLabelValues(repository, operation).Observe(time.Since(start).Seconds())
+	}
+}
+
+// CacheLookup counts a lookup of a cache, the hit ratio of a cache is
+// rate(cache_requests_total{result="hit"}) / rate(cache_requests_total).
+func CacheLookup(cache string, hit bool) {
+	if hit {
+		cacheRequests.WithLabelValues(cache, "hit").Inc()
+	} else {
+		cacheRequests.WithLabelValues(cache, "miss").Inc()
+	}
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
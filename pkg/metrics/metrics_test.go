@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"}
+	tests := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{name: "OK", code: "OK"},
+		{name: "Status error", err: status.Error(codes.NotFound, "not found"), code: "NotFound"},
+		{name: "Other error", err: errors.New("failed"), code: "Unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := interceptor(context.Background(), nil, info, func(context.Context, any) (any, error) {
+				return nil, tt.err
+			})
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, 1.0, testutil.ToFloat64(serverHandled.WithLabelValues(info.FullMethod, tt.code)))
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	GatewayRetry("rating")
+	ObserveRegistryLookup("rating", time.Now(), nil)
+	TimeQuery("rating", "get")()
+	CacheLookup("dns", true)
+	CacheLookup("dns", false)
+	assert.Equal(t, 1.0, testutil.ToFloat64(cacheRequests.WithLabelValues("dns", "hit")))
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, name := range []string{
+		`gateway_retries_total{gateway="rating"} 1`,
+		`registry_lookup_duration_seconds_count{result="ok",service="rating"} 1`,
+		`repository_query_duration_seconds_count{operation="get",repository="rating"} 1`,
+		`cache_requests_total{cache="dns",result="miss"} 1`,
+	} {
+		assert.Contains(t, body, name)
+	}
+}
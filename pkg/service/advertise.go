@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// AdvertiseConfig defines the address other services reach an instance at, which differs from the address
+// it binds to e.g. in containers binding to all interfaces.
+type AdvertiseConfig struct {
+	// Host is the advertised host name or IP address. If it is empty, the host the API binds to is advertised,
+	// unless it binds to all interfaces. Then the host is detected from the PodIPEnv environment variable,
+	// the addresses of the host name and the addresses of the network interfaces, in this order.
+	Host string `yaml:"host"`
+}
+
+// PodIPEnv defines the environment variable Kubernetes deployments set to the IP address of the pod
+// with the downward API.
+const PodIPEnv = "POD_IP"
+
+// ErrNoAdvertiseAddress is returned when no address to advertise could be detected.
+var ErrNoAdvertiseAddress = errors.New("no address to advertise detected, set the advertise host")
+
+// detector looks up the sources of advertised addresses, replaced in tests.
+type detector struct {
+	getenv         func(string) string
+	hostname       func() (string, error)
+	lookupIP       func(string) ([]net.IP, error)
+	interfaceAddrs func() ([]net.Addr, error)
+}
+
+var defaultDetector = detector{
+	getenv:         os.Getenv,
+	hostname:       os.Hostname,
+	lookupIP:       net.LookupIP,
+	interfaceAddrs: net.InterfaceAddrs,
+}
+
+// advertiseHost returns the host to advertise for an instance binding to bindHost.
+func (d detector) advertiseHost(cfg AdvertiseConfig, bindHost string) (string, error) {
+	if cfg.Host != "" {
+		return cfg.Host, nil
+	}
+	if !unspecified(bindHost) {
+		return bindHost, nil
+	}
+	if ip := d.getenv(PodIPEnv); ip != "" {
+		return ip, nil
+	}
+	if name, err := d.hostname(); err == nil {
+		if ips, err := d.lookupIP(name); err == nil {
+			if ip := firstRoutable(ips); ip != nil {
+				return ip.String(), nil
+			}
+		}
+	}
+	addrs, err := d.interfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok {
+			ips = append(ips, n.IP)
+		}
+	}
+	if ip := firstRoutable(ips); ip != nil {
+		return ip.String(), nil
+	}
+	return "", ErrNoAdvertiseAddress
+}
+
+// unspecified reports whether a bind host binds to all interfaces.
+func unspecified(host string) bool {
+	if host == "" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsUnspecified()
+}
+
+// firstRoutable returns the first address other hosts can reach, preferring IPv4 addresses.
+func firstRoutable(ips []net.IP) net.IP {
+	var res net.IP
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+			continue
+		}
+		if ip.To4() != nil {
+			return ip
+		}
+		if res == nil {
+			res = ip
+		}
+	}
+	return res
+}
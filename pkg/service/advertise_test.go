@@ -0,0 +1,49 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvertiseHost(t *testing.T) {
+	ipNet := func(s string) net.Addr { return &net.IPNet{IP: net.ParseIP(s)} }
+	tests := []struct {
+		name     string
+		cfg      AdvertiseConfig
+		bindHost string
+		podIP    string
+		hostIPs  []net.IP
+		addrs    []net.Addr
+		want     string
+		wantErr  error
+	}{
+		{name: "configured", cfg: AdvertiseConfig{Host: "rating.internal"}, bindHost: "0.0.0.0", podIP: "10.1.0.7", want: "rating.internal"},
+		{name: "bind host", bindHost: "localhost", podIP: "10.1.0.7", want: "localhost"},
+		{name: "pod ip", bindHost: "0.0.0.0", podIP: "10.1.0.7", want: "10.1.0.7"},
+		{name: "host name", bindHost: "::", hostIPs: []net.IP{net.ParseIP("127.0.1.1"), net.ParseIP("fd00::7"), net.ParseIP("172.17.0.3")}, want: "172.17.0.3"},
+		{name: "ipv6 host name", bindHost: "", hostIPs: []net.IP{net.ParseIP("fd00::7")}, want: "fd00::7"},
+		{name: "interface", bindHost: "", addrs: []net.Addr{ipNet("127.0.0.1"), ipNet("fe80::1"), ipNet("192.168.1.20")}, want: "192.168.1.20"},
+		{name: "loopback only", bindHost: "", addrs: []net.Addr{ipNet("127.0.0.1"), ipNet("::1")}, wantErr: ErrNoAdvertiseAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := detector{
+				getenv:   func(string) string { return tt.podIP },
+				hostname: func() (string, error) { return "rating-7d9f", nil },
+				lookupIP: func(string) ([]net.IP, error) {
+					if tt.hostIPs == nil {
+						return nil, errors.New("no such host")
+					}
+					return tt.hostIPs, nil
+				},
+				interfaceAddrs: func() ([]net.Addr, error) { return tt.addrs, nil },
+			}
+			got, err := d.advertiseHost(tt.cfg, tt.bindHost)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
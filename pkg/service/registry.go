@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ugurcancaykara/odd-service/pkg/discovery"
+	"github.com/ugurcancaykara/odd-service/pkg/discovery/consul"
+	"github.com/ugurcancaykara/odd-service/pkg/discovery/dns"
+	"github.com/ugurcancaykara/odd-service/pkg/discovery/kubernetes"
+	"github.com/ugurcancaykara/odd-service/pkg/discovery/static"
+)
+
+// RegistryConfig selects the registry a service registers with and discovers other services from.
+// Supported types are consul, the default, kubernetes, dns and static.
+type RegistryConfig struct {
+	Type string `yaml:"type"`
+	// Address defines the address of the Consul agent.
+	Address    string                   `yaml:"address"`
+	Kubernetes KubernetesRegistryConfig `yaml:"kubernetes"`
+	DNS        DNSRegistryConfig        `yaml:"dns"`
+	Static     StaticRegistryConfig     `yaml:"static"`
+}
+
+// KubernetesRegistryConfig defines how services are resolved from the Kubernetes API server.
+type KubernetesRegistryConfig struct {
+	// Namespace defines the namespace of the services, the namespace of the pod if empty.
+	Namespace string `yaml:"namespace"`
+	// PortName defines the name of the service port serving the gRPC API, grpc if empty.
+	PortName string `yaml:"portName"`
+}
+
+// DNSRegistryConfig defines how services are resolved from `_grpc._tcp.<service>` SRV records.
+type DNSRegistryConfig struct {
+	// Server defines the address of the name server, the name servers of /etc/resolv.conf if empty.
+	Server string `yaml:"server"`
+	// Domain is appended to the SRV names, e.g. service.consul.
+	Domain string `yaml:"domain"`
+}
+
+// StaticRegistryConfig defines the YAML file listing the addresses of services.
+type StaticRegistryConfig struct {
+	Path string `yaml:"path"`
+	// ReloadInterval defines how often the file is checked for changes, 5s if zero.
+	ReloadInterval time.Duration `yaml:"reloadInterval"`
+}
+
+// watcher defines a registry reloading its records in the background while the service runs.
+type watcher interface {
+	Watch(ctx context.Context) error
+}
+
+// Validate checks the settings required by the selected registry.
+func (c RegistryConfig) Validate() error {
+	switch c.Type {
+	case "", "consul":
+		if c.Address == "" {
+			return errors.New("consul registry needs registry.address")
+		}
+	case "kubernetes", "dns":
+	case "static":
+		if c.Static.Path == "" {
+			return errors.New("static registry needs registry.static.path")
+		}
+	default:
+		return fmt.Errorf("unsupported registry type %q", c.Type)
+	}
+	return nil
+}
+
+// NewRegistry creates the registry selected in the config. A nil logger logs to slog.Default().
+func NewRegistry(cfg RegistryConfig, logger *slog.Logger) (discovery.Registry, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	var registry discovery.Registry
+	var err error
+	switch cfg.Type {
+	case "kubernetes":
+		registry, err = kubernetes.NewInClusterRegistry(cfg.Kubernetes.Namespace, cfg.Kubernetes.PortName)
+	case "dns":
+		registry, err = dns.NewRegistry(cfg.DNS.Server, cfg.DNS.Domain)
+	case "static":
+		registry, err = static.NewRegistry(cfg.Static.Path, cfg.Static.ReloadInterval, logger)
+	default:
+		registry, err = consul.NewRegistry(cfg.Address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     RegistryConfig
+		wantErr bool
+	}{
+		{name: "consul by default", cfg: RegistryConfig{Address: "localhost:8500"}},
+		{name: "consul without address", cfg: RegistryConfig{Type: "consul"}, wantErr: true},
+		{name: "kubernetes", cfg: RegistryConfig{Type: "kubernetes"}},
+		{name: "dns", cfg: RegistryConfig{Type: "dns"}},
+		{name: "static", cfg: RegistryConfig{Type: "static", Static: StaticRegistryConfig{Path: "services.yaml"}}},
+		{name: "static without path", cfg: RegistryConfig{Type: "static"}, wantErr: true},
+		{name: "unsupported", cfg: RegistryConfig{Type: "etcd", Address: "localhost:2379"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewRegistry_Static(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("rating:\n  - localhost:8082\n"), 0o644))
+	registry, err := NewRegistry(RegistryConfig{Type: "static", Static: StaticRegistryConfig{Path: path}}, nil)
+	require.NoError(t, err)
+	addrs, err := registry.ServiceAddresses(context.Background(), "rating")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost:8082"}, addrs)
+
+	svc := New("movie", testConfig(), registry, nil)
+	require.Len(t, svc.tasks, 1, "the file is watched while the service runs")
+	assert.Equal(t, "registry watch", svc.tasks[0].name)
+}
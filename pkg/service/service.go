@@ -0,0 +1,394 @@
+// Package service runs the servers of a service: it serves the gRPC API and the optional HTTP and admin
+// endpoints, registers the instance with the registry while it is healthy and shuts everything down in order.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ugurcancaykara/odd-service/pkg/discovery"
+	"github.com/ugurcancaykara/odd-service/pkg/health"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
+	"github.com/ugurcancaykara/odd-service/pkg/metrics"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// APIConfig defines the address the gRPC API of a service binds to.
+type APIConfig struct {
+	Host string `yaml:"host"`
+	Port string `yaml:"port" validate:"required"`
+}
+
+// Addr returns the address the API listens on.
+func (c APIConfig) Addr() string {
+	return net.JoinHostPort(c.Host, c.Port)
+}
+
+// ListenConfig defines the address of an optional HTTP endpoint, an empty port disables it.
+type ListenConfig struct {
+	Host string `yaml:"host"`
+	Port string `yaml:"port"`
+}
+
+// Addr returns the address the endpoint listens on.
+func (c ListenConfig) Addr() string {
+	return net.JoinHostPort(c.Host, c.Port)
+}
+
+// Config defines the endpoints, health checks and shutdown of a service.
+type Config struct {
+	API APIConfig
+	// Advertise defines the address the instance is registered with.
+	Advertise AdvertiseConfig
+	// HTTP defines the endpoint serving the HTTP handlers of the service.
+	HTTP ListenConfig
+	// Admin defines the endpoint serving the metrics on /metrics, the liveness and readiness probes
+	// on /healthz and /readyz and the admin handlers of the service.
+	Admin  ListenConfig
+	Health health.Config
+	// HeartbeatInterval defines how often the healthy state is reported to the registry, 1s if zero.
+	HeartbeatInterval time.Duration
+	// ShutdownTimeout defines how long in-flight requests and background tasks may take to drain
+	// on shutdown, 30s if zero.
+	ShutdownTimeout time.Duration
+}
+
+// task defines a named function run by the service.
+type task struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Service defines the runner of a service.
+type Service struct {
+	name     string
+	cfg      Config
+	registry discovery.Registry
+	logger   *slog.Logger
+	checker  *health.Checker
+	grpc     *grpc.Server
+	http     *http.ServeMux
+	admin    *http.ServeMux
+	tasks    []task
+	hooks    []task
+}
+
+// New creates the runner of a service registering with a registry. The gRPC server logs, measures and traces
+// the handled RPCs and serves reflection and the grpc.health.v1.Health service, opts are added to its options.
+// Registries reloading their records in the background, e.g. from a file, are watched while the service runs.
+// A nil logger logs to slog.Default().
+func New(name string, cfg Config, registry discovery.Registry, logger *slog.Logger, opts ...grpc.ServerOption) *Service {
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = time.Second
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 30 * time.Second
+	}
+	logger = logging.OrDefault(logger)
+	checker := health.New(cfg.Health)
+	srv := grpc.NewServer(append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(logging.UnaryServerInterceptor(logger), metrics.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(logging.StreamServerInterceptor(logger), metrics.StreamServerInterceptor()),
+		tracing.ServerOption(),
+	}, opts...)...)
+	reflection.Register(srv)
+	checker.Register(srv)
+	admin := http.NewServeMux()
+	admin.Handle("/metrics", metrics.Handler())
+	admin.Handle("/healthz", checker.Liveness())
+	admin.Handle("/readyz", checker.Readiness())
+	s := &Service{
+		name:     name,
+		cfg:      cfg,
+		registry: registry,
+		logger:   logger,
+		checker:  checker,
+		grpc:     srv,
+		http:     http.NewServeMux(),
+		admin:    admin,
+	}
+	if w, ok := registry.(watcher); ok {
+		s.Go("registry watch", w.Watch)
+	}
+	return s
+}
+
+// GRPCServer returns the gRPC server to register the API of the service on.
+func (s *Service) GRPCServer() *grpc.Server {
+	return s.grpc
+}
+
+// Health returns the checker of the dependencies of the service.
+func (s *Service) Health() *health.Checker {
+	return s.checker
+}
+
+// HandleHTTP registers a handler on the HTTP endpoint.
+func (s *Service) HandleHTTP(pattern string, h http.Handler) {
+	s.http.Handle(pattern, h)
+}
+
+// HandleAdmin registers a handler on the admin endpoint.
+func (s *Service) HandleAdmin(pattern string, h http.Handler) {
+	s.admin.Handle(pattern, h)
+}
+
+// Go runs a background task while the service runs. The context of the task is canceled on shutdown
+// and the service waits for the task to return before running the shutdown hooks.
+func (s *Service) Go(name string, fn func(ctx context.Context) error) {
+	s.tasks = append(s.tasks, task{name, fn})
+}
+
+// OnShutdown adds a hook run on shutdown, after the servers are stopped and the background tasks returned.
+// Hooks run in the reverse order they were added, so dependencies added first are closed last.
+func (s *Service) OnShutdown(name string, fn func(ctx context.Context) error) {
+	s.hooks = append(s.hooks, task{name, fn})
+}
+
+// Run serves the service and registers it with the registry until the context is done, the process receives
+// SIGINT or SIGTERM, or a server fails. Then it shuts the service down:
+//  1. the service reports it isn't serving anymore and stops heartbeats,
+//  2. the instance is deregistered,
+//  3. the gRPC and HTTP servers drain the in-flight requests and the background tasks return,
+//  4. the shutdown hooks run,
+//  5. the admin endpoint stops.
+//
+// Draining takes up to the shutdown timeout, the servers are stopped forcibly after it.
+func (s *Service) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listeners, err := s.listen()
+	if err != nil {
+		return err
+	}
+	host, detectErr := defaultDetector.advertiseHost(s.cfg.Advertise, s.cfg.API.Host)
+	if detectErr != nil {
+		// Registries resolving instances on their own, e.g. Kubernetes, DNS and static ones, ignore the
+		// address and Consul falls back to the address of the node of its agent.
+		s.logger.Warn("Failed to detect the address to advertise, registering an empty host", "error", detectErr)
+	}
+	instanceID := discovery.GenerateInstanceID(s.name)
+	if err := s.registry.Register(ctx, instanceID, s.name, net.JoinHostPort(host, port(listeners.grpc)), listeners.ports(s.cfg, host)...); err != nil {
+		listeners.close()
+		return err
+	}
+
+	errs := make(chan error, 3)
+	go func() { errs <- serveErr("gRPC", s.grpc.Serve(listeners.grpc)) }()
+	var httpSrv, adminSrv *http.Server
+	if listeners.http != nil {
+		httpSrv = &http.Server{Handler: s.http}
+		go func() { errs <- serveErr("HTTP", httpSrv.Serve(listeners.http)) }()
+	}
+	if listeners.admin != nil {
+		adminSrv = &http.Server{Handler: s.admin}
+		go func() { errs <- serveErr("admin", adminSrv.Serve(listeners.admin)) }()
+	}
+	s.logger.Info("Serving the "+s.name+" service", "instance", instanceID, "advertise", host,
+		"grpc", addr(listeners.grpc), "http", addr(listeners.http), "admin", addr(listeners.admin))
+
+	tasksCtx, cancelTasks := context.WithCancel(context.Background())
+	defer cancelTasks()
+	var tasks sync.WaitGroup
+	for _, t := range s.tasks {
+		tasks.Add(1)
+		go func(t task) {
+			defer tasks.Done()
+			if err := t.fn(tasksCtx); err != nil && !errors.Is(err, context.Canceled) {
+				s.logger.Error("Background task failed", "task", t.name, "error", err)
+			}
+		}(t)
+	}
+
+	heartbeatCtx, stopHeartbeat := context.WithCancel(context.Background())
+	var heartbeat sync.WaitGroup
+	heartbeat.Add(2)
+	go func() {
+		defer heartbeat.Done()
+		s.checker.Run(heartbeatCtx)
+	}()
+	go func() {
+		defer heartbeat.Done()
+		s.heartbeat(heartbeatCtx, instanceID)
+	}()
+
+	select {
+	case <-ctx.Done():
+		s.logger.Info("Shutting down the " + s.name + " service")
+	case err = <-errs:
+		s.logger.Error("Server failed, shutting down the "+s.name+" service", "error", err)
+	}
+
+	s.checker.Shutdown()
+	stopHeartbeat()
+	heartbeat.Wait()
+	deregisterCtx, cancelDeregister := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := s.registry.Deregister(deregisterCtx, instanceID, s.name); err != nil {
+		s.logger.Error("Failed to deregister the instance", "instance", instanceID, "error", err)
+	}
+	cancelDeregister()
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	cancelTasks()
+	s.stopGRPC(drainCtx)
+	if httpSrv != nil {
+		if err := httpSrv.Shutdown(drainCtx); err != nil {
+			s.logger.Error("Failed to drain the HTTP server", "error", err)
+		}
+	}
+	if !waitContext(drainCtx, &tasks) {
+		s.logger.Error("Background tasks didn't return within the shutdown timeout")
+	}
+	for i := len(s.hooks) - 1; i >= 0; i-- {
+		if err := s.hooks[i].fn(drainCtx); err != nil {
+			s.logger.Error("Shutdown hook failed", "hook", s.hooks[i].name, "error", err)
+		}
+	}
+	if adminSrv != nil {
+		if err := adminSrv.Shutdown(drainCtx); err != nil {
+			s.logger.Error("Failed to stop the admin server", "error", err)
+		}
+	}
+	s.logger.Info("Stopped the " + s.name + " service")
+	return err
+}
+
+// listeners defines the listeners of the endpoints of a service, nil for disabled endpoints.
+type listeners struct {
+	grpc, http, admin net.Listener
+}
+
+// listen listens on the addresses of the endpoints, so the service fails before registering
+// if any of them is taken.
+func (s *Service) listen() (*listeners, error) {
+	res := &listeners{}
+	var err error
+	if res.grpc, err = net.Listen("tcp", s.cfg.API.Addr()); err != nil {
+		return nil, err
+	}
+	if s.cfg.HTTP.Port != "" {
+		if res.http, err = net.Listen("tcp", s.cfg.HTTP.Addr()); err != nil {
+			res.close()
+			return nil, err
+		}
+	}
+	if s.cfg.Admin.Port != "" {
+		if res.admin, err = net.Listen("tcp", s.cfg.Admin.Addr()); err != nil {
+			res.close()
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// ports returns the additional ports registered with the instance, the ports the endpoints listen on.
+// They are reached at the hosts the endpoints bind to, or the advertised host if they bind to all interfaces.
+func (l *listeners) ports(cfg Config, host string) []discovery.Port {
+	var res []discovery.Port
+	for _, p := range []struct {
+		name     string
+		bindHost string
+		lis      net.Listener
+	}{{discovery.PortHTTP, cfg.HTTP.Host, l.http}, {discovery.PortMetrics, cfg.Admin.Host, l.admin}} {
+		if p.lis != nil {
+			tagged := discovery.Port{Name: p.name, Host: p.bindHost, Port: p.lis.Addr().(*net.TCPAddr).Port}
+			if unspecified(tagged.Host) {
+				tagged.Host = host
+			}
+			res = append(res, tagged)
+		}
+	}
+	return res
+}
+
+func (l *listeners) close() {
+	for _, lis := range []net.Listener{l.grpc, l.http, l.admin} {
+		if lis != nil {
+			lis.Close()
+		}
+	}
+}
+
+// heartbeat reports the healthy state of an instance to the registry every heartbeat interval
+// while the dependencies of the service pass their checks.
+func (s *Service) heartbeat(ctx context.Context, instanceID string) {
+	ticker := time.NewTicker(s.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		if s.checker.Healthy() {
+			if err := s.registry.ReportHealthyState(instanceID, s.name); err != nil {
+				s.logger.Warn("Failed to report healthy state", "error", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stopGRPC stops the gRPC server gracefully, or forcibly once the context is done.
+func (s *Service) stopGRPC(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		s.logger.Info("Gracefully stopped gRPC server")
+	case <-ctx.Done():
+		s.logger.Warn("Stopping gRPC server with in-flight requests after the shutdown timeout")
+		s.grpc.Stop()
+		<-stopped
+	}
+}
+
+// waitContext waits for a wait group and reports whether it finished before the context was done.
+func waitContext(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func serveErr(server string, err error) error {
+	if err == nil || errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return fmt.Errorf("%s server: %w", server, err)
+}
+
+// port returns the port a listener listens on.
+func port(l net.Listener) string {
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+}
+
+func addr(l net.Listener) string {
+	if l == nil {
+		return ""
+	}
+	return l.Addr().String()
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/pkg/discovery"
+	"github.com/ugurcancaykara/odd-service/pkg/health"
+)
+
+// recordingRegistry records the calls of a service to the registry.
+type recordingRegistry struct {
+	mu       sync.Mutex
+	calls    []string
+	reports  int
+	hostPort string
+	ports    []discovery.Port
+}
+
+func (r *recordingRegistry) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recordingRegistry) Register(_ context.Context, _ string, serviceName string, hostPort string, ports ...discovery.Port) error {
+	r.record("register " + serviceName)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hostPort = hostPort
+	r.ports = ports
+	return nil
+}
+
+func (r *recordingRegistry) Deregister(_ context.Context, _ string, serviceName string) error {
+	r.record("deregister " + serviceName)
+	return nil
+}
+
+func (r *recordingRegistry) ServiceAddresses(context.Context, string) ([]string, error) {
+	return nil, nil
+}
+
+func (r *recordingRegistry) ReportHealthyState(string, string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reports++
+	return nil
+}
+
+func (r *recordingRegistry) reported() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reports
+}
+
+func testConfig() Config {
+	return Config{
+		API:               APIConfig{Host: "localhost", Port: "0"},
+		Health:            health.Config{Interval: 10 * time.Millisecond},
+		HeartbeatInterval: 10 * time.Millisecond,
+		ShutdownTimeout:   time.Second,
+	}
+}
+
+func TestService_Run(t *testing.T) {
+	registry := &recordingRegistry{}
+	svc := New("rating", testConfig(), registry, nil)
+	dbErr := errors.New("connection refused")
+	var mu sync.Mutex
+	svc.Health().Add("mysql", func(context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return dbErr
+	})
+	svc.Go("ingestion", func(ctx context.Context) error {
+		<-ctx.Done()
+		registry.record("ingestion stopped")
+		return ctx.Err()
+	})
+	svc.OnShutdown("dead-letter sink", func(context.Context) error {
+		registry.record("close dead-letter sink")
+		return nil
+	})
+	svc.OnShutdown("ingester", func(context.Context) error {
+		registry.record("close ingester")
+		return errors.New("commit failed")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- svc.Run(ctx) }()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, registry.reported(), "unhealthy instances don't report to the registry")
+	mu.Lock()
+	dbErr = nil
+	mu.Unlock()
+	assert.Eventually(t, func() bool { return registry.reported() > 0 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.False(t, svc.Health().Healthy())
+	reports := registry.reported()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, reports, registry.reported(), "heartbeats stop on shutdown")
+	assert.Equal(t, []string{
+		"register rating",
+		"deregister rating",
+		"ingestion stopped",
+		"close ingester",
+		"close dead-letter sink",
+	}, registry.calls)
+}
+
+func TestService_RunListenError(t *testing.T) {
+	registry := &recordingRegistry{}
+	cfg := testConfig()
+	cfg.API.Port = "invalid"
+	err := New("rating", cfg, registry, nil).Run(context.Background())
+	assert.Error(t, err)
+	assert.Empty(t, registry.calls, "the instance isn't registered if it can't listen")
+}
+
+func TestService_RunRegistersAdvertisedPorts(t *testing.T) {
+	registry := &recordingRegistry{}
+	cfg := testConfig()
+	cfg.API.Host = "::"
+	cfg.Advertise.Host = "fd00::10"
+	cfg.HTTP = ListenConfig{Port: "0"}
+	cfg.Admin = ListenConfig{Host: "localhost", Port: "0"}
+	svc := New("rating", cfg, registry, nil)
+	svc.Health().Add("mysql", func(context.Context) error { return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- svc.Run(ctx) }()
+	assert.Eventually(t, func() bool { return registry.reported() > 0 }, time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	host, port, err := net.SplitHostPort(registry.hostPort)
+	require.NoError(t, err)
+	assert.Equal(t, "fd00::10", host)
+	assert.NotEqual(t, "0", port, "the port the API listens on is registered")
+	require.Len(t, registry.ports, 2)
+	assert.Equal(t, discovery.PortHTTP, registry.ports[0].Name)
+	assert.Equal(t, "fd00::10", registry.ports[0].Host, "ports binding to all interfaces are reached at the advertised host")
+	assert.NotZero(t, registry.ports[0].Port)
+	assert.Equal(t, discovery.PortMetrics, registry.ports[1].Name)
+	assert.Equal(t, "localhost", registry.ports[1].Host, "ports are reached at the host they bind to")
+	assert.NotZero(t, registry.ports[1].Port)
+}
+
+func TestService_RunWithoutAdvertiseAddress(t *testing.T) {
+	detector := defaultDetector
+	defer func() { defaultDetector = detector }()
+	defaultDetector.getenv = func(string) string { return "" }
+	defaultDetector.hostname = func() (string, error) { return "", errors.New("no host name") }
+	defaultDetector.interfaceAddrs = func() ([]net.Addr, error) { return nil, nil }
+
+	registry := &recordingRegistry{}
+	cfg := testConfig()
+	cfg.API.Host = "0.0.0.0"
+	svc := New("rating", cfg, registry, nil)
+	svc.Health().Add("mysql", func(context.Context) error { return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- svc.Run(ctx) }()
+	assert.Eventually(t, func() bool { return registry.reported() > 0 }, time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	host, _, err := net.SplitHostPort(registry.hostPort)
+	require.NoError(t, err)
+	assert.Empty(t, host, "the service runs if no address is detected")
+}
@@ -0,0 +1,152 @@
+// Package tracing sets up OpenTelemetry tracing of the services and propagates trace context
+// across gRPC calls and Kafka messages.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+const instrumentationName = "github.com/ugurcancaykara/odd-service"
+
+// Exporters of finished spans.
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+)
+
+// Config defines where the spans of a service are exported to.
+type Config struct {
+	// Exporter is otlp, file or none. Tracing is disabled if it is empty or none.
+	Exporter string `yaml:"exporter"`
+	// Endpoint defines the OTLP gRPC collector, e.g. localhost:4317.
+	Endpoint string `yaml:"endpoint"`
+	// File defines the file spans are appended to as JSON, one span per line.
+	File string `yaml:"file"`
+	// SampleRatio defines the ratio of traces started by the service which are sampled, from 0 to 1.
+	// Traces started by callers keep their sampling decision.
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+// Init sets up the global tracer provider and trace context propagation of a service.
+// The returned function flushes the pending spans and shuts the exporter down.
+func Init(ctx context.Context, service string, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	var exporter sdktrace.SpanExporter
+	var closeFile func() error
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		var err error
+		exporter, err = otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(cfg.Endpoint), otlptracegrpc.WithInsecure())
+		if err != nil {
+			return nil, err
+		}
+	case ExporterFile:
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		closeFile = f.Close
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter %q", cfg.Exporter)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(service)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeFile != nil {
+			err = errors.Join(err, closeFile())
+		}
+		return err
+	}, nil
+}
+
+// Start starts a span as a child of the span of a context, if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Trace starts a span like Start and returns a function recording the error of the traced operation, if any,
+// on the span and ending it. It is deferred with the named error result of the operation:
+//
+//	ctx, done := tracing.Trace(ctx, "rating repository get")
+//	defer func() { done(err) }()
+func Trace(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, func(error)) {
+	ctx, span := Start(ctx, name, attrs...)
+	return ctx, func(err error) {
+		End(span, err)
+	}
+}
+
+// StartLinked starts a span handling several messages, linked to the spans of the trace contexts of their headers.
+func StartLinked(ctx context.Context, name string, headers []map[string]string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	links := make([]trace.Link, 0, len(headers))
+	for _, h := range headers {
+		if sc := trace.SpanContextFromContext(Extract(context.Background(), h)); sc.IsValid() {
+			links = append(links, trace.Link{SpanContext: sc})
+		}
+	}
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...), trace.WithLinks(links...))
+}
+
+// End records err, if any, on a span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject returns the trace context of a context as a carrier for message headers.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// Extract returns a context continuing the trace context of message headers.
+func Extract(ctx context.Context, headers map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(headers))
+}
+
+// ServerOption returns the gRPC server option tracing handled RPCs and continuing the trace context of callers.
+func ServerOption() grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler())
+}
+
+// DialOption returns the gRPC dial option tracing called RPCs and propagating their trace context.
+func DialOption() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler())
+}
@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestPropagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	shutdown, err := Init(context.Background(), "test", Config{Exporter: ExporterNone})
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+
+	ctx, publish := Start(context.Background(), "ratings publish")
+	headers := Inject(ctx)
+	assert.Contains(t, headers, "traceparent")
+	publish.End()
+
+	_, receive := Start(Extract(context.Background(), headers), "ratings receive")
+	End(receive, errors.New("decode failed"))
+	_, batch := StartLinked(context.Background(), "rating ingest batch", []map[string]string{headers, {}})
+	batch.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, spans[0].SpanContext.TraceID(), spans[1].SpanContext.TraceID())
+	assert.Equal(t, spans[0].SpanContext.SpanID(), spans[1].Parent.SpanID())
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+	assert.NotEqual(t, spans[0].SpanContext.TraceID(), spans[2].SpanContext.TraceID())
+	require.Len(t, spans[2].Links, 1)
+	assert.Equal(t, spans[0].SpanContext.SpanID(), spans[2].Links[0].SpanContext.SpanID())
+}
+
+func TestTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	get := func(fail bool) (err error) {
+		_, done := Trace(context.Background(), "rating repository get")
+		defer func() { done(err) }()
+		if fail {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	assert.NoError(t, get(false))
+	assert.Error(t, get(true))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Equal(t, codes.Error, spans[1].Status.Code, "the error result is recorded")
+	assert.Equal(t, "connection refused", spans[1].Status.Description)
+}
+
+func TestInit_File(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Init(context.Background(), "test", Config{Exporter: ExporterFile, File: file, SampleRatio: 1})
+	require.NoError(t, err)
+	_, span := Start(context.Background(), "test span")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"Name":"test span"`)
+
+	_, err = Init(context.Background(), "test", Config{Exporter: "zipkin"})
+	assert.Error(t, err)
+}
@@ -1,9 +1,359 @@
 package main
 
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ugurcancaykara/odd-service/pkg/health"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
+	"github.com/ugurcancaykara/odd-service/pkg/service"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
+	"github.com/ugurcancaykara/odd-service/rating/internal/anomaly"
+	"github.com/ugurcancaykara/odd-service/rating/internal/controller/rating"
+	"github.com/ugurcancaykara/odd-service/rating/internal/leaderboard"
+	"github.com/ugurcancaykara/odd-service/rating/internal/ratelimit"
+	ratelimitmysql "github.com/ugurcancaykara/odd-service/rating/internal/ratelimit/mysql"
+	"github.com/ugurcancaykara/odd-service/rating/internal/recordtype"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
 type serviceConfig struct {
-	API apiConfig `yaml:"api"`
+	API         service.APIConfig           `yaml:"api"`
+	Advertise   service.AdvertiseConfig     `yaml:"advertise"`
+	HTTP        service.ListenConfig        `yaml:"http"`
+	Admin       service.ListenConfig        `yaml:"admin"`
+	Registry    service.RegistryConfig      `yaml:"registry"`
+	Health      health.Config               `yaml:"health"`
+	MySQL       mysqlConfig                 `yaml:"mysql"`
+	Ingester    ingesterConfig              `yaml:"ingester"`
+	RecordTypes map[string]recordTypeConfig `yaml:"recordTypes"`
+	Validation  validationConfig            `yaml:"validation"`
+	DeadLetter  deadLetterConfig            `yaml:"deadLetter"`
+	Aggregation aggregationConfig           `yaml:"aggregation"`
+	Leaderboard leaderboardConfig           `yaml:"leaderboard"`
+	Anomaly     anomalyConfig               `yaml:"anomaly"`
+	RateLimit   rateLimitConfig             `yaml:"rateLimit"`
+	Logging     logging.Config              `yaml:"logging"`
+	Tracing     tracing.Config              `yaml:"tracing"`
+
+	// ReloadInterval defines how often the config is reloaded, zero disables reloading.
+	// Only the rate limits and the leaderboard strategy are applied at runtime.
+	ReloadInterval time.Duration `yaml:"reloadInterval"`
+	// ShutdownTimeout defines how long in-flight requests may take to drain on shutdown.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+}
+
+// runnerConfig returns the config of the runner of the service.
+func (c *serviceConfig) runnerConfig() service.Config {
+	return service.Config{API: c.API, Advertise: c.Advertise, HTTP: c.HTTP, Admin: c.Admin, Health: c.Health, ShutdownTimeout: c.ShutdownTimeout}
+}
+
+// mysqlConfig defines the database of the ratings, the data source name must enable parseTime.
+type mysqlConfig struct {
+	DSN string `yaml:"dsn" validate:"required"`
+}
+
+// Validate checks the settings required by the selected registry, ingester and dead-letter sink.
+func (c *serviceConfig) Validate() error {
+	if err := c.Registry.Validate(); err != nil {
+		return err
+	}
+	if c.Ingester.Type == "kafka" && (c.Ingester.Kafka.Addr == "" || c.Ingester.Kafka.Topic == "") {
+		return errors.New("kafka ingester needs ingester.kafka.addr and ingester.kafka.topic")
+	}
+	if c.DeadLetter.Type == "kafka" && (c.DeadLetter.Kafka.Addr == "" || c.DeadLetter.Kafka.Topic == "") {
+		return errors.New("kafka dead-letter sink needs deadLetter.kafka.addr and deadLetter.kafka.topic")
+	}
+	if c.RateLimit.Enabled && c.RateLimit.Backend == "mysql" && c.MySQL.DSN == "" {
+		return errors.New("mysql rate limit backend needs mysql.dsn")
+	}
+	return nil
+}
+
+// ingesterConfig selects the rating event ingester. Supported types are kafka, file, dir and none.
+type ingesterConfig struct {
+	Type    string              `yaml:"type"`
+	Kafka   kafkaIngesterConfig `yaml:"kafka"`
+	File    fileIngesterConfig  `yaml:"file"`
+	Dir     dirIngesterConfig   `yaml:"dir"`
+	Retry   retryConfig         `yaml:"retry"`
+	Workers workersConfig       `yaml:"workers"`
+}
+
+type kafkaIngesterConfig struct {
+	Addr    string `yaml:"addr"`
+	GroupID string `yaml:"groupId"`
+	Topic   string `yaml:"topic"`
+}
+
+type fileIngesterConfig struct {
+	Path         string        `yaml:"path"`
+	Follow       bool          `yaml:"follow"`
+	PollInterval time.Duration `yaml:"pollInterval"`
+}
+
+type dirIngesterConfig struct {
+	Path         string        `yaml:"path"`
+	Pattern      string        `yaml:"pattern"`
+	ArchiveDir   string        `yaml:"archiveDir"`
+	FailedDir    string        `yaml:"failedDir"`
+	PollInterval time.Duration `yaml:"pollInterval"`
+}
+
+// retryConfig defines the backoff used when persisting ingested rating events fails.
+type retryConfig struct {
+	InitialInterval time.Duration `yaml:"initialInterval"`
+	MaxInterval     time.Duration `yaml:"maxInterval"`
+	MaxElapsedTime  time.Duration `yaml:"maxElapsedTime"`
+}
+
+// workersConfig defines the worker pool processing ingested rating events.
+type workersConfig struct {
+	Count           int           `yaml:"count"`
+	QueueSize       int           `yaml:"queueSize"`
+	BatchSize       int           `yaml:"batchSize"`
+	FlushInterval   time.Duration `yaml:"flushInterval"`
+	StatsInterval   time.Duration `yaml:"statsInterval"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+}
+
+// validationConfig defines the rules ingested rating events are validated against,
+// in addition to the record types and their scales.
+type validationConfig struct {
+	Providers []string `yaml:"providers"`
+	// Deprecated: MinValue, MaxValue and RecordTypes are read for one more release, use recordTypes.
+	MinValue    int      `yaml:"minValue"`
+	MaxValue    int      `yaml:"maxValue"`
+	RecordTypes []string `yaml:"recordTypes"`
+}
+
+// recordTypeConfig defines the rating scale of a record type, either a predefined scale (stars5, points10 or thumbs)
+// or a custom one from min to max.
+type recordTypeConfig struct {
+	Scale string `yaml:"scale"`
+	Min   *int   `yaml:"min"`
+	Max   *int   `yaml:"max"`
+}
+
+// deadLetterConfig selects the sink for rejected rating events. Supported types are kafka, file and none.
+type deadLetterConfig struct {
+	Type  string                `yaml:"type"`
+	Kafka kafkaDeadLetterConfig `yaml:"kafka"`
+	File  fileDeadLetterConfig  `yaml:"file"`
+}
+
+type kafkaDeadLetterConfig struct {
+	Addr  string `yaml:"addr"`
+	Topic string `yaml:"topic"`
+}
+
+type fileDeadLetterConfig struct {
+	Path string `yaml:"path"`
+}
+
+// aggregationConfig defines how ratings are aggregated. Ratings are weighted by the trust weight of their provider,
+// providers missing from providerWeights are weighted with defaultWeight (1 if not set).
+type aggregationConfig struct {
+	DefaultWeight   *float64           `yaml:"defaultWeight"`
+	ProviderWeights map[string]float64 `yaml:"providerWeights"`
+}
+
+// leaderboardConfig defines the top rated and trending leaderboards.
+type leaderboardConfig struct {
+	// Strategy is mean, weighted or bayesian.
+	Strategy string `yaml:"strategy"`
+	// PriorMean is a fraction of the rating scale of a record type, 0 for the lowest value and 1 for the highest.
+	PriorMean         float64       `yaml:"priorMean"`
+	PriorVotes        float64       `yaml:"priorVotes"`
+	TrendingBucket    time.Duration `yaml:"trendingBucket"`
+	TrendingRetention time.Duration `yaml:"trendingRetention"`
+	// RebuildInterval defines how often the leaderboards are rebuilt from the repository, zero disables rebuilding.
+	RebuildInterval time.Duration `yaml:"rebuildInterval"`
+}
+
+// anomalyConfig defines the detection of suspicious ratings, which are quarantined until they are moderated.
+// Zero thresholds use the defaults.
+type anomalyConfig struct {
+	Enabled           bool          `yaml:"enabled"`
+	Window            time.Duration `yaml:"window"`
+	BaselineWindow    time.Duration `yaml:"baselineWindow"`
+	BurstMinRatings   int           `yaml:"burstMinRatings"`
+	BurstFactor       float64       `yaml:"burstFactor"`
+	NewUserAge        time.Duration `yaml:"newUserAge"`
+	UserRetention     time.Duration `yaml:"userRetention"`
+	NewUserMinRatings int           `yaml:"newUserMinRatings"`
+	NewUserRatio      float64       `yaml:"newUserRatio"`
+	OutlierMinVotes   int           `yaml:"outlierMinVotes"`
+	OutlierMinRatings int           `yaml:"outlierMinRatings"`
+	OutlierDeviation  float64       `yaml:"outlierDeviation"`
+}
+
+// rateLimitConfig defines the rate limits of rating writes per user and per provider. Supported backends are
+// memory (per instance) and mysql (shared by all instances), a zero rate disables a limit.
+type rateLimitConfig struct {
+	Enabled      bool                   `yaml:"enabled"`
+	Backend      string                 `yaml:"backend"`
+	User         limitConfig            `yaml:"user"`
+	IngestedUser limitConfig            `yaml:"ingestedUser"`
+	Provider     limitConfig            `yaml:"provider"`
+	Providers    map[string]limitConfig `yaml:"providers"`
+}
+
+// limitConfig defines a token bucket refilled with rate tokens per second up to burst tokens.
+type limitConfig struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+func rateLimits(cfg rateLimitConfig) (ratelimit.Config, error) {
+	limit := func(name string, l limitConfig) (ratelimit.Limit, error) {
+		if l.Rate < 0 || l.Burst < 0 {
+			return ratelimit.Limit{}, fmt.Errorf("negative %s rate limit", name)
+		}
+		return ratelimit.Limit{Rate: l.Rate, Burst: l.Burst}, nil
+	}
+	var res ratelimit.Config
+	var err error
+	if res.User, err = limit("user", cfg.User); err != nil {
+		return res, err
+	}
+	if res.IngestedUser, err = limit("ingested user", cfg.IngestedUser); err != nil {
+		return res, err
+	}
+	if res.Provider, err = limit("provider", cfg.Provider); err != nil {
+		return res, err
+	}
+	res.Providers = map[string]ratelimit.Limit{}
+	for provider, l := range cfg.Providers {
+		if res.Providers[provider], err = limit(fmt.Sprintf("provider %q", provider), l); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+func newRateLimiter(cfg rateLimitConfig, dsn string) (*ratelimit.Limiter, error) {
+	limits, err := rateLimits(cfg)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.Backend {
+	case "", "memory":
+		return ratelimit.New(limits, ratelimit.NewMemoryStore()), nil
+	case "mysql":
+		store, err := ratelimitmysql.New(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return ratelimit.New(limits, store), nil
+	default:
+		return nil, fmt.Errorf("unsupported rate limit backend %q", cfg.Backend)
+	}
+}
+
+// anomalyPolicy creates the anomaly detection config, outlierDeviation being a fraction of the range of the rating scale.
+func anomalyPolicy(cfg anomalyConfig) (anomaly.Config, error) {
+	if cfg.OutlierDeviation > 1 {
+		return anomaly.Config{}, fmt.Errorf("anomaly.outlierDeviation %v is not a fraction of the rating scale between 0 and 1", cfg.OutlierDeviation)
+	}
+	return anomaly.Config{
+		Window:            cfg.Window,
+		BaselineWindow:    cfg.BaselineWindow,
+		BurstMinRatings:   cfg.BurstMinRatings,
+		BurstFactor:       cfg.BurstFactor,
+		NewUserAge:        cfg.NewUserAge,
+		UserRetention:     cfg.UserRetention,
+		NewUserMinRatings: cfg.NewUserMinRatings,
+		NewUserRatio:      cfg.NewUserRatio,
+		OutlierMinVotes:   cfg.OutlierMinVotes,
+		OutlierMinRatings: cfg.OutlierMinRatings,
+		OutlierDeviation:  cfg.OutlierDeviation,
+	}, nil
+}
+
+// recordTypes creates the record type registry. The deprecated validation keys, if set, override the scales of their
+// record types (movies by default) with a custom min and max (1 to 5 by default).
+func recordTypes(cfg map[string]recordTypeConfig, legacy validationConfig, logger *slog.Logger) (*recordtype.Registry, error) {
+	if legacy.MinValue != 0 || legacy.MaxValue != 0 || len(legacy.RecordTypes) > 0 {
+		logger.Warn("validation.minValue, validation.maxValue and validation.recordTypes are deprecated and will be removed in the next release, use recordTypes")
+		minValue, maxValue := 1, 5
+		if legacy.MinValue != 0 || legacy.MaxValue != 0 {
+			minValue, maxValue = legacy.MinValue, legacy.MaxValue
+		}
+		types := legacy.RecordTypes
+		if len(types) == 0 {
+			types = []string{string(model.RecordTypeMovie)}
+		}
+		merged := map[string]recordTypeConfig{}
+		for t, c := range cfg {
+			merged[t] = c
+		}
+		for _, t := range types {
+			merged[t] = recordTypeConfig{Min: &minValue, Max: &maxValue}
+		}
+		cfg = merged
+	}
+	if len(cfg) == 0 {
+		return recordtype.Default, nil
+	}
+	scales := map[model.RecordType]model.RatingScale{}
+	for t, c := range cfg {
+		switch {
+		case c.Scale != "":
+			scale, ok := recordtype.Scales[c.Scale]
+			if !ok {
+				return nil, fmt.Errorf("unknown rating scale %q of record type %q", c.Scale, t)
+			}
+			scales[model.RecordType(t)] = scale
+		case c.Min != nil && c.Max != nil:
+			scales[model.RecordType(t)] = model.RatingScale{Name: t, Min: model.RatingValue(*c.Min), Max: model.RatingValue(*c.Max)}
+		default:
+			return nil, fmt.Errorf("record type %q needs a scale or a min and max", t)
+		}
+	}
+	return recordtype.New(scales)
+}
+
+func trustWeights(cfg aggregationConfig) (rating.TrustWeights, error) {
+	res := rating.TrustWeights{Default: rating.DefaultTrustWeights.Default, Providers: cfg.ProviderWeights}
+	if cfg.DefaultWeight != nil {
+		res.Default = *cfg.DefaultWeight
+	}
+	if res.Default < 0 {
+		return res, fmt.Errorf("negative default provider weight %v", res.Default)
+	}
+	for provider, weight := range cfg.ProviderWeights {
+		if weight < 0 {
+			return res, fmt.Errorf("negative weight %v for provider %q", weight, provider)
+		}
+	}
+	return res, nil
 }
 
-type apiConfig struct {
-	Port string `yaml:"port"`
+func leaderboardPolicy(cfg leaderboardConfig) (leaderboard.Config, error) {
+	res := leaderboard.DefaultConfig
+	switch s := model.AggregationStrategy(cfg.Strategy); s {
+	case "":
+	case model.AggregationStrategyMean, model.AggregationStrategyWeighted, model.AggregationStrategyBayesian:
+		res.Strategy = s
+	default:
+		return res, fmt.Errorf("unknown leaderboard strategy %q", cfg.Strategy)
+	}
+	if cfg.PriorMean > 1 {
+		return res, fmt.Errorf("leaderboard.priorMean %v is not a fraction of the rating scale between 0 and 1", cfg.PriorMean)
+	} else if cfg.PriorMean > 0 {
+		res.PriorMean = cfg.PriorMean
+	}
+	if cfg.PriorVotes > 0 {
+		res.PriorVotes = cfg.PriorVotes
+	}
+	if cfg.TrendingBucket > 0 {
+		res.TrendingBucket = cfg.TrendingBucket
+	}
+	if cfg.TrendingRetention > 0 {
+		res.TrendingRetention = cfg.TrendingRetention
+	}
+	return res, nil
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/ugurcancaykara/odd-service/rating/internal/controller/rating"
+	filedeadletter "github.com/ugurcancaykara/odd-service/rating/internal/deadletter/file"
+	kafkadeadletter "github.com/ugurcancaykara/odd-service/rating/internal/deadletter/kafka"
+	"github.com/ugurcancaykara/odd-service/rating/internal/ingester/dir"
+	"github.com/ugurcancaykara/odd-service/rating/internal/ingester/file"
+	"github.com/ugurcancaykara/odd-service/rating/internal/ingester/kafka"
+	"github.com/ugurcancaykara/odd-service/rating/internal/recordtype"
+	"github.com/ugurcancaykara/odd-service/rating/internal/validation"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+type ingester interface {
+	Ingest(ctx context.Context) (chan model.RatingEvent, error)
+	Commit(ctx context.Context, event model.RatingEvent) error
+}
+
+// pinger is implemented by ingesters depending on a broker whose availability is checked.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type deadLetterSink interface {
+	Write(ctx context.Context, entry model.DeadLetterEntry) error
+	Close() error
+}
+
+// newIngester creates the rating event ingester selected in the config.
+// It returns nil if ingestion is disabled.
+func newIngester(cfg ingesterConfig, deadLetter deadLetterSink, logger *slog.Logger) (ingester, error) {
+	switch cfg.Type {
+	case "", "none":
+		return nil, nil
+	case "kafka":
+		return kafka.NewIngester(cfg.Kafka.Addr, cfg.Kafka.GroupID, cfg.Kafka.Topic, deadLetter, logger)
+	case "file":
+		return file.NewIngester(cfg.File.Path, cfg.File.Follow, cfg.File.PollInterval, logger), nil
+	case "dir":
+		return dir.NewIngester(cfg.Dir.Path, cfg.Dir.Pattern, cfg.Dir.ArchiveDir, cfg.Dir.FailedDir, cfg.Dir.PollInterval, logger), nil
+	default:
+		return nil, fmt.Errorf("unsupported ingester type %q", cfg.Type)
+	}
+}
+
+// newDeadLetterSink creates the dead-letter sink selected in the config.
+// It returns nil if the dead-letter sink is disabled.
+func newDeadLetterSink(cfg deadLetterConfig) (deadLetterSink, error) {
+	switch cfg.Type {
+	case "", "none":
+		return nil, nil
+	case "kafka":
+		return kafkadeadletter.NewSink(cfg.Kafka.Addr, cfg.Kafka.Topic)
+	case "file":
+		return filedeadletter.NewSink(cfg.File.Path)
+	default:
+		return nil, fmt.Errorf("unsupported dead-letter sink type %q", cfg.Type)
+	}
+}
+
+// newValidator creates a rating event validator checking values against the scales of the record types.
+func newValidator(cfg validationConfig, recordTypes *recordtype.Registry) *validation.Validator {
+	return validation.New(validation.Config{RecordTypes: recordTypes, Providers: cfg.Providers})
+}
+
+// retryPolicy converts the retry config into a controller retry policy, using defaults for unset values.
+func retryPolicy(cfg retryConfig) rating.RetryConfig {
+	res := rating.DefaultRetryConfig
+	if cfg.InitialInterval > 0 {
+		res.InitialInterval = cfg.InitialInterval
+	}
+	if cfg.MaxInterval > 0 {
+		res.MaxInterval = cfg.MaxInterval
+	}
+	if cfg.MaxElapsedTime > 0 {
+		res.MaxElapsedTime = cfg.MaxElapsedTime
+	}
+	return res
+}
+
+func ingestionPolicy(cfg workersConfig) rating.IngestionConfig {
+	res := rating.DefaultIngestionConfig
+	if cfg.Count > 0 {
+		res.Workers = cfg.Count
+	}
+	if cfg.QueueSize > 0 {
+		res.QueueSize = cfg.QueueSize
+	}
+	if cfg.BatchSize > 0 {
+		res.BatchSize = cfg.BatchSize
+	}
+	if cfg.FlushInterval > 0 {
+		res.FlushInterval = cfg.FlushInterval
+	}
+	if cfg.StatsInterval > 0 {
+		res.StatsInterval = cfg.StatsInterval
+	}
+	if cfg.ShutdownTimeout > 0 {
+		res.ShutdownTimeout = cfg.ShutdownTimeout
+	}
+	return res
+}
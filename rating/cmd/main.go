@@ -2,100 +2,167 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
-	"net"
+	"io"
+	"log/slog"
+	"net/http"
 	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/ugurcancaykara/odd-service/gen"
-	"github.com/ugurcancaykara/odd-service/pkg/discovery"
-	"github.com/ugurcancaykara/odd-service/pkg/discovery/consul"
+	"github.com/ugurcancaykara/odd-service/pkg/config"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
+	"github.com/ugurcancaykara/odd-service/pkg/service"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
+	"github.com/ugurcancaykara/odd-service/rating/internal/anomaly"
 	"github.com/ugurcancaykara/odd-service/rating/internal/controller/rating"
 	grpchandler "github.com/ugurcancaykara/odd-service/rating/internal/handler/grpc"
-	"github.com/ugurcancaykara/odd-service/rating/internal/ingester/kafka"
+	httphandler "github.com/ugurcancaykara/odd-service/rating/internal/handler/http"
+	"github.com/ugurcancaykara/odd-service/rating/internal/ratelimit"
 	"github.com/ugurcancaykara/odd-service/rating/internal/repository/mysql"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
-	"gopkg.in/yaml.v3"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
 )
 
 const serviceName = "rating"
 
 func main() {
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		configPath = "configs/base.yaml"
+	watcher, err := config.Watch[serviceConfig](config.NewLoader(serviceName), os.Args[1:])
+	if err != nil {
+		slog.Error("Failed to load config", "error", err)
+		os.Exit(1)
 	}
-
-	f, err := os.Open(configPath)
+	cfg := watcher.Config()
+	logger, err := logging.New(cfg.Logging, os.Stderr)
 	if err != nil {
-		panic(err)
+		slog.Error("Failed to set up logging", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+	shutdownTracing, err := tracing.Init(context.Background(), serviceName, cfg.Tracing)
+	if err != nil {
+		logger.Error("Failed to set up tracing", "error", err)
+		os.Exit(1)
 	}
-	defer f.Close()
-	var cfg serviceConfig
-	if err = yaml.NewDecoder(f).Decode(&cfg); err != nil {
+	registry, err := service.NewRegistry(cfg.Registry, logger)
+	if err != nil {
 		panic(err)
 	}
-	port := cfg.API.Port
-	log.Printf("Starting the metadata service on port %s", port)
-
-	registry, err := consul.NewRegistry("localhost:8500")
+	svc := service.New(serviceName, cfg.runnerConfig(), registry, logger)
+	svc.OnShutdown("tracing", shutdownTracing)
+	repo, err := mysql.New(cfg.MySQL.DSN, logger)
 	if err != nil {
 		panic(err)
 	}
-	ctx, cancel := context.WithCancel(context.Background())
-	instanceID := discovery.GenerateInstanceID(serviceName)
-	if err := registry.Register(ctx, instanceID, serviceName, fmt.Sprintf("localhost:%s", port)); err != nil {
+	deadLetter, err := newDeadLetterSink(cfg.DeadLetter)
+	if err != nil {
 		panic(err)
 	}
-	go func() {
-		for {
-			if err := registry.ReportHealthyState(instanceID, serviceName); err != nil {
-				log.Println("Failed to report healthy state: " + err.Error())
-			}
-			time.Sleep(1 * time.Second)
-		}
-	}()
-	defer registry.Deregister(ctx, instanceID, serviceName)
-	repo, err := mysql.New()
+	// Ingesters log per event, so their records are sampled.
+	eventLogger := logging.Sample(logger, cfg.Logging.Sampling)
+	ingester, err := newIngester(cfg.Ingester, deadLetter, eventLogger)
+	if err != nil {
+		logger.Error("Failed to create the rating event ingester", "type", cfg.Ingester.Type, "error", err)
+		os.Exit(1)
+	}
+	types, err := recordTypes(cfg.RecordTypes, cfg.Validation, logger)
 	if err != nil {
 		panic(err)
 	}
-	newIngester, err := kafka.NewIngester("localhost", "odd-service-rating-ingester", "ratings")
+	weights, err := trustWeights(cfg.Aggregation)
 	if err != nil {
-		fmt.Println("consumer client olustururken hata verdi")
 		panic(err)
 	}
-	ctrl := rating.New(repo, newIngester)
-	go ctrl.StartIngestion(ctx)
-
-	h := grpchandler.New(ctrl)
-	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%v", port))
+	leaderboardCfg, err := leaderboardPolicy(cfg.Leaderboard)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
-	}
-	srv := grpc.NewServer()
-	reflection.Register(srv)
-	gen.RegisterRatingServiceServer(srv, h)
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGKILL)
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		s := <-sigChan
-		cancel()
-		log.Printf("Received signal %v, attempting graceful shutdown...", s)
-		srv.GracefulStop()
-		log.Println("Gracefully stopped gRPC server")
-	}()
-	fmt.Println("starting grpc server ")
-	if err := srv.Serve(lis); err != nil {
 		panic(err)
 	}
-	wg.Wait()
+	opts := []rating.Option{
+		rating.WithLogger(logger),
+		rating.WithEventLogger(eventLogger),
+		rating.WithTrustWeights(weights),
+		rating.WithLeaderboardConfig(leaderboardCfg),
+		rating.WithRetryConfig(retryPolicy(cfg.Ingester.Retry)),
+		rating.WithIngestionConfig(ingestionPolicy(cfg.Ingester.Workers)),
+		rating.WithRecordTypes(types),
+		rating.WithValidator(newValidator(cfg.Validation, types)),
+	}
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		limiter, err = newRateLimiter(cfg.RateLimit, cfg.MySQL.DSN)
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, rating.WithRateLimiter(limiter))
+	}
+	if cfg.Anomaly.Enabled {
+		anomalyCfg, err := anomalyPolicy(cfg.Anomaly)
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, rating.WithAnomalyDetector(anomaly.New(anomalyCfg)))
+	}
+	if deadLetter != nil {
+		svc.OnShutdown("dead-letter sink", func(context.Context) error { return deadLetter.Close() })
+		opts = append(opts, rating.WithDeadLetterSink(deadLetter))
+	}
+	ctrl := rating.New(repo, ingester, opts...)
+	svc.Health().Add("mysql", repo.Ping)
+	if p, ok := ingester.(pinger); ok {
+		svc.Health().Add("ingester", p.Ping)
+	}
+	prometheus.MustRegister(ingestionCollector{ctrl})
+	watcher.OnReload(func(cfg *serviceConfig) {
+		if limiter != nil {
+			if limits, err := rateLimits(cfg.RateLimit); err != nil {
+				logger.Error("Failed to apply rate limits", "error", err)
+			} else {
+				limiter.SetConfig(limits)
+			}
+		}
+		if err := ctrl.SetAggregationStrategy(model.AggregationStrategy(cfg.Leaderboard.Strategy)); err != nil {
+			logger.Error("Failed to apply leaderboard strategy", "error", err)
+		}
+	})
+	gen.RegisterRatingServiceServer(svc.GRPCServer(), grpchandler.New(ctrl))
+	svc.HandleHTTP("/rating", http.HandlerFunc(httphandler.New(ctrl).Handle))
+	svc.HandleAdmin("/config", watcher)
+	if interval := cfg.ReloadInterval; interval > 0 {
+		svc.Go("config reload", func(ctx context.Context) error {
+			watcher.Run(ctx, interval)
+			return nil
+		})
+	}
+	// The leaderboards are built in the background, so the service is serving while the ratings are read.
+	svc.Go("leaderboard rebuild", func(ctx context.Context) error {
+		if err := ctrl.RebuildLeaderboard(ctx); err != nil {
+			logger.Error("Failed to build leaderboards", "error", err)
+		}
+		interval := cfg.Leaderboard.RebuildInterval
+		if interval <= 0 {
+			return nil
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				if err := ctrl.RebuildLeaderboard(ctx); err != nil {
+					logger.Error("Failed to rebuild leaderboards", "error", err)
+				}
+			}
+		}
+	})
+	if ingester != nil {
+		svc.Go("rating ingestion", ctrl.StartIngestion)
+		// Offsets are committed on close, so the ingester is closed after the consumed events are persisted.
+		if c, ok := ingester.(io.Closer); ok {
+			svc.OnShutdown("ingester", func(context.Context) error { return c.Close() })
+		}
+	}
+	if err := svc.Run(context.Background()); err != nil {
+		logger.Error("Rating service stopped", "error", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/ugurcancaykara/odd-service/rating/internal/controller/rating"
+)
+
+var (
+	ingestedEventsDesc = prometheus.NewDesc("rating_ingestion_events_total",
+		"Number of ingested rating events by result (processed, failed, dead_lettered or quarantined).", []string{"result"}, nil)
+	ingestionLagDesc = prometheus.NewDesc("rating_ingestion_lag",
+		"Number of rating events waiting to be consumed, missing if the ingester can't report it.", nil, nil)
+)
+
+// ingestionCollector exports the rating ingestion stats of a controller, read on every scrape.
+type ingestionCollector struct {
+	ctrl *rating.Controller
+}
+
+func (c ingestionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ingestedEventsDesc
+	ch <- ingestionLagDesc
+}
+
+func (c ingestionCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.ctrl.IngestionStats()
+	for result, n := range map[string]uint64{
+		"processed":     stats.Processed,
+		"failed":        stats.Failed,
+		"dead_lettered": stats.DeadLettered,
+		"quarantined":   stats.Quarantined,
+	} {
+		ch <- prometheus.MustNewConstMetric(ingestedEventsDesc, prometheus.CounterValue, float64(n), result)
+	}
+	if stats.Lag >= 0 {
+		ch <- prometheus.MustNewConstMetric(ingestionLagDesc, prometheus.GaugeValue, float64(stats.Lag))
+	}
+}
@@ -0,0 +1,319 @@
+package anomaly
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// Config defines the thresholds of anomaly detection.
+type Config struct {
+	// Window defines the time window in which the recent ratings of a record are compared with its history.
+	Window time.Duration
+	// BaselineWindow defines how far back the usual rate of ratings of a record is measured.
+	BaselineWindow time.Duration
+	// BurstMinRatings and BurstFactor define a burst: at least BurstMinRatings ratings of a record within Window,
+	// BurstFactor times more than the average within Window over the baseline.
+	BurstMinRatings int
+	BurstFactor     float64
+	// NewUserAge defines how long a user is considered new after their first rating.
+	NewUserAge time.Duration
+	// UserRetention defines how long a user is remembered after their last rating,
+	// users rating again after a longer time are considered new.
+	UserRetention time.Duration
+	// NewUserMinRatings and NewUserRatio define a new user spike: at least NewUserMinRatings ratings of new users
+	// within Window, making up at least NewUserRatio of the recent ratings of a record.
+	NewUserMinRatings int
+	NewUserRatio      float64
+	// OutlierMinVotes defines how many ratings a record needs before outliers are detected. A rating is an outlier
+	// if both the rating and the mean of at least OutlierMinRatings recent ratings are OutlierDeviation or more
+	// away from the mean of the record, in the same direction. OutlierDeviation is a fraction of the range of the
+	// rating scale of the record type, e.g. 0.375 is 1.5 stars out of 1 to 5.
+	OutlierMinVotes   int
+	OutlierMinRatings int
+	OutlierDeviation  float64
+}
+
+// DefaultConfig defines the default anomaly detection config.
+var DefaultConfig = Config{
+	Window:            10 * time.Minute,
+	BaselineWindow:    24 * time.Hour,
+	BurstMinRatings:   20,
+	BurstFactor:       5,
+	NewUserAge:        24 * time.Hour,
+	UserRetention:     90 * 24 * time.Hour,
+	NewUserMinRatings: 5,
+	NewUserRatio:      0.5,
+	OutlierMinVotes:   20,
+	OutlierMinRatings: 5,
+	OutlierDeviation:  0.375,
+}
+
+// Baseline defines the accepted ratings of a record outliers are detected against.
+type Baseline struct {
+	Mean  float64
+	Votes int
+	// Scale is the rating scale of the record type, outliers aren't detected without one.
+	Scale model.RatingScale
+}
+
+type recordKey struct {
+	id  model.RecordID
+	typ model.RecordType
+}
+
+type event struct {
+	at      time.Time
+	value   float64
+	newUser bool
+}
+
+// history holds the ratings of a record within the window ending at its latest rating, ordered by time,
+// and the number of older ratings per window-sized bucket.
+type history struct {
+	recent  []event
+	buckets map[int64]int
+	latest  time.Time
+	// checked is when a rating of the record was last checked.
+	checked time.Time
+}
+
+// user holds the times of the first and the last rating of a user.
+type user struct {
+	first time.Time
+	last  time.Time
+}
+
+// Detector defines an anomaly detector watching incoming ratings for bursts per record,
+// spikes of ratings from new users and outlying ratings.
+//
+// Ratings are placed in time by when they were rated rather than when they are checked, so backfills of past
+// ratings are judged by the rate they were rated at. Ratings older than the window of their record are only
+// counted in its baseline.
+type Detector struct {
+	mu      sync.Mutex
+	cfg     Config
+	now     func() time.Time
+	users   map[model.UserID]user
+	records map[recordKey]*history
+	// pruned is when the users and records were last pruned.
+	pruned time.Time
+}
+
+// New creates a new anomaly detector. Zero values of cfg are replaced with the defaults.
+func New(cfg Config) *Detector {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultConfig.Window
+	}
+	if cfg.BaselineWindow < cfg.Window {
+		cfg.BaselineWindow = DefaultConfig.BaselineWindow
+	}
+	if cfg.BurstMinRatings <= 0 {
+		cfg.BurstMinRatings = DefaultConfig.BurstMinRatings
+	}
+	if cfg.BurstFactor <= 0 {
+		cfg.BurstFactor = DefaultConfig.BurstFactor
+	}
+	if cfg.NewUserAge <= 0 {
+		cfg.NewUserAge = DefaultConfig.NewUserAge
+	}
+	if cfg.UserRetention < cfg.NewUserAge {
+		cfg.UserRetention = max(DefaultConfig.UserRetention, cfg.NewUserAge)
+	}
+	if cfg.NewUserMinRatings <= 0 {
+		cfg.NewUserMinRatings = DefaultConfig.NewUserMinRatings
+	}
+	if cfg.NewUserRatio <= 0 {
+		cfg.NewUserRatio = DefaultConfig.NewUserRatio
+	}
+	if cfg.OutlierMinVotes <= 0 {
+		cfg.OutlierMinVotes = DefaultConfig.OutlierMinVotes
+	}
+	if cfg.OutlierMinRatings <= 0 {
+		cfg.OutlierMinRatings = DefaultConfig.OutlierMinRatings
+	}
+	if cfg.OutlierDeviation <= 0 {
+		cfg.OutlierDeviation = DefaultConfig.OutlierDeviation
+	}
+	return &Detector{
+		cfg:     cfg,
+		now:     time.Now,
+		users:   map[model.UserID]user{},
+		records: map[recordKey]*history{},
+	}
+}
+
+// Observe records a stored rating of a user, so that users with earlier ratings are not considered new.
+// Observing the same rating again has no effect, ratings older than the user retention are ignored.
+func (d *Detector) Observe(rating *model.Rating) {
+	first, last := rating.CreatedAt, rating.UpdatedAt
+	if first.IsZero() {
+		first = last
+	}
+	if last.IsZero() {
+		last = first
+	}
+	if first.IsZero() {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.now().Sub(last) >= d.cfg.UserRetention {
+		return
+	}
+	d.seen(rating.UserID, first, last)
+}
+
+// seen extends the known activity of a user and returns it.
+func (d *Detector) seen(userID model.UserID, first time.Time, last time.Time) user {
+	u, ok := d.users[userID]
+	if !ok || first.Before(u.first) {
+		u.first = first
+	}
+	if last.After(u.last) {
+		u.last = last
+	}
+	d.users[userID] = u
+	return u
+}
+
+// Check records an incoming rating of a record and returns the reasons it is suspicious, if any.
+// The rating is placed at its update or creation time, the current time if it has neither.
+// Flagged ratings still count as activity of the record and their user.
+func (d *Detector) Check(recordID model.RecordID, recordType model.RecordType, rating *model.Rating, baseline Baseline) []model.AnomalyReason {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+	d.prune(now)
+	at := rating.UpdatedAt
+	if at.IsZero() {
+		at = rating.CreatedAt
+	}
+	if at.IsZero() {
+		at = now
+	}
+	// The user is remembered from when they were last checked, so that users of backfilled ratings are retained.
+	u := d.seen(rating.UserID, at, now)
+	newUser := at.Sub(u.first) < d.cfg.NewUserAge
+
+	key := recordKey{recordID, recordType}
+	h, ok := d.records[key]
+	if !ok {
+		h = &history{buckets: map[int64]int{}}
+		d.records[key] = h
+	}
+	h.checked = now
+	if at.After(h.latest) {
+		h.latest = at
+	}
+	d.expire(h, h.latest)
+	if h.latest.Sub(at) >= d.cfg.Window {
+		// The window of the rating has passed, it only counts for the baseline.
+		if h.latest.Sub(at) < d.cfg.BaselineWindow {
+			h.buckets[d.bucket(at)]++
+		}
+		return nil
+	}
+	h.insert(event{at: at, value: float64(rating.Value), newUser: newUser})
+
+	var reasons []model.AnomalyReason
+	if d.burst(h) {
+		reasons = append(reasons, model.AnomalyReasonBurst)
+	}
+	if newUser && d.newUserSpike(h) {
+		reasons = append(reasons, model.AnomalyReasonNewUserSpike)
+	}
+	if d.outlier(h, float64(rating.Value), baseline) {
+		reasons = append(reasons, model.AnomalyReasonOutlier)
+	}
+	return reasons
+}
+
+// insert adds an event to the recent ratings, keeping them ordered by time.
+func (h *history) insert(e event) {
+	i := len(h.recent)
+	for i > 0 && h.recent[i-1].at.After(e.at) {
+		i--
+	}
+	h.recent = append(h.recent, event{})
+	copy(h.recent[i+1:], h.recent[i:])
+	h.recent[i] = e
+}
+
+// prune drops, at most once per window, the users not seen within the user retention and the records
+// not checked within the baseline window.
+func (d *Detector) prune(now time.Time) {
+	if now.Sub(d.pruned) < d.cfg.Window {
+		return
+	}
+	d.pruned = now
+	for id, u := range d.users {
+		if now.Sub(u.last) >= d.cfg.UserRetention {
+			delete(d.users, id)
+		}
+	}
+	for key, h := range d.records {
+		if now.Sub(h.checked) >= d.cfg.BaselineWindow {
+			delete(d.records, key)
+		}
+	}
+}
+
+// expire moves ratings older than the window into the baseline buckets and drops buckets older than the baseline.
+func (d *Detector) expire(h *history, now time.Time) {
+	i := 0
+	for ; i < len(h.recent) && now.Sub(h.recent[i].at) >= d.cfg.Window; i++ {
+		h.buckets[d.bucket(h.recent[i].at)]++
+	}
+	h.recent = h.recent[i:]
+	oldest := d.bucket(now.Add(-d.cfg.BaselineWindow))
+	for b := range h.buckets {
+		if b < oldest {
+			delete(h.buckets, b)
+		}
+	}
+}
+
+func (d *Detector) bucket(t time.Time) int64 {
+	return t.UnixNano() / int64(d.cfg.Window)
+}
+
+func (d *Detector) burst(h *history) bool {
+	n := len(h.recent)
+	if n < d.cfg.BurstMinRatings {
+		return false
+	}
+	var older int
+	for _, count := range h.buckets {
+		older += count
+	}
+	windows := float64(d.cfg.BaselineWindow / d.cfg.Window)
+	return float64(n) > d.cfg.BurstFactor*float64(older)/windows
+}
+
+func (d *Detector) newUserSpike(h *history) bool {
+	var n int
+	for _, e := range h.recent {
+		if e.newUser {
+			n++
+		}
+	}
+	return n >= d.cfg.NewUserMinRatings && float64(n) >= d.cfg.NewUserRatio*float64(len(h.recent))
+}
+
+func (d *Detector) outlier(h *history, value float64, baseline Baseline) bool {
+	if baseline.Votes < d.cfg.OutlierMinVotes || len(h.recent) < d.cfg.OutlierMinRatings || baseline.Scale.Max <= baseline.Scale.Min {
+		return false
+	}
+	threshold := d.cfg.OutlierDeviation * float64(baseline.Scale.Max-baseline.Scale.Min)
+	var sum float64
+	for _, e := range h.recent {
+		sum += e.value
+	}
+	shift := sum/float64(len(h.recent)) - baseline.Mean
+	deviation := value - baseline.Mean
+	return math.Abs(shift) >= threshold && math.Abs(deviation) >= threshold &&
+		math.Signbit(shift) == math.Signbit(deviation)
+}
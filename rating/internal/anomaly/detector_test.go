@@ -0,0 +1,191 @@
+package anomaly
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+func TestDetector_Check(t *testing.T) {
+	start := time.Date(2024, time.January, 8, 12, 0, 0, 0, time.UTC)
+	cfg := Config{
+		Window:            10 * time.Minute,
+		BaselineWindow:    time.Hour,
+		BurstMinRatings:   4,
+		BurstFactor:       2,
+		NewUserAge:        24 * time.Hour,
+		NewUserMinRatings: 3,
+		NewUserRatio:      0.5,
+		OutlierMinVotes:   10,
+		OutlierMinRatings: 3,
+		OutlierDeviation:  0.375,
+	}
+	established := func(d *Detector, users int) {
+		for i := 0; i < users; i++ {
+			d.Observe(&model.Rating{UserID: model.UserID(fmt.Sprintf("old%d", i)), CreatedAt: start.AddDate(0, -1, 0)})
+		}
+	}
+
+	tests := []struct {
+		name     string
+		baseline Baseline
+		// ratings are put one minute apart by established users unless newUsers is set.
+		values   []model.RatingValue
+		newUsers bool
+		expected [][]model.AnomalyReason
+	}{
+		{
+			name:     "Steady ratings",
+			values:   []model.RatingValue{4, 5, 3},
+			expected: [][]model.AnomalyReason{nil, nil, nil},
+		},
+		{
+			name:     "Burst",
+			values:   []model.RatingValue{4, 5, 3, 4, 4},
+			expected: [][]model.AnomalyReason{nil, nil, nil, {model.AnomalyReasonBurst}, {model.AnomalyReasonBurst}},
+		},
+		{
+			name:     "New user spike",
+			values:   []model.RatingValue{4, 5, 3},
+			newUsers: true,
+			expected: [][]model.AnomalyReason{nil, nil, {model.AnomalyReasonNewUserSpike}},
+		},
+		{
+			name:     "Outliers",
+			baseline: Baseline{Mean: 4.5, Votes: 100, Scale: model.RatingScaleStars5},
+			values:   []model.RatingValue{1, 5, 1},
+			expected: [][]model.AnomalyReason{nil, nil, {model.AnomalyReasonOutlier}},
+		},
+		{
+			name:     "Thumbs outliers",
+			baseline: Baseline{Mean: 0.9, Votes: 100, Scale: model.RatingScaleThumbs},
+			values:   []model.RatingValue{0, 1, 0},
+			expected: [][]model.AnomalyReason{nil, nil, {model.AnomalyReasonOutlier}},
+		},
+		{
+			name:     "Outliers need a scale",
+			baseline: Baseline{Mean: 4.5, Votes: 100},
+			values:   []model.RatingValue{1, 1, 1},
+			expected: [][]model.AnomalyReason{nil, nil, nil},
+		},
+		{
+			name:     "Outliers need enough votes",
+			baseline: Baseline{Mean: 4.5, Votes: 5, Scale: model.RatingScaleStars5},
+			values:   []model.RatingValue{1, 1, 1},
+			expected: [][]model.AnomalyReason{nil, nil, nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New(cfg)
+			now := start
+			d.now = func() time.Time { return now }
+			established(d, len(tt.values))
+			for i, v := range tt.values {
+				userID := model.UserID(fmt.Sprintf("old%d", i))
+				if tt.newUsers {
+					userID = model.UserID(fmt.Sprintf("new%d", i))
+				}
+				got := d.Check("1", model.RecordTypeMovie, &model.Rating{UserID: userID, Value: v}, tt.baseline)
+				assert.Equal(t, tt.expected[i], got, "rating %d", i)
+				now = now.Add(time.Minute)
+			}
+		})
+	}
+}
+
+func TestDetector_BurstBaseline(t *testing.T) {
+	now := time.Date(2024, time.January, 8, 12, 0, 0, 0, time.UTC)
+	d := New(Config{Window: 10 * time.Minute, BaselineWindow: time.Hour, BurstMinRatings: 3, BurstFactor: 2})
+	d.now = func() time.Time { return now }
+	check := func(n int) []model.AnomalyReason {
+		var reasons []model.AnomalyReason
+		for i := 0; i < n; i++ {
+			userID := model.UserID(fmt.Sprintf("user%d", i))
+			d.Observe(&model.Rating{UserID: userID, CreatedAt: now.AddDate(0, -1, 0)})
+			reasons = d.Check("1", model.RecordTypeMovie, &model.Rating{UserID: userID, Value: 4}, Baseline{})
+		}
+		return reasons
+	}
+
+	// 18 ratings within the last hour make a usual rate of 3 ratings per window.
+	for w := 0; w < 6; w++ {
+		check(3)
+		now = now.Add(10 * time.Minute)
+	}
+	assert.Empty(t, check(6))
+	now = now.Add(10 * time.Minute)
+	// The first window has left the baseline, which now holds 21 ratings in the last 6 windows.
+	assert.Empty(t, check(7))
+	assert.Equal(t, []model.AnomalyReason{model.AnomalyReasonBurst}, check(1))
+
+	// Ratings older than the baseline window are forgotten.
+	now = now.Add(2 * time.Hour)
+	assert.Equal(t, []model.AnomalyReason{model.AnomalyReasonBurst}, check(3))
+}
+
+func TestDetector_Backfill(t *testing.T) {
+	now := time.Date(2024, time.January, 8, 12, 0, 0, 0, time.UTC)
+	d := New(Config{Window: 10 * time.Minute, BaselineWindow: time.Hour, BurstMinRatings: 3, BurstFactor: 2, NewUserAge: time.Hour, NewUserMinRatings: 3, NewUserRatio: 0.5})
+	d.now = func() time.Time { return now }
+
+	// Ratings rated an hour apart a year ago are checked at once, neither a burst nor a new user spike.
+	rated := now.AddDate(-1, 0, 0)
+	for i := 0; i < 10; i++ {
+		at := rated.Add(time.Duration(i) * time.Hour)
+		rating := &model.Rating{UserID: model.UserID(fmt.Sprintf("user%d", i)), Value: 4, CreatedAt: at, UpdatedAt: at}
+		assert.Empty(t, d.Check("1", model.RecordTypeMovie, rating, Baseline{}), "rating %d", i)
+	}
+
+	// Ratings rated within a window are a burst, even if they are checked later.
+	for i := 0; i < 3; i++ {
+		at := rated.Add(20*time.Hour + time.Duration(i)*time.Minute)
+		rating := &model.Rating{UserID: "user1", Value: 4, UpdatedAt: at}
+		now = now.Add(time.Minute)
+		got := d.Check("2", model.RecordTypeMovie, rating, Baseline{})
+		if i == 2 {
+			assert.Equal(t, []model.AnomalyReason{model.AnomalyReasonBurst}, got)
+		}
+	}
+
+	// Ratings older than the window of the record only count for its baseline.
+	h := d.records[recordKey{"2", model.RecordTypeMovie}]
+	rating := &model.Rating{UserID: "user2", Value: 4, UpdatedAt: rated.Add(19*time.Hour + 30*time.Minute)}
+	assert.Empty(t, d.Check("2", model.RecordTypeMovie, rating, Baseline{}))
+	assert.Len(t, h.recent, 3)
+	assert.Equal(t, 1, h.buckets[d.bucket(rating.UpdatedAt)])
+}
+
+func TestDetector_Prune(t *testing.T) {
+	now := time.Date(2024, time.January, 8, 12, 0, 0, 0, time.UTC)
+	d := New(Config{Window: 10 * time.Minute, BaselineWindow: time.Hour, NewUserAge: time.Hour, UserRetention: 24 * time.Hour})
+	d.now = func() time.Time { return now }
+
+	d.Observe(&model.Rating{UserID: "dormant", CreatedAt: now.AddDate(0, -1, 0)})
+	assert.NotContains(t, d.users, model.UserID("dormant"), "users not seen within the retention aren't observed")
+	d.Observe(&model.Rating{UserID: "old", CreatedAt: now.AddDate(0, -1, 0), UpdatedAt: now.Add(-20 * time.Hour)})
+	d.Check("1", model.RecordTypeMovie, &model.Rating{UserID: "user1", Value: 4}, Baseline{})
+
+	now = now.Add(2 * time.Hour)
+	d.Check("2", model.RecordTypeMovie, &model.Rating{UserID: "user2", Value: 4}, Baseline{})
+	assert.Len(t, d.records, 1, "records not checked within the baseline window are dropped")
+	assert.Len(t, d.users, 3)
+
+	now = now.Add(4 * time.Hour)
+	d.Check("2", model.RecordTypeMovie, &model.Rating{UserID: "user2", Value: 4}, Baseline{})
+	assert.Equal(t, []model.UserID{"user1", "user2"}, sortedUsers(d), "users not seen within the retention are dropped")
+}
+
+func sortedUsers(d *Detector) []model.UserID {
+	var res []model.UserID
+	for id := range d.users {
+		res = append(res, id)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
@@ -0,0 +1,114 @@
+package rating
+
+import (
+	"sort"
+	"time"
+
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// TrustWeights defines how much the ratings of each provider count in aggregated ratings.
+// Ratings of providers missing from Providers are weighted with Default, a zero weight ignores a provider.
+type TrustWeights struct {
+	Default   float64
+	Providers map[string]float64
+}
+
+// DefaultTrustWeights weights all providers equally and is used unless WithTrustWeights is passed to New.
+var DefaultTrustWeights = TrustWeights{Default: 1}
+
+// Weight returns the trust weight of a provider.
+func (w TrustWeights) Weight(providerID string) float64 {
+	if weight, ok := w.Providers[providerID]; ok {
+		return weight
+	}
+	return w.Default
+}
+
+// WithTrustWeights sets the provider trust weights used to aggregate ratings.
+func WithTrustWeights(w TrustWeights) Option {
+	return func(c *Controller) {
+		c.weights = w
+	}
+}
+
+// aggregate returns the trust-weighted average of ratings.
+// It returns false if no rating has a positive weight.
+func (w TrustWeights) aggregate(ratings []model.Rating) (float64, bool) {
+	var sum, total float64
+	for _, r := range ratings {
+		weight := w.Weight(r.ProviderID)
+		sum += weight * float64(r.Value)
+		total += weight
+	}
+	if total <= 0 {
+		return 0, false
+	}
+	return sum / total, true
+}
+
+// stats returns the rating stats of a record with a breakdown per provider, sorted by provider id.
+func (w TrustWeights) stats(ratings []model.Rating) *model.RatingStats {
+	res := &model.RatingStats{Count: len(ratings)}
+	res.Aggregated, _ = w.aggregate(ratings)
+	byProvider := map[string]*model.ProviderRatingStats{}
+	for _, r := range ratings {
+		p, ok := byProvider[r.ProviderID]
+		if !ok {
+			p = &model.ProviderRatingStats{ProviderID: r.ProviderID, Weight: w.Weight(r.ProviderID)}
+			byProvider[r.ProviderID] = p
+		}
+		p.Count++
+		p.Average += float64(r.Value)
+	}
+	for _, p := range byProvider {
+		p.Average /= float64(p.Count)
+		res.Providers = append(res.Providers, *p)
+	}
+	sort.Slice(res.Providers, func(i, j int) bool {
+		return res.Providers[i].ProviderID < res.Providers[j].ProviderID
+	})
+	return res
+}
+
+// inWindow returns the ratings last updated within a time window.
+func inWindow(ratings []model.Rating, window model.TimeWindow) []model.Rating {
+	if window == (model.TimeWindow{}) {
+		return ratings
+	}
+	var res []model.Rating
+	for _, r := range ratings {
+		if window.Contains(r.UpdatedAt) {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// timeSeries buckets ratings by the UTC day or week (starting on Monday) they were last updated.
+func (w TrustWeights) timeSeries(ratings []model.Rating, interval model.TimeSeriesInterval) []model.RatingTimeSeriesPoint {
+	buckets := map[time.Time][]model.Rating{}
+	for _, r := range ratings {
+		start := bucketStart(r.UpdatedAt, interval)
+		buckets[start] = append(buckets[start], r)
+	}
+	res := make([]model.RatingTimeSeriesPoint, 0, len(buckets))
+	for start, bucket := range buckets {
+		v, _ := w.aggregate(bucket)
+		res = append(res, model.RatingTimeSeriesPoint{Start: start, Count: len(bucket), Average: v})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Start.Before(res[j].Start)
+	})
+	return res
+}
+
+func bucketStart(t time.Time, interval model.TimeSeriesInterval) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if interval != model.TimeSeriesIntervalWeek {
+		return day
+	}
+	// time.Weekday starts on Sunday.
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
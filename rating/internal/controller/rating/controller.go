@@ -4,8 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync/atomic"
+	"time"
 
+	"github.com/ugurcancaykara/odd-service/rating/internal/anomaly"
+	"github.com/ugurcancaykara/odd-service/rating/internal/leaderboard"
+	"github.com/ugurcancaykara/odd-service/rating/internal/recordtype"
 	"github.com/ugurcancaykara/odd-service/rating/internal/repository"
 	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
 )
@@ -13,34 +18,206 @@ import (
 // ErrNotFound is returned when no ratings are found for a record.
 var ErrNotFound = errors.New("ratings not found for a record")
 
+// ErrInvalidInterval is returned when a time series interval is not supported.
+var ErrInvalidInterval = errors.New("invalid time series interval")
+
+// ErrInvalidRating is returned when a rating has an unknown record type or a value outside the scale of its record type.
+var ErrInvalidRating = errors.New("invalid rating")
+
+// ErrInvalidPageToken is returned when a page token is malformed.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// Page sizes of listings, the default is used when no page size is requested.
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
 type ratingRepository interface {
 	Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error)
-	Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error
+	Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) (*model.Rating, error)
+	Delete(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) (*model.Rating, error)
+	ListByProvider(ctx context.Context, providerID string, pageToken string, pageSize int) ([]model.Rating, string, error)
+	DeleteByProvider(ctx context.Context, providerID string) (int64, error)
+	ForEach(ctx context.Context, fn func(model.Rating) error) error
+	GetUserRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) (*model.Rating, error)
+	ListUserRatings(ctx context.Context, userID model.UserID, pageToken string, pageSize int) ([]model.Rating, string, error)
 }
 
 type ratingIngester interface {
 	Ingest(ctx context.Context) (chan model.RatingEvent, error)
+	Commit(ctx context.Context, event model.RatingEvent) error
+}
+
+type eventValidator interface {
+	Validate(e *model.RatingEvent) error
+}
+
+type rateLimiter interface {
+	Allow(ctx context.Context, userID string, providerID string) error
+	AllowIngested(ctx context.Context, userID string, providerID string) error
+}
+
+type deadLetterSink interface {
+	Write(ctx context.Context, entry model.DeadLetterEntry) error
+}
+
+// Option configures a rating service controller.
+type Option func(*Controller)
+
+// WithValidator sets the validator applied to ingested rating events.
+func WithValidator(v eventValidator) Option {
+	return func(c *Controller) {
+		c.validator = v
+	}
+}
+
+// WithDeadLetterSink sets the sink receiving ingested rating events which are invalid or can't be persisted.
+func WithDeadLetterSink(sink deadLetterSink) Option {
+	return func(c *Controller) {
+		c.deadLetter = sink
+	}
+}
+
+// WithRecordTypes sets the registry of rateable record types and their rating scales,
+// recordtype.Default is used unless it is passed to New.
+func WithRecordTypes(r *recordtype.Registry) Option {
+	return func(c *Controller) {
+		c.recordTypes = r
+	}
+}
+
+// WithRateLimiter sets the rate limiter of rating writes. Writes through the API exceeding a limit are rejected,
+// ingested events are held back until the limit allows them instead.
+func WithRateLimiter(l rateLimiter) Option {
+	return func(c *Controller) {
+		c.limiter = l
+	}
+}
+
+// WithLogger sets the logger of the controller, slog.Default() unless set.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Controller) {
+		c.logger = l
+	}
+}
+
+// WithEventLogger sets the logger of messages logged per ingested event, which should be sampled.
+// The logger of the controller is used unless set.
+func WithEventLogger(l *slog.Logger) Option {
+	return func(c *Controller) {
+		c.eventLogger = l
+	}
 }
 
 // Controller defines a rating service controller.
 type Controller struct {
-	repo     ratingRepository
-	ingester ratingIngester
+	repo       ratingRepository
+	ingester   ratingIngester
+	retry      RetryConfig
+	ingestion  IngestionConfig
+	validator  eventValidator
+	deadLetter deadLetterSink
+	weights    TrustWeights
+	stats      ingestionCounters
+
+	leaderboardConfig leaderboard.Config
+	// strategy replaces the strategy of leaderboardConfig once set at runtime.
+	strategy     atomic.Pointer[model.AggregationStrategy]
+	leaderboards leaderboards
+	detector     *anomaly.Detector
+	limiter      rateLimiter
+	recordTypes  *recordtype.Registry
+
+	logger *slog.Logger
+	// eventLogger logs per ingested event and is usually sampled.
+	eventLogger *slog.Logger
 }
 
 // New creates a rating service controller.
-func New(repo ratingRepository, ingester ratingIngester) *Controller {
-	return &Controller{repo, ingester}
+func New(repo ratingRepository, ingester ratingIngester, opts ...Option) *Controller {
+	c := &Controller{
+		repo:              repo,
+		ingester:          ingester,
+		retry:             DefaultRetryConfig,
+		ingestion:         DefaultIngestionConfig,
+		weights:           DefaultTrustWeights,
+		leaderboardConfig: leaderboard.DefaultConfig,
+		recordTypes:       recordtype.Default,
+		logger:            slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.eventLogger == nil {
+		c.eventLogger = c.logger
+	}
+	c.leaderboards.current = c.newLeaderboard()
+	return c
 }
 
 // GetAggregatedRating returns the aggregated rating for a record or ErrNotFound if there are no ratings for it.
+// Ratings are weighted by the trust weight of their provider, ratings of providers weighted with zero are ignored.
 func (c *Controller) GetAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (float64, error) {
+	return c.GetAggregatedRatingInWindow(ctx, recordID, recordType, model.TimeWindow{})
+}
+
+// GetAggregatedRatingInWindow returns the aggregated rating of the ratings of a record last updated within a time window
+// or ErrNotFound if there are no such ratings.
+func (c *Controller) GetAggregatedRatingInWindow(ctx context.Context, recordID model.RecordID, recordType model.RecordType, window model.TimeWindow) (float64, error) {
+	ratings, err := c.getRatings(ctx, recordID, recordType)
+	if err != nil {
+		return 0, err
+	}
+	v, ok := c.weights.aggregate(inWindow(ratings, window))
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return v, nil
+}
+
+// GetRatingStats returns rating stats for a record with a breakdown per provider or ErrNotFound if there are no ratings for it.
+// The aggregated rating is also normalized to the scale of the record type, if it is registered.
+func (c *Controller) GetRatingStats(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (*model.RatingStats, error) {
+	ratings, err := c.getRatings(ctx, recordID, recordType)
+	if err != nil {
+		return nil, err
+	}
+	stats := c.weights.stats(ratings)
+	if scale, err := c.recordTypes.Scale(recordType); err == nil {
+		stats.Scale = scale
+		stats.Normalized = scale.Normalize(stats.Aggregated)
+	}
+	return stats, nil
+}
+
+// GetScale returns the rating scale of a record type or ErrInvalidRating if the record type is unknown.
+func (c *Controller) GetScale(recordType model.RecordType) (model.RatingScale, error) {
+	scale, err := c.recordTypes.Scale(recordType)
+	if err != nil {
+		return scale, fmt.Errorf("%w: %v", ErrInvalidRating, err)
+	}
+	return scale, nil
+}
+
+// ListRecordTypes returns the rateable record types in alphabetical order with their rating scales.
+func (c *Controller) ListRecordTypes() []model.RecordTypeScale {
+	types := c.recordTypes.Types()
+	res := make([]model.RecordTypeScale, 0, len(types))
+	for _, t := range types {
+		scale, _ := c.recordTypes.Scale(t)
+		res = append(res, model.RecordTypeScale{RecordType: t, Scale: scale})
+	}
+	return res
+}
+
+func (c *Controller) getRatings(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
 	ratings, err := c.repo.Get(ctx, recordID, recordType)
 	if err != nil && err == repository.ErrNotFound {
-		return 0, ErrNotFound
+		return nil, ErrNotFound
 	} else if err != nil {
-		log.Printf("Failed to get ratings for %v %v: %v", recordID, recordType, err)
-		log.Printf("Fallback: returning locally cached ratings for %v %v", recordID, recordType)
+		c.logger.ErrorContext(ctx, "Failed to get ratings, no cached ratings to fall back to",
+			"record_id", recordID, "record_type", recordType, "error", err)
 		// Fallback scenario to increase reliability of a service,
 		// return locally cached ratings in case of mysql db is unavailable
 		// Using fallbacks is an example of graceful degradation - a pracice of handling application failures in a wa
@@ -50,35 +227,145 @@ func (c *Controller) GetAggregatedRating(ctx context.Context, recordID model.Rec
 
 		// TODO: implement in-memory cache solution
 		// return c.getCachedRatings(recordID, recordType)
-		return 0, err
+		return nil, err
 	}
-	sum := float64(0)
-	for _, r := range ratings {
-		sum += float64(r.Value)
+	return ratings, nil
+}
+
+// GetRatingTimeSeries returns the number and aggregated value of the ratings of a record per day or week,
+// bucketed by the time the ratings were last updated. Only buckets containing ratings are returned, ordered by time.
+func (c *Controller) GetRatingTimeSeries(ctx context.Context, recordID model.RecordID, recordType model.RecordType, interval model.TimeSeriesInterval, window model.TimeWindow) ([]model.RatingTimeSeriesPoint, error) {
+	if interval != model.TimeSeriesIntervalDay && interval != model.TimeSeriesIntervalWeek {
+		return nil, ErrInvalidInterval
+	}
+	ratings, err := c.getRatings(ctx, recordID, recordType)
+	if err != nil {
+		return nil, err
 	}
-	return sum / float64(len(ratings)), nil
+	return c.weights.timeSeries(inWindow(ratings, window), interval), nil
 }
 
-// PutRating writes a rating for a given record.
+// PutRating writes a rating for a given record, replacing an earlier rating of the same user.
+// Ratings without ingestion, creation or update times are stamped with the current time.
+// If anomaly detection is enabled, suspicious ratings are quarantined instead until they are moderated.
+// If the user or provider of the rating exceeded their rate limit, the limiter error is returned.
+// It returns ErrInvalidRating if the record type is unknown or the value is outside its scale.
 func (c *Controller) PutRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
-	return c.repo.Put(ctx, recordID, recordType, rating)
+	if err := c.recordTypes.Validate(recordType, rating.Value); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRating, err)
+	}
+	if c.limiter != nil {
+		if err := c.limiter.Allow(ctx, string(rating.UserID), rating.ProviderID); err != nil {
+			return err
+		}
+	}
+	stamp(rating, time.Now())
+	if reasons := c.check(recordID, recordType, rating); len(reasons) > 0 {
+		return c.quarantine(ctx, recordID, recordType, rating, reasons)
+	}
+	return c.putRating(ctx, recordID, recordType, rating)
 }
 
-// StartIngestion starts the ingestion of rating events.
-func (s *Controller) StartIngestion(ctx context.Context) error {
-	ch, err := s.ingester.Ingest(ctx)
+func (c *Controller) putRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
+	c.leaderboards.writes.RLock()
+	defer c.leaderboards.writes.RUnlock()
+	old, err := c.repo.Put(ctx, recordID, recordType, rating)
 	if err != nil {
 		return err
 	}
-	fmt.Println("starting to ingestion")
-	for e := range ch {
-		if err := s.PutRating(ctx, e.RecordID, e.RecordType, &model.Rating{UserID: e.UserID, Value: e.Value}); err != nil {
+	c.leaderboardPut(recordID, recordType, *rating, old)
+	return nil
+}
+
+// DeleteRating deletes the rating of a user for a given record, including a quarantined one.
+func (c *Controller) DeleteRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) error {
+	if err := c.deleteRating(ctx, recordID, recordType, userID); err != nil {
+		return err
+	}
+	if repo, ok := c.repo.(quarantineRepository); ok {
+		if err := repo.DeleteQuarantined(ctx, recordID, recordType, userID); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (c *Controller) deleteRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) error {
+	c.leaderboards.writes.RLock()
+	defer c.leaderboards.writes.RUnlock()
+	old, err := c.repo.Delete(ctx, recordID, recordType, userID)
+	if err != nil {
+		return err
+	}
+	if old != nil {
+		c.leaderboardDelete(recordID, recordType, *old)
+	}
+	return nil
+}
+
+func stamp(rating *model.Rating, now time.Time) {
+	if rating.IngestedAt.IsZero() {
+		rating.IngestedAt = now
+	}
+	if rating.CreatedAt.IsZero() {
+		rating.CreatedAt = rating.IngestedAt
+	}
+	if rating.UpdatedAt.IsZero() {
+		rating.UpdatedAt = rating.IngestedAt
+	}
+}
+
+// GetUserRating returns the rating of a user for a record or ErrNotFound if the user didn't rate it.
+func (c *Controller) GetUserRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) (*model.Rating, error) {
+	rating, err := c.repo.GetUserRating(ctx, recordID, recordType, userID)
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return rating, err
+}
+
+// ListUserRatings returns a page of the ratings of a user ordered by record type and id, and the token of the next page.
+// An empty page token starts from the first page, an empty next page token marks the last page.
+func (c *Controller) ListUserRatings(ctx context.Context, userID model.UserID, pageToken string, pageSize int) ([]model.Rating, string, error) {
+	ratings, next, err := c.repo.ListUserRatings(ctx, userID, pageToken, normalizePageSize(pageSize))
+	if err != nil && errors.Is(err, repository.ErrInvalidPageToken) {
+		return nil, "", ErrInvalidPageToken
+	}
+	return ratings, next, err
+}
+
+func normalizePageSize(pageSize int) int {
+	if pageSize <= 0 {
+		return defaultPageSize
+	}
+	return min(pageSize, maxPageSize)
+}
+
+// ListProviderRatings returns a page of the ratings received from a provider ordered by record type, record id
+// and user id, and the token of the next page. An empty next page token marks the last page.
+func (c *Controller) ListProviderRatings(ctx context.Context, providerID string, pageToken string, pageSize int) ([]model.Rating, string, error) {
+	ratings, next, err := c.repo.ListByProvider(ctx, providerID, pageToken, normalizePageSize(pageSize))
+	if err != nil && errors.Is(err, repository.ErrInvalidPageToken) {
+		return nil, "", ErrInvalidPageToken
+	}
+	return ratings, next, err
+}
+
+// PurgeProviderRatings deletes all ratings received from a provider and returns how many were deleted.
+// The leaderboards are rebuilt afterwards.
+func (c *Controller) PurgeProviderRatings(ctx context.Context, providerID string) (int64, error) {
+	deleted, err := c.repo.DeleteByProvider(ctx, providerID)
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		if err := c.RebuildLeaderboard(ctx); err != nil {
+			c.logger.ErrorContext(ctx, "Failed to rebuild leaderboards after purging provider", "provider_id", providerID, "error", err)
+		}
+	}
+	return deleted, nil
+}
+
 // At this point, the rating service provides both a synchronous API for the callers that
 // want to create ratings in real time and asynchronous logic for ingesting
 // rating events from Apache Kafka.
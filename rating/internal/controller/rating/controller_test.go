@@ -4,11 +4,20 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	gen "github.com/ugurcancaykara/odd-service/gen/mock/rating/repository"
+	"github.com/ugurcancaykara/odd-service/rating/internal/anomaly"
+	"github.com/ugurcancaykara/odd-service/rating/internal/ingester/memory"
+	"github.com/ugurcancaykara/odd-service/rating/internal/leaderboard"
+	"github.com/ugurcancaykara/odd-service/rating/internal/ratelimit"
+	"github.com/ugurcancaykara/odd-service/rating/internal/recordtype"
 	"github.com/ugurcancaykara/odd-service/rating/internal/repository"
+	memoryrepo "github.com/ugurcancaykara/odd-service/rating/internal/repository/memory"
+	"github.com/ugurcancaykara/odd-service/rating/internal/validation"
 	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
 )
 
@@ -115,20 +124,36 @@ func TestController_PutRating(t *testing.T) {
 			recordType: "movie",
 			rating:     &model.Rating{UserID: "user1", Value: 5},
 			mockSetup: func() {
-				mockRepo.EXPECT().Put(gomock.Any(), gomock.Eq(model.RecordID("record1")), gomock.Eq(model.RecordType("movie")), gomock.Any()).Return(nil)
+				mockRepo.EXPECT().Put(gomock.Any(), gomock.Eq(model.RecordID("record1")), gomock.Eq(model.RecordType("movie")), gomock.Any()).Return(nil, nil)
 			},
 			expectedError: nil,
 		},
 		{
 			name:       "Error during rating put",
 			recordID:   "record2",
-			recordType: "music",
+			recordType: "movie",
 			rating:     &model.Rating{UserID: "user2", Value: 3},
 			mockSetup: func() {
-				mockRepo.EXPECT().Put(gomock.Any(), gomock.Eq(model.RecordID("record2")), gomock.Eq(model.RecordType("music")), gomock.Any()).Return(errors.New("database error"))
+				mockRepo.EXPECT().Put(gomock.Any(), gomock.Eq(model.RecordID("record2")), gomock.Eq(model.RecordType("movie")), gomock.Any()).Return(nil, errors.New("database error"))
 			},
 			expectedError: errors.New("database error"),
 		},
+		{
+			name:          "Unknown record type",
+			recordID:      "record3",
+			recordType:    "music",
+			rating:        &model.Rating{UserID: "user2", Value: 3},
+			mockSetup:     func() {},
+			expectedError: errors.New(`invalid rating: unknown record type "music"`),
+		},
+		{
+			name:          "Value out of scale",
+			recordID:      "record3",
+			recordType:    "movie",
+			rating:        &model.Rating{UserID: "user2", Value: -1},
+			mockSetup:     func() {},
+			expectedError: errors.New(`invalid rating: rating value out of scale: rating value -1 out of range [1, 5] of "movie"`),
+		},
 	}
 
 	for _, tt := range tests {
@@ -151,7 +176,11 @@ func TestController_StartIngestion(t *testing.T) {
 
 	mockRepo := gen.NewMockratingRepository(mockCtrl)
 	mockIngester := gen.NewMockratingIngester(mockCtrl)
-	controller := New(mockRepo, mockIngester)
+	controller := New(mockRepo, mockIngester, WithRetryConfig(RetryConfig{
+		InitialInterval: time.Millisecond,
+		MaxInterval:     time.Millisecond,
+		MaxElapsedTime:  20 * time.Millisecond,
+	}))
 
 	tests := []struct {
 		name          string
@@ -166,7 +195,42 @@ func TestController_StartIngestion(t *testing.T) {
 				close(events)
 
 				mockIngester.EXPECT().Ingest(gomock.Any()).Return(events, nil)
-				mockRepo.EXPECT().Put(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+				mockRepo.EXPECT().Put(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+				mockIngester.EXPECT().Commit(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Transient repository error is retried before commit",
+			setupMocks: func() {
+				events := make(chan model.RatingEvent, 1)
+				events <- model.RatingEvent{RecordID: "record1", RecordType: "movie", UserID: "user1", Value: 5}
+				close(events)
+
+				mockIngester.EXPECT().Ingest(gomock.Any()).Return(events, nil)
+				gomock.InOrder(
+					mockRepo.EXPECT().Put(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("database error")),
+					mockRepo.EXPECT().Put(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil),
+					mockIngester.EXPECT().Commit(gomock.Any(), gomock.Any()).Return(nil),
+				)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Persistent repository error skips the event",
+			setupMocks: func() {
+				events := make(chan model.RatingEvent, 2)
+				events <- model.RatingEvent{RecordID: "record1", RecordType: "movie", UserID: "user1", Value: 5}
+				events <- model.RatingEvent{RecordID: "record2", RecordType: "movie", UserID: "user1", Value: 4}
+				close(events)
+
+				mockIngester.EXPECT().Ingest(gomock.Any()).Return(events, nil)
+				mockRepo.EXPECT().Put(gomock.Any(), gomock.Eq(model.RecordID("record1")), gomock.Any(), gomock.Any()).Return(nil, errors.New("database error")).MinTimes(1)
+				mockRepo.EXPECT().Put(gomock.Any(), gomock.Eq(model.RecordID("record2")), gomock.Any(), gomock.Any()).Return(nil, nil)
+				mockIngester.EXPECT().Commit(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, e model.RatingEvent) error {
+					assert.Equal(t, model.RecordID("record2"), e.RecordID)
+					return nil
+				})
 			},
 			expectedError: nil,
 		},
@@ -192,3 +256,605 @@ func TestController_StartIngestion(t *testing.T) {
 		})
 	}
 }
+
+type fakeDeadLetterSink struct {
+	entries []model.DeadLetterEntry
+}
+
+func (s *fakeDeadLetterSink) Write(_ context.Context, entry model.DeadLetterEntry) error {
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func TestController_StartIngestionDeadLetter(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockRepo := gen.NewMockratingRepository(mockCtrl)
+	ingester := memory.NewIngester(2)
+	sink := &fakeDeadLetterSink{}
+	controller := New(mockRepo, ingester, WithValidator(validation.New(validation.DefaultConfig)), WithDeadLetterSink(sink))
+
+	valid := model.RatingEvent{RecordID: "record1", RecordType: "movie", UserID: "user1", Value: 5}
+	invalid := model.RatingEvent{
+		RecordID:   "record1",
+		RecordType: "movie",
+		UserID:     "user1",
+		Value:      42,
+		Source:     &model.EventSource{Topic: "ratings", Offset: 7, Payload: []byte("raw")},
+	}
+	mockRepo.EXPECT().Put(gomock.Any(), gomock.Eq(model.RecordID("record1")), gomock.Any(), gomock.Any()).Return(nil, nil)
+	assert.NoError(t, ingester.Publish(context.Background(), invalid, valid))
+	ingester.Close()
+
+	assert.NoError(t, controller.StartIngestion(context.Background()))
+	assert.Len(t, ingester.Committed(), 2)
+	if assert.Len(t, sink.entries, 1) {
+		assert.Equal(t, model.DeadLetterReasonValidate, sink.entries[0].Reason)
+		assert.Equal(t, []byte("raw"), sink.entries[0].Payload)
+		assert.Equal(t, int64(7), sink.entries[0].Source.Offset)
+		assert.Contains(t, sink.entries[0].Error, "out of range")
+	}
+}
+
+func TestController_StartIngestionOrdering(t *testing.T) {
+	repo := memoryrepo.New()
+	ingester := memory.NewIngester(100)
+	controller := New(repo, ingester, WithIngestionConfig(IngestionConfig{
+		Workers:         4,
+		QueueSize:       10,
+		BatchSize:       5,
+		FlushInterval:   time.Millisecond,
+		ShutdownTimeout: time.Second,
+	}))
+
+	var events []model.RatingEvent
+	for _, recordID := range []model.RecordID{"record1", "record2", "record3"} {
+		events = append(events,
+			model.RatingEvent{RecordID: recordID, RecordType: "movie", UserID: "user1", Value: 1, EventType: model.RatingEventTypePut},
+			model.RatingEvent{RecordID: recordID, RecordType: "movie", UserID: "user2", Value: 2, EventType: model.RatingEventTypePut},
+			model.RatingEvent{RecordID: recordID, RecordType: "movie", UserID: "user1", EventType: model.RatingEventTypeDelete},
+			model.RatingEvent{RecordID: recordID, RecordType: "movie", UserID: "user2", Value: 4, EventType: model.RatingEventTypePut},
+		)
+	}
+	assert.NoError(t, ingester.Publish(context.Background(), events...))
+	ingester.Close()
+
+	assert.NoError(t, controller.StartIngestion(context.Background()))
+	assert.Len(t, ingester.Committed(), len(events))
+	for _, recordID := range []model.RecordID{"record1", "record2", "record3"} {
+		ratings, err := repo.Get(context.Background(), recordID, "movie")
+		assert.NoError(t, err)
+		if assert.Len(t, ratings, 1, recordID) {
+			assert.Equal(t, model.UserID("user2"), ratings[0].UserID)
+			assert.Equal(t, model.RatingValue(4), ratings[0].Value)
+		}
+	}
+	assert.Equal(t, uint64(len(events)), controller.IngestionStats().Processed)
+}
+
+// contextRepository fails writes once the context is done, like a database driver.
+type contextRepository struct {
+	*memoryrepo.Repository
+}
+
+func (r contextRepository) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) (*model.Rating, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.Repository.Put(ctx, recordID, recordType, rating)
+}
+
+func (r contextRepository) PutBatch(ctx context.Context, ratings []model.Rating) ([]*model.Rating, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.Repository.PutBatch(ctx, ratings)
+}
+
+func TestController_IngestionShutdown(t *testing.T) {
+	repo := contextRepository{memoryrepo.New()}
+	ingester := memory.NewIngester(0)
+	controller := New(repo, ingester, WithIngestionConfig(IngestionConfig{
+		Workers:         1,
+		QueueSize:       10,
+		BatchSize:       2,
+		FlushInterval:   time.Hour,
+		ShutdownTimeout: time.Second,
+	}))
+	queue := make(chan model.RatingEvent, 10)
+	for _, recordID := range []model.RecordID{"record1", "record2", "record3"} {
+		queue <- model.RatingEvent{RecordID: recordID, RecordType: "movie", UserID: "user1", Value: 4, EventType: model.RatingEventTypePut}
+	}
+	close(queue)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The worker pool is closed with events left in the queue.
+	controller.runWorker(ctx, queue)
+	assert.Len(t, ingester.Committed(), 3, "the events left in the queue are persisted and committed")
+	for _, recordID := range []model.RecordID{"record1", "record2", "record3"} {
+		ratings, err := repo.Get(context.Background(), recordID, "movie")
+		require.NoError(t, err)
+		assert.Len(t, ratings, 1, recordID)
+	}
+}
+
+func TestController_TrustWeights(t *testing.T) {
+	repo := memoryrepo.New()
+	ctx := context.Background()
+	for _, r := range []model.Rating{
+		{UserID: "user1", Value: 5, ProviderID: "trusted"},
+		{UserID: "user2", Value: 3, ProviderID: "trusted"},
+		{UserID: "user3", Value: 1, ProviderID: "spammy"},
+		{UserID: "user4", Value: 1, ProviderID: "unknown"},
+	} {
+		putRating(t, repo, "record1", "movie", &r)
+	}
+
+	tests := []struct {
+		name           string
+		weights        TrustWeights
+		expectedResult float64
+		expectedError  error
+	}{
+		{
+			name:           "Equal weights",
+			weights:        DefaultTrustWeights,
+			expectedResult: 2.5,
+		},
+		{
+			name:           "Provider weights",
+			weights:        TrustWeights{Default: 1, Providers: map[string]float64{"trusted": 2, "spammy": 0}},
+			expectedResult: 3.4,
+		},
+		{
+			name:          "No trusted provider",
+			weights:       TrustWeights{Default: 0},
+			expectedError: ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := New(repo, nil, WithTrustWeights(tt.weights))
+			v, err := controller.GetAggregatedRating(ctx, "record1", "movie")
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.expectedResult, v, 1e-9)
+		})
+	}
+
+	controller := New(repo, nil, WithTrustWeights(TrustWeights{Default: 1, Providers: map[string]float64{"spammy": 0}}))
+	stats, err := controller.GetRatingStats(ctx, "record1", "movie")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, stats.Count)
+	assert.Equal(t, []model.ProviderRatingStats{
+		{ProviderID: "spammy", Count: 1, Average: 1, Weight: 0},
+		{ProviderID: "trusted", Count: 2, Average: 4, Weight: 1},
+		{ProviderID: "unknown", Count: 1, Average: 1, Weight: 1},
+	}, stats.Providers)
+
+	deleted, err := controller.PurgeProviderRatings(ctx, "spammy")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	ratings, next, err := controller.ListProviderRatings(ctx, "spammy", "", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, ratings)
+	assert.Empty(t, next)
+	ratings, next, err = controller.ListProviderRatings(ctx, "trusted", "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, ratings, 2)
+	assert.Empty(t, next)
+}
+
+func TestController_ListProviderRatings(t *testing.T) {
+	repo := memoryrepo.New()
+	ctx := context.Background()
+	for _, r := range []struct {
+		recordID model.RecordID
+		userID   model.UserID
+		provider string
+	}{
+		{"2", "user1", "imdb"},
+		{"1", "user2", "imdb"},
+		{"1", "user1", "imdb"},
+		{"1", "user3", "letterboxd"},
+		{"3", "user1", "imdb"},
+	} {
+		putRating(t, repo, r.recordID, "movie", &model.Rating{UserID: r.userID, Value: 4, ProviderID: r.provider})
+	}
+	controller := New(repo, nil)
+
+	var got []string
+	token := ""
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+		ratings, next, err := controller.ListProviderRatings(ctx, "imdb", token, 2)
+		require.NoError(t, err)
+		for _, r := range ratings {
+			got = append(got, r.RecordID+"/"+string(r.UserID))
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	assert.Equal(t, []string{"1/user1", "1/user2", "2/user1", "3/user1"}, got)
+
+	_, _, err := controller.ListProviderRatings(ctx, "imdb", "!", 0)
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
+}
+
+func TestController_GetRatingTimeSeries(t *testing.T) {
+	repo := memoryrepo.New()
+	ctx := context.Background()
+	day := func(d int) time.Time {
+		// 2024-01-01 is a Monday.
+		return time.Date(2024, time.January, d, 12, 0, 0, 0, time.UTC)
+	}
+	for _, r := range []model.Rating{
+		{UserID: "user1", Value: 5, UpdatedAt: day(1)},
+		{UserID: "user2", Value: 3, UpdatedAt: day(1)},
+		{UserID: "user3", Value: 4, UpdatedAt: day(3)},
+		{UserID: "user4", Value: 1, UpdatedAt: day(8)},
+	} {
+		putRating(t, repo, "record1", "movie", &r)
+	}
+	controller := New(repo, nil)
+
+	points, err := controller.GetRatingTimeSeries(ctx, "record1", "movie", model.TimeSeriesIntervalDay, model.TimeWindow{})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.RatingTimeSeriesPoint{
+		{Start: day(1).Truncate(24 * time.Hour), Count: 2, Average: 4},
+		{Start: day(3).Truncate(24 * time.Hour), Count: 1, Average: 4},
+		{Start: day(8).Truncate(24 * time.Hour), Count: 1, Average: 1},
+	}, points)
+
+	points, err = controller.GetRatingTimeSeries(ctx, "record1", "movie", model.TimeSeriesIntervalWeek, model.TimeWindow{To: day(8)})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.RatingTimeSeriesPoint{
+		{Start: day(1).Truncate(24 * time.Hour), Count: 3, Average: 4},
+	}, points)
+
+	_, err = controller.GetRatingTimeSeries(ctx, "record1", "movie", "month", model.TimeWindow{})
+	assert.ErrorIs(t, err, ErrInvalidInterval)
+
+	v, err := controller.GetAggregatedRatingInWindow(ctx, "record1", "movie", model.TimeWindow{From: day(2), To: day(9)})
+	assert.NoError(t, err)
+	assert.Equal(t, 2.5, v)
+
+	_, err = controller.GetAggregatedRatingInWindow(ctx, "record1", "movie", model.TimeWindow{From: day(9)})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, controller.PutRating(ctx, "record1", "movie", &model.Rating{UserID: "user1", Value: 2}))
+	ratings, err := repo.Get(ctx, "record1", "movie")
+	assert.NoError(t, err)
+	assert.Len(t, ratings, 4)
+	for _, r := range ratings {
+		if r.UserID == "user1" {
+			assert.Equal(t, model.RatingValue(2), r.Value)
+			assert.True(t, r.UpdatedAt.After(day(8)))
+		}
+	}
+}
+
+func TestController_Leaderboards(t *testing.T) {
+	repo := memoryrepo.New()
+	ctx := context.Background()
+	putRating(t, repo, "record1", "movie", &model.Rating{UserID: "user1", Value: 2})
+	controller := New(repo, nil)
+
+	records, err := controller.ListTopRated(ctx, "movie", model.AggregationStrategyMean, 0, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, records, "ratings are only picked up from the repository on rebuild")
+
+	assert.NoError(t, controller.RebuildLeaderboard(ctx))
+	assert.NoError(t, controller.PutRating(ctx, "record2", "movie", &model.Rating{UserID: "user1", Value: 4}))
+	records, err = controller.ListTopRated(ctx, "movie", model.AggregationStrategyMean, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.RankedRecord{
+		{RecordID: "record2", RecordType: "movie", Score: 4, Votes: 1},
+		{RecordID: "record1", RecordType: "movie", Score: 2, Votes: 1},
+	}, records)
+
+	assert.NoError(t, controller.DeleteRating(ctx, "record2", "movie", "user1"))
+	records, err = controller.ListTrending(ctx, "movie", time.Hour, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.RankedRecord{
+		{RecordID: "record1", RecordType: "movie", Score: 1, Votes: 1},
+		{RecordID: "record2", RecordType: "movie", Score: 1, Votes: 1},
+	}, records, "deleted ratings still count as rating activity")
+	records, err = controller.ListTopRated(ctx, "movie", "", 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	assert.ErrorIs(t, controller.SetAggregationStrategy("median"), leaderboard.ErrUnknownStrategy)
+	assert.NoError(t, controller.SetAggregationStrategy(model.AggregationStrategyBayesian))
+	bayesian, err := controller.ListTopRated(ctx, "movie", model.AggregationStrategyBayesian, 0, 10)
+	assert.NoError(t, err)
+	records, err = controller.ListTopRated(ctx, "movie", "", 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, bayesian, records)
+	assert.NotEqual(t, 2.0, records[0].Score)
+}
+
+// snapshotRepository reads all ratings on ForEach and calls beforeScan and afterScan around passing them on,
+// simulating writes racing the rebuild of the leaderboards.
+type snapshotRepository struct {
+	*memoryrepo.Repository
+	beforeScan func()
+	afterScan  func()
+}
+
+func (r *snapshotRepository) ForEach(ctx context.Context, fn func(model.Rating) error) error {
+	r.beforeScan()
+	var ratings []model.Rating
+	if err := r.Repository.ForEach(ctx, func(rating model.Rating) error {
+		ratings = append(ratings, rating)
+		return nil
+	}); err != nil {
+		return err
+	}
+	r.afterScan()
+	for _, rating := range ratings {
+		if err := fn(rating); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestController_RebuildLeaderboard_ConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	repo := &snapshotRepository{Repository: memoryrepo.New()}
+	putRating(t, repo.Repository, "record1", "movie", &model.Rating{UserID: "user1", Value: 1})
+	putRating(t, repo.Repository, "record2", "movie", &model.Rating{UserID: "user1", Value: 1})
+	controller := New(repo, nil)
+
+	// The rebuild sees the write to record1 but not the one to record2, both are applied exactly once.
+	repo.beforeScan = func() {
+		assert.NoError(t, controller.PutRating(ctx, "record1", "movie", &model.Rating{UserID: "user1", Value: 5}))
+	}
+	repo.afterScan = func() {
+		assert.NoError(t, controller.PutRating(ctx, "record2", "movie", &model.Rating{UserID: "user2", Value: 3}))
+	}
+	require.NoError(t, controller.RebuildLeaderboard(ctx))
+	records, err := controller.ListTopRated(ctx, "movie", model.AggregationStrategyMean, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []model.RankedRecord{
+		{RecordID: "record1", RecordType: "movie", Score: 5, Votes: 1},
+		{RecordID: "record2", RecordType: "movie", Score: 2, Votes: 2},
+	}, records)
+
+	repo.beforeScan = func() {
+		assert.NoError(t, controller.DeleteRating(ctx, "record1", "movie", "user1"))
+	}
+	repo.afterScan = func() {}
+	require.NoError(t, controller.RebuildLeaderboard(ctx))
+	records, err = controller.ListTopRated(ctx, "movie", model.AggregationStrategyMean, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []model.RankedRecord{{RecordID: "record2", RecordType: "movie", Score: 2, Votes: 2}}, records)
+}
+
+func TestController_UserRatings(t *testing.T) {
+	repo := memoryrepo.New()
+	ctx := context.Background()
+	for _, id := range []model.RecordID{"3", "1", "2"} {
+		putRating(t, repo, id, "movie", &model.Rating{UserID: "user1", Value: 4})
+	}
+	putRating(t, repo, "1", "movie", &model.Rating{UserID: "user2", Value: 1})
+	controller := New(repo, nil)
+
+	r, err := controller.GetUserRating(ctx, "1", "movie", "user2")
+	assert.NoError(t, err)
+	assert.Equal(t, model.RatingValue(1), r.Value)
+	_, err = controller.GetUserRating(ctx, "2", "movie", "user2")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	var ids []string
+	token := ""
+	for {
+		ratings, next, err := controller.ListUserRatings(ctx, "user1", token, 2)
+		assert.NoError(t, err)
+		for _, r := range ratings {
+			ids = append(ids, r.RecordID)
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	assert.Equal(t, []string{"1", "2", "3"}, ids)
+
+	assert.NoError(t, controller.DeleteRating(ctx, "2", "movie", "user1"))
+	ratings, next, err := controller.ListUserRatings(ctx, "user1", "", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, next)
+	assert.Len(t, ratings, 2)
+
+	_, _, err = controller.ListUserRatings(ctx, "user1", "!", 0)
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
+}
+
+func TestController_Moderation(t *testing.T) {
+	repo := memoryrepo.New()
+	ctx := context.Background()
+	putRating(t, repo, "record1", "movie", &model.Rating{UserID: "user1", Value: 5, CreatedAt: time.Now().AddDate(0, -1, 0)})
+	detector := anomaly.New(anomaly.Config{NewUserMinRatings: 2, NewUserRatio: 0.5})
+	controller := New(repo, nil, WithAnomalyDetector(detector))
+	assert.NoError(t, controller.RebuildLeaderboard(ctx))
+
+	// The second rating of a new user within the window makes a new user spike.
+	for _, userID := range []model.UserID{"user1", "new1", "new2"} {
+		assert.NoError(t, controller.PutRating(ctx, "record1", "movie", &model.Rating{UserID: userID, Value: 1}))
+	}
+	ratings, err := repo.Get(ctx, "record1", "movie")
+	assert.NoError(t, err)
+	assert.Len(t, ratings, 2)
+
+	quarantined, next, err := controller.ListQuarantinedRatings(ctx, "", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, next)
+	assert.Len(t, quarantined, 1)
+	assert.Equal(t, model.UserID("new2"), quarantined[0].Rating.UserID)
+	assert.Equal(t, []model.AnomalyReason{model.AnomalyReasonNewUserSpike}, quarantined[0].Reasons)
+
+	assert.ErrorIs(t, controller.ModerateRating(ctx, "record1", "movie", "new2", "ignore"), ErrInvalidDecision)
+	assert.NoError(t, controller.ModerateRating(ctx, "record1", "movie", "new2", model.ModerationDecisionApprove))
+	assert.ErrorIs(t, controller.ModerateRating(ctx, "record1", "movie", "new2", model.ModerationDecisionReject), ErrNotQuarantined)
+	ratings, err = repo.Get(ctx, "record1", "movie")
+	assert.NoError(t, err)
+	assert.Len(t, ratings, 3)
+	top, err := controller.ListTopRated(ctx, "movie", model.AggregationStrategyMean, 0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, top[0].Votes)
+
+	assert.NoError(t, controller.PutRating(ctx, "record1", "movie", &model.Rating{UserID: "new3", Value: 1}))
+	assert.NoError(t, controller.ModerateRating(ctx, "record1", "movie", "new3", model.ModerationDecisionReject))
+	quarantined, _, err = controller.ListQuarantinedRatings(ctx, "", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, quarantined)
+	_, err = controller.GetUserRating(ctx, "record1", "movie", "new3")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestController_RateLimit(t *testing.T) {
+	repo := memoryrepo.New()
+	ctx := context.Background()
+	limiter := ratelimit.New(ratelimit.Config{
+		User:         ratelimit.Limit{Rate: 50, Burst: 1},
+		IngestedUser: ratelimit.Limit{Rate: 5, Burst: 1},
+	}, ratelimit.NewMemoryStore())
+	ingester := memory.NewIngester(10)
+	controller := New(repo, ingester, WithRateLimiter(limiter),
+		WithIngestionConfig(IngestionConfig{Workers: 1, QueueSize: 10, BatchSize: 1, FlushInterval: time.Millisecond}))
+
+	assert.NoError(t, controller.PutRating(ctx, "record1", "movie", &model.Rating{UserID: "user1", Value: 4}))
+	assert.ErrorIs(t, controller.PutRating(ctx, "record2", "movie", &model.Rating{UserID: "user1", Value: 4}), ratelimit.ErrRateLimited)
+	assert.NoError(t, controller.PutRating(ctx, "record2", "movie", &model.Rating{UserID: "user2", Value: 4}))
+
+	// Ingested events exceeding the limit are held back rather than rejected,
+	// without holding back the events of other records of the same worker.
+	for _, id := range []model.RecordID{"record3", "record4", "record5"} {
+		assert.NoError(t, ingester.Publish(ctx, model.RatingEvent{UserID: "user3", RecordID: id, RecordType: "movie", Value: 3, EventType: model.RatingEventTypePut}))
+	}
+	assert.NoError(t, ingester.Publish(ctx, model.RatingEvent{UserID: "user4", RecordID: "record6", RecordType: "movie", Value: 3, EventType: model.RatingEventTypePut}))
+	// The delete is held back behind the held back put of its record.
+	for _, e := range []model.RatingEvent{
+		{UserID: "user5", RecordID: "record7", RecordType: "movie", Value: 3, EventType: model.RatingEventTypePut},
+		{UserID: "user5", RecordID: "record7", RecordType: "movie", Value: 4, EventType: model.RatingEventTypePut},
+		{UserID: "user5", RecordID: "record7", RecordType: "movie", EventType: model.RatingEventTypeDelete},
+	} {
+		assert.NoError(t, ingester.Publish(ctx, e))
+	}
+	userRatings := func(userID model.UserID) int {
+		ratings, _, err := repo.ListUserRatings(ctx, userID, "", 10)
+		assert.NoError(t, err)
+		return len(ratings)
+	}
+	ingestCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() { done <- controller.StartIngestion(ingestCtx) }()
+	require.Eventually(t, func() bool { return userRatings("user4") == 1 }, time.Second, time.Millisecond)
+	assert.Less(t, userRatings("user3"), 3)
+	assert.Eventually(t, func() bool { return userRatings("user3") == 3 }, 2*time.Second, 10*time.Millisecond)
+	assert.Zero(t, userRatings("user5"))
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestController_RecordTypes(t *testing.T) {
+	repo := memoryrepo.New()
+	ctx := context.Background()
+	types, err := recordtype.New(map[model.RecordType]model.RatingScale{
+		model.RecordTypeMovie: model.RatingScaleStars5,
+		"series":              model.RatingScalePoints10,
+		"episode":             model.RatingScaleThumbs,
+	})
+	assert.NoError(t, err)
+	controller := New(repo, nil, WithRecordTypes(types))
+
+	assert.NoError(t, controller.PutRating(ctx, "1", "series", &model.Rating{UserID: "user1", Value: 10}))
+	assert.NoError(t, controller.PutRating(ctx, "1", "series", &model.Rating{UserID: "user2", Value: 5}))
+	assert.NoError(t, controller.PutRating(ctx, "1", "episode", &model.Rating{UserID: "user1", Value: 0}))
+	assert.ErrorIs(t, controller.PutRating(ctx, "1", "episode", &model.Rating{UserID: "user1", Value: 2}), ErrInvalidRating)
+	assert.ErrorIs(t, controller.PutRating(ctx, "1", "music", &model.Rating{UserID: "user1", Value: 1}), ErrInvalidRating)
+
+	stats, err := controller.GetRatingStats(ctx, "1", "series")
+	assert.NoError(t, err)
+	assert.Equal(t, 7.5, stats.Aggregated)
+	assert.Equal(t, model.RatingScalePoints10, stats.Scale)
+	assert.InDelta(t, 6.5/9, stats.Normalized, 1e-9)
+
+	assert.Equal(t, []model.RecordTypeScale{
+		{RecordType: "episode", Scale: model.RatingScaleThumbs},
+		{RecordType: model.RecordTypeMovie, Scale: model.RatingScaleStars5},
+		{RecordType: "series", Scale: model.RatingScalePoints10},
+	}, controller.ListRecordTypes())
+}
+
+func TestController_ExportRatings(t *testing.T) {
+	repo := memoryrepo.New()
+	ctx := context.Background()
+	updated := time.Date(2024, time.January, 8, 12, 0, 0, 0, time.UTC)
+	putRating(t, repo, "2", "movie", &model.Rating{UserID: "user1", Value: 4, ProviderID: "imdb", UpdatedAt: updated})
+	putRating(t, repo, "1", "movie", &model.Rating{UserID: "user2", Value: 3, ProviderID: "imdb", UpdatedAt: updated.Add(time.Hour)})
+	putRating(t, repo, "1", "movie", &model.Rating{UserID: "user1", Value: 5, UpdatedAt: updated})
+	putRating(t, repo, "1", "series", &model.Rating{UserID: "user1", Value: 2, ProviderID: "imdb", UpdatedAt: updated})
+	controller := New(repo, nil)
+
+	tests := []struct {
+		name     string
+		filter   model.ExportFilter
+		expected []string
+	}{
+		{
+			name:     "All ratings",
+			expected: []string{"1/movie/user1", "1/movie/user2", "1/series/user1", "2/movie/user1"},
+		},
+		{
+			name:     "Record type",
+			filter:   model.ExportFilter{RecordType: "movie"},
+			expected: []string{"1/movie/user1", "1/movie/user2", "2/movie/user1"},
+		},
+		{
+			name:     "Provider and time range",
+			filter:   model.ExportFilter{ProviderID: "imdb", Window: model.TimeWindow{To: updated.Add(time.Minute)}},
+			expected: []string{"1/series/user1", "2/movie/user1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := controller.ExportRatings(ctx, tt.filter, func(r model.Rating) error {
+				got = append(got, r.RecordID+"/"+r.RecordType+"/"+string(r.UserID))
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func putRating(t *testing.T, repo *memoryrepo.Repository, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) {
+	t.Helper()
+	_, err := repo.Put(context.Background(), recordID, recordType, rating)
+	require.NoError(t, err)
+}
+
+func TestToRating(t *testing.T) {
+	rated := time.Date(2023, time.March, 4, 20, 15, 0, 0, time.UTC)
+	r := toRating(model.RatingEvent{UserID: "user1", RecordID: "1", RecordType: "movie", Value: 4, Timestamp: rated})
+	assert.Equal(t, rated, r.CreatedAt, "backfilled ratings are created when they were rated")
+	assert.Equal(t, rated, r.UpdatedAt)
+	assert.True(t, r.IngestedAt.After(rated))
+
+	r = toRating(model.RatingEvent{UserID: "user1", RecordID: "1", RecordType: "movie", Value: 4})
+	assert.Equal(t, r.IngestedAt, r.CreatedAt)
+	assert.Equal(t, r.IngestedAt, r.UpdatedAt)
+}
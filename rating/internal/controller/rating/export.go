@@ -0,0 +1,27 @@
+package rating
+
+import (
+	"context"
+
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// exportRepository is implemented by repositories able to export a consistent snapshot of filtered ratings.
+type exportRepository interface {
+	Export(ctx context.Context, filter model.ExportFilter, fn func(model.Rating) error) error
+}
+
+// ExportRatings calls fn for every stored rating matching a filter, each rating carrying its record id and type.
+// Repositories supporting exports provide a consistent snapshot, others are scanned while being written.
+// The export stops at the first error returned by fn.
+func (c *Controller) ExportRatings(ctx context.Context, filter model.ExportFilter, fn func(model.Rating) error) error {
+	if repo, ok := c.repo.(exportRepository); ok {
+		return repo.Export(ctx, filter, fn)
+	}
+	return c.repo.ForEach(ctx, func(rating model.Rating) error {
+		if !filter.Matches(&rating) {
+			return nil
+		}
+		return fn(rating)
+	})
+}
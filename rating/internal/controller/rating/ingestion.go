@@ -0,0 +1,584 @@
+package rating
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log/slog"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
+	"github.com/ugurcancaykara/odd-service/rating/internal/ratelimit"
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// batchRepository is implemented by repositories able to write multiple ratings at once.
+type batchRepository interface {
+	PutBatch(ctx context.Context, ratings []model.Rating) ([]*model.Rating, error)
+}
+
+// lagReporter is implemented by ingesters able to report how many events are waiting to be consumed.
+type lagReporter interface {
+	Lag() (int64, error)
+}
+
+// RetryConfig defines the exponential backoff used when persisting ingested rating events fails.
+type RetryConfig struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	MaxElapsedTime  time.Duration
+}
+
+// DefaultRetryConfig is the retry policy used unless WithRetryConfig is passed to New.
+var DefaultRetryConfig = RetryConfig{
+	InitialInterval: 100 * time.Millisecond,
+	MaxInterval:     5 * time.Second,
+	MaxElapsedTime:  60 * time.Second,
+}
+
+// WithRetryConfig sets the retry policy for persisting ingested rating events.
+func WithRetryConfig(cfg RetryConfig) Option {
+	return func(c *Controller) {
+		c.retry = cfg
+	}
+}
+
+// IngestionConfig defines how ingested rating events are processed.
+// Events are distributed to Workers by record, so events of the same record are processed in order.
+// Each worker buffers up to QueueSize events, the ingester is blocked once the buffer is full.
+// Each worker also holds back up to QueueSize events exceeding a rate limit.
+type IngestionConfig struct {
+	Workers       int
+	QueueSize     int
+	BatchSize     int
+	FlushInterval time.Duration
+	// StatsInterval defines how often ingestion stats are logged, zero disables logging.
+	StatsInterval time.Duration
+	// ShutdownTimeout bounds persisting the events already consumed when ingestion stops.
+	ShutdownTimeout time.Duration
+}
+
+// DefaultIngestionConfig is the ingestion config used unless WithIngestionConfig is passed to New.
+var DefaultIngestionConfig = IngestionConfig{
+	Workers:         4,
+	QueueSize:       100,
+	BatchSize:       100,
+	FlushInterval:   200 * time.Millisecond,
+	StatsInterval:   30 * time.Second,
+	ShutdownTimeout: 10 * time.Second,
+}
+
+// WithIngestionConfig sets the ingestion worker pool config.
+func WithIngestionConfig(cfg IngestionConfig) Option {
+	return func(c *Controller) {
+		c.ingestion = cfg
+	}
+}
+
+// IngestionStats defines rating ingestion counters.
+type IngestionStats struct {
+	Processed    uint64
+	Failed       uint64
+	DeadLettered uint64
+	Quarantined  uint64
+	// Throughput defines the number of processed events per second during the last stats interval.
+	Throughput float64
+	// Lag defines the number of events waiting to be consumed, or -1 if the ingester can't report it.
+	Lag int64
+}
+
+type ingestionCounters struct {
+	processed      atomic.Uint64
+	failed         atomic.Uint64
+	deadLettered   atomic.Uint64
+	quarantined    atomic.Uint64
+	throughputBits atomic.Uint64
+}
+
+// IngestionStats returns the current rating ingestion stats.
+func (s *Controller) IngestionStats() IngestionStats {
+	stats := IngestionStats{
+		Processed:    s.stats.processed.Load(),
+		Failed:       s.stats.failed.Load(),
+		DeadLettered: s.stats.deadLettered.Load(),
+		Quarantined:  s.stats.quarantined.Load(),
+		Throughput:   math.Float64frombits(s.stats.throughputBits.Load()),
+		Lag:          -1,
+	}
+	if r, ok := s.ingester.(lagReporter); ok {
+		if lag, err := r.Lag(); err == nil {
+			stats.Lag = lag
+		}
+	}
+	return stats
+}
+
+// StartIngestion starts the ingestion of rating events.
+// Events are processed by a pool of workers, each record always being handled by the same worker.
+// Events are acknowledged to the ingester only after they are persisted or recorded in the
+// dead-letter sink, failed writes are retried with exponential backoff and a single failure
+// doesn't stop the ingestion. It returns when the context is done or the ingester is exhausted,
+// after the already consumed events are processed.
+func (s *Controller) StartIngestion(ctx context.Context) error {
+	ch, err := s.ingester.Ingest(ctx)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("Starting rating ingestion", "workers", max(s.ingestion.Workers, 1))
+	workers := max(s.ingestion.Workers, 1)
+	queues := make([]chan model.RatingEvent, workers)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan model.RatingEvent, s.ingestion.QueueSize)
+		wg.Add(1)
+		go func(queue chan model.RatingEvent) {
+			defer wg.Done()
+			s.runWorker(ctx, queue)
+		}(queues[i])
+	}
+	statsDone := make(chan struct{})
+	go s.reportStats(statsDone)
+	defer close(statsDone)
+
+	s.dispatch(ctx, ch, queues)
+	for _, q := range queues {
+		close(q)
+	}
+	wg.Wait()
+	return nil
+}
+
+// dispatch routes events to worker queues by record until the context is done or the ingester is exhausted.
+// Sending blocks while a queue is full, which stops reading from the ingester.
+func (s *Controller) dispatch(ctx context.Context, ch chan model.RatingEvent, queues []chan model.RatingEvent) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			select {
+			case queues[workerIndex(e, len(queues))] <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func workerIndex(e model.RatingEvent, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(e.RecordType))
+	h.Write([]byte{0})
+	h.Write([]byte(e.RecordID))
+	return int(h.Sum32() % uint32(workers))
+}
+
+// runWorker processes the events of a queue, batching puts. Delete events flush the pending batch first
+// to keep the order of events of a record. Puts exceeding a rate limit are held back with the later events
+// of their record until the limit allows them, while the events of other records go on. Once QueueSize events
+// are held back, the worker stops consuming its queue. Once the context is done, the events left in the queue are
+// persisted within the shutdown timeout, held back events are left uncommitted.
+func (s *Controller) runWorker(ctx context.Context, queue chan model.RatingEvent) {
+	// Persist what was already consumed even though the ingestion context is done.
+	var shutdownCtx context.Context
+	cancelShutdown := func() {}
+	defer func() { cancelShutdown() }()
+	eventCtx := func() context.Context {
+		if ctx.Err() == nil {
+			return ctx
+		}
+		if shutdownCtx == nil {
+			shutdownCtx, cancelShutdown = context.WithTimeout(context.WithoutCancel(ctx), s.ingestion.ShutdownTimeout)
+		}
+		return shutdownCtx
+	}
+	w := &worker{Controller: s, batchSize: max(s.ingestion.BatchSize, 1), held: map[recordKey]*heldEvents{}}
+	w.retryInterval = s.ingestion.FlushInterval
+	if w.retryInterval <= 0 {
+		w.retryInterval = DefaultIngestionConfig.FlushInterval
+	}
+	w.batch = make([]model.RatingEvent, 0, w.batchSize)
+	maxHeld := max(s.ingestion.QueueSize, 1)
+	ticker := time.NewTicker(w.retryInterval)
+	defer ticker.Stop()
+	var retry <-chan time.Time
+	var retryAt time.Time
+	for {
+		in, done := queue, (<-chan struct{})(nil)
+		if w.heldCount >= maxHeld && ctx.Err() == nil {
+			in, done = nil, ctx.Done()
+		}
+		select {
+		case e, ok := <-in:
+			if !ok {
+				w.flush(eventCtx())
+				return
+			}
+			w.handle(eventCtx(), e)
+		case now := <-retry:
+			retryAt = time.Time{}
+			w.release(eventCtx(), now)
+		case <-ticker.C:
+			w.flush(eventCtx())
+		case <-done:
+		}
+		if next := w.nextRetry(); !next.Equal(retryAt) {
+			retryAt, retry = next, nil
+			if !next.IsZero() {
+				retry = time.After(time.Until(next))
+			}
+		}
+	}
+}
+
+// worker defines the state of an ingestion worker.
+type worker struct {
+	*Controller
+	batch         []model.RatingEvent
+	batchSize     int
+	retryInterval time.Duration
+	held          map[recordKey]*heldEvents
+	heldCount     int
+}
+
+// heldEvents defines the events of a record held back by the rate limiter, in order.
+type heldEvents struct {
+	events  []model.RatingEvent
+	retryAt time.Time
+}
+
+// handle validates an event and processes it, unless it is held back behind earlier events of its record
+// or by the rate limiter.
+func (w *worker) handle(ctx context.Context, e model.RatingEvent) {
+	if !w.accept(ctx, e) {
+		return
+	}
+	key := recordKey{e.RecordID, e.RecordType}
+	if h, ok := w.held[key]; ok {
+		h.events = append(h.events, e)
+		w.heldCount++
+		return
+	}
+	if wait, ok := w.allow(ctx, e); !ok {
+		w.held[key] = &heldEvents{events: []model.RatingEvent{e}, retryAt: time.Now().Add(wait)}
+		w.heldCount++
+		return
+	}
+	w.process(ctx, e)
+}
+
+// release processes the held back events due for a retry, until the rate limiter holds back an event again.
+func (w *worker) release(ctx context.Context, now time.Time) {
+	for key, h := range w.held {
+		if h.retryAt.After(now) {
+			continue
+		}
+		for len(h.events) > 0 {
+			wait, ok := w.allow(ctx, h.events[0])
+			if !ok {
+				h.retryAt = now.Add(wait)
+				break
+			}
+			e := h.events[0]
+			h.events = h.events[1:]
+			w.heldCount--
+			w.process(ctx, e)
+		}
+		if len(h.events) == 0 {
+			delete(w.held, key)
+		}
+	}
+}
+
+// nextRetry returns when the held back events are retried next, or zero if no event is held back.
+func (w *worker) nextRetry() time.Time {
+	var next time.Time
+	for _, h := range w.held {
+		if next.IsZero() || h.retryAt.Before(next) {
+			next = h.retryAt
+		}
+	}
+	return next
+}
+
+// allow reports whether the rate limits of the user and provider of a put event allow it,
+// or how long to wait before retrying otherwise. Delete events aren't limited.
+func (w *worker) allow(ctx context.Context, e model.RatingEvent) (time.Duration, bool) {
+	if w.limiter == nil || e.EventType == model.RatingEventTypeDelete {
+		return 0, true
+	}
+	err := w.limiter.AllowIngested(ctx, string(e.UserID), e.ProviderID)
+	if err == nil {
+		return 0, true
+	}
+	var limited *ratelimit.LimitedError
+	if errors.As(err, &limited) {
+		return limited.RetryAfter, false
+	}
+	w.eventLogger.WarnContext(ctx, "Failed to check the rate limit of rating event, retrying", eventAttr(e), "error", err)
+	return w.retryInterval, false
+}
+
+// process screens and persists an event allowed by the rate limiter. Puts are batched.
+func (w *worker) process(ctx context.Context, e model.RatingEvent) {
+	if e.EventType == model.RatingEventTypeDelete {
+		w.flush(ctx)
+		w.processEvent(ctx, e)
+		return
+	}
+	if w.quarantineEvent(ctx, e) {
+		return
+	}
+	w.batch = append(w.batch, e)
+	if len(w.batch) >= w.batchSize {
+		w.flush(ctx)
+	}
+}
+
+// flush persists the pending batch.
+func (w *worker) flush(ctx context.Context) {
+	w.Controller.flush(ctx, w.batch)
+	w.batch = w.batch[:0]
+}
+
+// accept validates an ingested event with the validator or, without one, against the record type registry.
+// Invalid events are sent to the dead-letter sink and committed.
+func (s *Controller) accept(ctx context.Context, e model.RatingEvent) bool {
+	var err error
+	if s.validator != nil {
+		err = s.validator.Validate(&e)
+	} else if e.EventType != model.RatingEventTypeDelete {
+		err = s.recordTypes.Validate(e.RecordType, e.Value)
+	}
+	if err == nil {
+		return true
+	}
+	s.eventLogger.WarnContext(ctx, "Rejected rating event", eventAttr(e), "error", err)
+	s.stats.failed.Add(1)
+	// Invalid events never become valid, so they are committed even without a dead-letter sink.
+	if s.deadLetter != nil {
+		if err := s.writeDeadLetter(ctx, e, model.DeadLetterReasonValidate, err); err != nil {
+			s.logger.ErrorContext(ctx, "Failed to write rating event to dead-letter sink", eventAttr(e), "error", err)
+			return false
+		}
+	}
+	s.commit(ctx, e)
+	return false
+}
+
+// flush persists a batch of put events and commits them. If the batch can't be written,
+// the events are processed one by one so that only the failing ones end up in the dead-letter sink.
+func (s *Controller) flush(ctx context.Context, batch []model.RatingEvent) {
+	if len(batch) == 0 {
+		return
+	}
+	repo, ok := s.repo.(batchRepository)
+	if !ok || len(batch) == 1 {
+		for _, e := range batch {
+			s.processEvent(ctx, e)
+		}
+		return
+	}
+	ratings := make([]model.Rating, 0, len(batch))
+	headers := make([]map[string]string, 0, len(batch))
+	for _, e := range batch {
+		ratings = append(ratings, toRating(e))
+		if e.Source != nil {
+			headers = append(headers, e.Source.TraceContext)
+		}
+	}
+	batchCtx, span := tracing.StartLinked(ctx, "rating ingest batch", headers, attribute.Int("batch.size", len(batch)))
+	err := s.withRetry(batchCtx, func() error {
+		return s.putBatch(batchCtx, repo, ratings)
+	})
+	tracing.End(span, err)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.WarnContext(ctx, "Failed to persist a batch of rating events, processing them one by one", "events", len(batch), "error", err)
+		for _, e := range batch {
+			s.processEvent(ctx, e)
+		}
+		return
+	}
+	s.stats.processed.Add(uint64(len(batch)))
+	for _, e := range batch {
+		s.commit(ctx, e)
+	}
+}
+
+// putBatch persists a batch of ratings and applies them to the leaderboards.
+func (s *Controller) putBatch(ctx context.Context, repo batchRepository, ratings []model.Rating) error {
+	s.leaderboards.writes.RLock()
+	defer s.leaderboards.writes.RUnlock()
+	old, err := repo.PutBatch(ctx, ratings)
+	if err != nil {
+		return err
+	}
+	for i := range ratings {
+		s.leaderboardPut(model.RecordID(ratings[i].RecordID), model.RecordType(ratings[i].RecordType), ratings[i], old[i])
+	}
+	return nil
+}
+
+// quarantineEvent screens a put event with the anomaly detector. Suspicious events are quarantined and
+// committed, it returns false if the event is not suspicious and still has to be processed.
+func (s *Controller) quarantineEvent(ctx context.Context, e model.RatingEvent) bool {
+	rating := toRating(e)
+	reasons := s.check(e.RecordID, e.RecordType, &rating)
+	if len(reasons) == 0 {
+		return false
+	}
+	err := s.withRetry(ctx, func() error {
+		return s.quarantine(ctx, e.RecordID, e.RecordType, &rating, reasons)
+	})
+	if err == nil {
+		s.stats.quarantined.Add(1)
+	}
+	s.finish(ctx, e, err)
+	return true
+}
+
+// processEvent persists a single validated and screened event and commits it once it is handled.
+// The handling continues the trace of the message the event was decoded from.
+func (s *Controller) processEvent(ctx context.Context, e model.RatingEvent) {
+	if e.Source != nil {
+		ctx = tracing.Extract(ctx, e.Source.TraceContext)
+	}
+	ctx, span := tracing.Start(ctx, "rating ingest event", attribute.String("rating.event_type", string(e.EventType)))
+	err := s.withRetry(ctx, func() error {
+		if e.EventType == model.RatingEventTypeDelete {
+			return s.DeleteRating(ctx, e.RecordID, e.RecordType, e.UserID)
+		}
+		rating := toRating(e)
+		return s.putRating(ctx, e.RecordID, e.RecordType, &rating)
+	})
+	if err == nil {
+		s.stats.processed.Add(1)
+	}
+	s.finish(ctx, e, err)
+	tracing.End(span, err)
+}
+
+// finish commits a handled event. Events which failed with err are sent to the dead-letter sink first,
+// and are left uncommitted if that fails too or the context is done.
+func (s *Controller) finish(ctx context.Context, e model.RatingEvent, err error) {
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		s.eventLogger.ErrorContext(ctx, "Failed to persist rating event", eventAttr(e), "error", err)
+		s.stats.failed.Add(1)
+		if s.deadLetter == nil {
+			return
+		}
+		if err := s.writeDeadLetter(ctx, e, model.DeadLetterReasonPersist, err); err != nil {
+			s.logger.ErrorContext(ctx, "Failed to write rating event to dead-letter sink", eventAttr(e), "error", err)
+			return
+		}
+	}
+	s.commit(ctx, e)
+}
+
+// eventAttr returns the attributes identifying a rating event in log records.
+func eventAttr(e model.RatingEvent) slog.Attr {
+	attrs := []any{"type", e.EventType, "record_id", e.RecordID, "record_type", e.RecordType, "user_id", e.UserID}
+	if e.Source != nil {
+		attrs = append(attrs, "topic", e.Source.Topic, "partition", e.Source.Partition, "offset", e.Source.Offset)
+	}
+	return slog.Group("event", attrs...)
+}
+
+func toRating(e model.RatingEvent) model.Rating {
+	rating := model.Rating{
+		RecordID:   string(e.RecordID),
+		RecordType: string(e.RecordType),
+		UserID:     e.UserID,
+		Value:      e.Value,
+		ProviderID: e.ProviderID,
+		CreatedAt:  e.Timestamp,
+		UpdatedAt:  e.Timestamp,
+	}
+	stamp(&rating, time.Now())
+	return rating
+}
+
+func (s *Controller) commit(ctx context.Context, e model.RatingEvent) {
+	if err := s.ingester.Commit(ctx, e); err != nil {
+		s.eventLogger.ErrorContext(ctx, "Failed to commit rating event", eventAttr(e), "error", err)
+	}
+}
+
+func (s *Controller) writeDeadLetter(ctx context.Context, e model.RatingEvent, reason model.DeadLetterReason, cause error) error {
+	entry := model.DeadLetterEntry{
+		Event:  &e,
+		Reason: reason,
+		Error:  cause.Error(),
+		Source: e.Source,
+		Time:   time.Now(),
+	}
+	if e.Source != nil {
+		entry.Payload = e.Source.Payload
+		entry.ContentType = e.Source.ContentType
+	}
+	if err := s.deadLetter.Write(ctx, entry); err != nil {
+		return err
+	}
+	s.stats.deadLettered.Add(1)
+	return nil
+}
+
+// withRetry runs a repository operation with exponential backoff until it succeeds,
+// the retry budget is exhausted or the context is done.
+func (s *Controller) withRetry(ctx context.Context, op func() error) error {
+	expBackoff := backoff.NewExponentialBackOff()
+	expBackoff.InitialInterval = s.retry.InitialInterval
+	expBackoff.MaxInterval = s.retry.MaxInterval
+	expBackoff.MaxElapsedTime = s.retry.MaxElapsedTime
+	expBackoff.RandomizationFactor = 0.5
+
+	operation := func() error {
+		err := op()
+		if err != nil && ctx.Err() != nil {
+			return backoff.Permanent(err)
+		}
+		return err
+	}
+	notify := func(err error, next time.Duration) {
+		s.eventLogger.WarnContext(ctx, "Failed to persist rating events, retrying", "retry_in", next, "error", err)
+	}
+	return backoff.RetryNotify(operation, backoff.WithContext(expBackoff, ctx), notify)
+}
+
+// reportStats periodically updates the throughput and logs the ingestion stats until done is closed.
+func (s *Controller) reportStats(done chan struct{}) {
+	if s.ingestion.StatsInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.ingestion.StatsInterval)
+	defer ticker.Stop()
+	last, lastTime := s.stats.processed.Load(), time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			processed := s.stats.processed.Load()
+			throughput := float64(processed-last) / now.Sub(lastTime).Seconds()
+			s.stats.throughputBits.Store(math.Float64bits(throughput))
+			last, lastTime = processed, now
+			stats := s.IngestionStats()
+			s.logger.Info("Ingestion stats", "processed", stats.Processed, "throughput", math.Round(stats.Throughput*10)/10,
+				"failed", stats.Failed, "dead_lettered", stats.DeadLettered, "quarantined", stats.Quarantined, "lag", stats.Lag)
+		}
+	}
+}
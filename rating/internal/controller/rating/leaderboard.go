@@ -0,0 +1,153 @@
+package rating
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ugurcancaykara/odd-service/rating/internal/leaderboard"
+	"github.com/ugurcancaykara/odd-service/rating/internal/repository"
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// WithLeaderboardConfig sets the config of the top rated and trending leaderboards.
+func WithLeaderboardConfig(cfg leaderboard.Config) Option {
+	return func(c *Controller) {
+		c.leaderboardConfig = cfg
+	}
+}
+
+// leaderboards holds the leaderboard fed by rating writes. While it is rebuilt from the repository, the records
+// written to are collected in touched and read again from the repository before the new leaderboard replaces
+// the current one, as the rebuild may or may not have seen those writes.
+type leaderboards struct {
+	mu         sync.Mutex
+	current    *leaderboard.Leaderboard
+	rebuilding bool
+	touched    map[recordKey]struct{}
+	// writes is held shared by rating writes from the repository write until the leaderboard is updated
+	// and exclusively by a rebuild while it re-reads the touched records and replaces the leaderboard.
+	writes sync.RWMutex
+	// rebuildMu serializes rebuilds.
+	rebuildMu sync.Mutex
+}
+
+type recordKey struct {
+	id  model.RecordID
+	typ model.RecordType
+}
+
+func (l *leaderboards) get() *leaderboard.Leaderboard {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current
+}
+
+func (l *leaderboards) update(key recordKey, op func(*leaderboard.Leaderboard)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	op(l.current)
+	if l.rebuilding {
+		l.touched[key] = struct{}{}
+	}
+}
+
+// SetAggregationStrategy replaces the aggregation strategy of top rated leaderboards requested without one.
+// It returns leaderboard.ErrUnknownStrategy for unsupported strategies.
+func (c *Controller) SetAggregationStrategy(strategy model.AggregationStrategy) error {
+	if strategy == "" {
+		strategy = leaderboard.DefaultConfig.Strategy
+	}
+	if !leaderboard.Supported(strategy) {
+		return leaderboard.ErrUnknownStrategy
+	}
+	c.strategy.Store(&strategy)
+	return nil
+}
+
+// ListTopRated returns up to limit records of a type with at least minVotes ratings ordered by their aggregated rating.
+// An empty strategy selects the configured one.
+func (c *Controller) ListTopRated(_ context.Context, recordType model.RecordType, strategy model.AggregationStrategy, minVotes int, limit int) ([]model.RankedRecord, error) {
+	if strategy == "" {
+		if s := c.strategy.Load(); s != nil {
+			strategy = *s
+		}
+	}
+	return c.leaderboards.get().TopRated(recordType, strategy, minVotes, limit)
+}
+
+// ListTrending returns up to limit records of a type with the most ratings per hour within the window ending now.
+func (c *Controller) ListTrending(_ context.Context, recordType model.RecordType, window time.Duration, limit int) ([]model.RankedRecord, error) {
+	return c.leaderboards.get().Trending(recordType, window, limit), nil
+}
+
+// RebuildLeaderboard rebuilds the leaderboards from all ratings in the repository. The leaderboards are rebuilt
+// at startup and periodically to pick up ratings written by other instances of the service.
+// The users of the ratings are also made known to the anomaly detector.
+func (c *Controller) RebuildLeaderboard(ctx context.Context) error {
+	c.leaderboards.rebuildMu.Lock()
+	defer c.leaderboards.rebuildMu.Unlock()
+
+	c.leaderboards.mu.Lock()
+	c.leaderboards.rebuilding = true
+	c.leaderboards.touched = map[recordKey]struct{}{}
+	c.leaderboards.mu.Unlock()
+	defer func() {
+		c.leaderboards.mu.Lock()
+		c.leaderboards.rebuilding = false
+		c.leaderboards.touched = nil
+		c.leaderboards.mu.Unlock()
+	}()
+
+	board := c.newLeaderboard()
+	err := c.repo.ForEach(ctx, func(rating model.Rating) error {
+		board.Put(model.RecordID(rating.RecordID), model.RecordType(rating.RecordType), &rating, nil)
+		if c.detector != nil {
+			c.detector.Observe(&rating)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Writes wait until the records written to during the rebuild are read again and the leaderboard is replaced,
+	// so they are applied either to the current leaderboard before or to the new one after.
+	c.leaderboards.writes.Lock()
+	defer c.leaderboards.writes.Unlock()
+	c.leaderboards.mu.Lock()
+	touched := c.leaderboards.touched
+	c.leaderboards.mu.Unlock()
+	for key := range touched {
+		ratings, err := c.repo.Get(ctx, key.id, key.typ)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		board.Reset(key.id, key.typ, ratings)
+	}
+	c.leaderboards.mu.Lock()
+	c.leaderboards.current = board
+	c.leaderboards.mu.Unlock()
+	return nil
+}
+
+func (c *Controller) newLeaderboard() *leaderboard.Leaderboard {
+	return leaderboard.New(c.leaderboardConfig, c.weights.Weight, c.recordTypes.Scale)
+}
+
+// leaderboardPut applies a rating written to the repository to the leaderboards, old being the rating it replaced.
+// The caller holds c.leaderboards.writes since the repository write.
+func (c *Controller) leaderboardPut(recordID model.RecordID, recordType model.RecordType, rating model.Rating, old *model.Rating) {
+	c.leaderboards.update(recordKey{recordID, recordType}, func(l *leaderboard.Leaderboard) {
+		l.Put(recordID, recordType, &rating, old)
+	})
+}
+
+// leaderboardDelete applies a rating deleted from the repository to the leaderboards.
+// The caller holds c.leaderboards.writes since the repository write.
+func (c *Controller) leaderboardDelete(recordID model.RecordID, recordType model.RecordType, old model.Rating) {
+	c.leaderboards.update(recordKey{recordID, recordType}, func(l *leaderboard.Leaderboard) {
+		l.Delete(recordID, recordType, &old)
+	})
+}
@@ -0,0 +1,125 @@
+package rating
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ugurcancaykara/odd-service/rating/internal/anomaly"
+	"github.com/ugurcancaykara/odd-service/rating/internal/repository"
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// ErrModerationUnsupported is returned when the repository can't store quarantined ratings.
+var ErrModerationUnsupported = errors.New("rating moderation is not supported by the repository")
+
+// ErrNotQuarantined is returned when a rating to moderate is not quarantined.
+var ErrNotQuarantined = errors.New("rating not quarantined")
+
+// ErrInvalidDecision is returned when a moderation decision is not supported.
+var ErrInvalidDecision = errors.New("invalid moderation decision")
+
+// quarantineRepository is implemented by repositories able to hold suspicious ratings until they are moderated.
+type quarantineRepository interface {
+	PutQuarantined(ctx context.Context, q *model.QuarantinedRating) error
+	GetQuarantined(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) (*model.QuarantinedRating, error)
+	ListQuarantined(ctx context.Context, pageToken string, pageSize int) ([]model.QuarantinedRating, string, error)
+	DeleteQuarantined(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) error
+}
+
+// WithAnomalyDetector sets the detector screening incoming ratings. Suspicious ratings are quarantined
+// instead of stored, which requires a repository able to hold them.
+func WithAnomalyDetector(d *anomaly.Detector) Option {
+	return func(c *Controller) {
+		c.detector = d
+	}
+}
+
+// check returns the reasons a rating is suspicious, if any. Ratings which are not suspicious are
+// observed by the detector as stored ratings of their user.
+func (c *Controller) check(recordID model.RecordID, recordType model.RecordType, rating *model.Rating) []model.AnomalyReason {
+	if c.detector == nil {
+		return nil
+	}
+	if _, ok := c.repo.(quarantineRepository); !ok {
+		return nil
+	}
+	mean, votes := c.leaderboards.get().Mean(recordID, recordType)
+	// Records of types without a scale aren't checked for outliers.
+	scale, _ := c.recordTypes.Scale(recordType)
+	reasons := c.detector.Check(recordID, recordType, rating, anomaly.Baseline{Mean: mean, Votes: votes, Scale: scale})
+	if len(reasons) == 0 {
+		c.detector.Observe(rating)
+	}
+	return reasons
+}
+
+// quarantine holds back a suspicious rating until it is moderated.
+func (c *Controller) quarantine(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating, reasons []model.AnomalyReason) error {
+	q := model.QuarantinedRating{Rating: *rating, Reasons: reasons, QuarantinedAt: time.Now()}
+	q.Rating.RecordID = string(recordID)
+	q.Rating.RecordType = string(recordType)
+	if err := c.repo.(quarantineRepository).PutQuarantined(ctx, &q); err != nil {
+		return err
+	}
+	c.eventLogger.InfoContext(ctx, "Quarantined rating",
+		"record_id", recordID, "record_type", recordType, "user_id", rating.UserID, "reasons", reasons)
+	return nil
+}
+
+// ListQuarantinedRatings returns a page of the quarantined ratings ordered by record type, record id and user id,
+// and the token of the next page.
+func (c *Controller) ListQuarantinedRatings(ctx context.Context, pageToken string, pageSize int) ([]model.QuarantinedRating, string, error) {
+	repo, ok := c.repo.(quarantineRepository)
+	if !ok {
+		return nil, "", ErrModerationUnsupported
+	}
+	ratings, next, err := repo.ListQuarantined(ctx, pageToken, normalizePageSize(pageSize))
+	if err != nil && errors.Is(err, repository.ErrInvalidPageToken) {
+		return nil, "", ErrInvalidPageToken
+	}
+	return ratings, next, err
+}
+
+// ModerateRating approves or rejects the quarantined rating of a user for a record. An approved rating is stored
+// unless the user has rated the record again since.
+func (c *Controller) ModerateRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID, decision model.ModerationDecision) error {
+	repo, ok := c.repo.(quarantineRepository)
+	if !ok {
+		return ErrModerationUnsupported
+	}
+	if decision != model.ModerationDecisionApprove && decision != model.ModerationDecisionReject {
+		return ErrInvalidDecision
+	}
+	q, err := repo.GetQuarantined(ctx, recordID, recordType, userID)
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrNotQuarantined
+	} else if err != nil {
+		return err
+	}
+	if decision == model.ModerationDecisionApprove {
+		if err := c.approve(ctx, recordID, recordType, &q.Rating); err != nil {
+			return err
+		}
+	}
+	c.logger.InfoContext(ctx, "Moderated rating",
+		"record_id", recordID, "record_type", recordType, "user_id", userID, "decision", decision)
+	return repo.DeleteQuarantined(ctx, recordID, recordType, userID)
+}
+
+func (c *Controller) approve(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
+	current, err := c.repo.GetUserRating(ctx, recordID, recordType, rating.UserID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	if err == nil && current.UpdatedAt.After(rating.UpdatedAt) {
+		return nil
+	}
+	if err := c.putRating(ctx, recordID, recordType, rating); err != nil {
+		return err
+	}
+	if c.detector != nil {
+		c.detector.Observe(rating)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// Sink defines a dead-letter sink appending entries to a JSON lines file.
+type Sink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewSink creates a new file dead-letter sink, creating the file if it doesn't exist.
+func NewSink(path string) (*Sink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{f: f}, nil
+}
+
+// Write appends an entry to the dead-letter file.
+func (s *Sink) Write(_ context.Context, entry model.DeadLetterEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+// Close closes the dead-letter file.
+func (s *Sink) Close() error {
+	return s.f.Close()
+}
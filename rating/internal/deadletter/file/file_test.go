@@ -0,0 +1,54 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+func TestSink_Write(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratings-dlq.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"reason":"decode","error":"earlier entry"}`+"\n"), 0o644))
+	at := time.Date(2024, time.January, 8, 12, 0, 0, 0, time.UTC)
+	entries := []model.DeadLetterEntry{
+		{Payload: []byte("not json"), ContentType: "application/json", Reason: model.DeadLetterReasonDecode, Error: "invalid character", Time: at},
+		{
+			Payload: []byte(`{"userId":"105"}`),
+			Event:   &model.RatingEvent{UserID: "105", RecordID: "1", RecordType: model.RecordTypeMovie, Value: 5},
+			Reason:  model.DeadLetterReasonPersist,
+			Error:   "connection refused",
+			Source:  &model.EventSource{Topic: "ratings", Partition: 1, Offset: 42},
+			Time:    at,
+		},
+	}
+
+	sink, err := NewSink(path)
+	require.NoError(t, err)
+	for _, e := range entries {
+		require.NoError(t, sink.Write(context.Background(), e))
+	}
+	require.NoError(t, sink.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var got []model.DeadLetterEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e model.DeadLetterEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		got = append(got, e)
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, got, 3, "entries are appended to the file")
+	assert.Equal(t, "earlier entry", got[0].Error)
+	assert.Equal(t, entries, got[1:])
+}
@@ -0,0 +1,75 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// Sink defines a dead-letter sink producing entries to a Kafka topic.
+type Sink struct {
+	producer *kafka.Producer
+	topic    string
+}
+
+// NewSink creates a new Kafka dead-letter sink.
+func NewSink(addr string, topic string) (*Sink, error) {
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": addr})
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{producer, topic}, nil
+}
+
+// Write produces an entry to the dead-letter topic and waits until it is delivered.
+func (s *Sink) Write(ctx context.Context, entry model.DeadLetterEntry) error {
+	msg, err := message(s.topic, entry)
+	if err != nil {
+		return err
+	}
+	delivery := make(chan kafka.Event, 1)
+	if err := s.producer.Produce(msg, delivery); err != nil {
+		return err
+	}
+	select {
+	case e := <-delivery:
+		m, ok := e.(*kafka.Message)
+		if !ok {
+			return fmt.Errorf("unexpected delivery event: %v", e)
+		}
+		return m.TopicPartition.Error
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// message encodes an entry as a message of the dead-letter topic, keyed by the record of its event
+// so that the entries of a record stay in order.
+func message(topic string, entry model.DeadLetterEntry) (*kafka.Message, error) {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Value:          b,
+		Headers: []kafka.Header{
+			{Key: "reason", Value: []byte(entry.Reason)},
+			{Key: "error", Value: []byte(entry.Error)},
+		},
+	}
+	if entry.Event != nil {
+		msg.Key = []byte(entry.Event.RecordID)
+	}
+	return msg, nil
+}
+
+// Close flushes pending entries and closes the producer.
+func (s *Sink) Close() error {
+	s.producer.Flush(10 * 1000)
+	s.producer.Close()
+	return nil
+}
@@ -0,0 +1,49 @@
+package kafka
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+func TestMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   model.DeadLetterEntry
+		wantKey []byte
+	}{
+		{
+			name:  "undecodable payload",
+			entry: model.DeadLetterEntry{Payload: []byte("not json"), Reason: model.DeadLetterReasonDecode, Error: "invalid character"},
+		},
+		{
+			name: "decoded event",
+			entry: model.DeadLetterEntry{
+				Payload: []byte(`{"recordId":"7"}`),
+				Event:   &model.RatingEvent{UserID: "105", RecordID: "7", RecordType: model.RecordTypeMovie},
+				Reason:  model.DeadLetterReasonValidate,
+				Error:   "empty value",
+			},
+			wantKey: []byte("7"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := message("ratings-dlq", tt.entry)
+			require.NoError(t, err)
+			assert.Equal(t, "ratings-dlq", *msg.TopicPartition.Topic)
+			assert.Equal(t, tt.wantKey, msg.Key)
+			assert.Equal(t, []kafka.Header{
+				{Key: "reason", Value: []byte(tt.entry.Reason)},
+				{Key: "error", Value: []byte(tt.entry.Error)},
+			}, msg.Headers)
+			var got model.DeadLetterEntry
+			require.NoError(t, json.Unmarshal(msg.Value, &got))
+			assert.Equal(t, tt.entry, got)
+		})
+	}
+}
@@ -3,11 +3,18 @@ package grpc
 import (
 	"context"
 	"errors"
+	"log/slog"
+	"math"
+	"strconv"
 
 	"github.com/ugurcancaykara/odd-service/gen"
 	"github.com/ugurcancaykara/odd-service/rating/internal/controller/rating"
+	"github.com/ugurcancaykara/odd-service/rating/internal/leaderboard"
+	"github.com/ugurcancaykara/odd-service/rating/internal/ratelimit"
 	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -27,13 +34,19 @@ func (h *Handler) GetAggregatedRating(ctx context.Context, req *gen.GetAggregate
 	if req == nil || req.RecordId == "" || req.RecordType == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
 	}
-	v, err := h.ctrl.GetAggregatedRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType))
+	window := model.TimeWindowFromProto(req.From, req.To)
+	v, err := h.ctrl.GetAggregatedRatingInWindow(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), window)
 	if err != nil && errors.Is(err, rating.ErrNotFound) {
 		return nil, status.Errorf(codes.NotFound, err.Error())
 	} else if err != nil {
 		return nil, status.Errorf(codes.Internal, err.Error())
 	}
-	return &gen.GetAggregatedRatingResponse{RatingValue: v}, nil
+	res := &gen.GetAggregatedRatingResponse{RatingValue: v}
+	// Ratings of record types which are no longer registered are not normalized.
+	if scale, err := h.ctrl.GetScale(model.RecordType(req.RecordType)); err == nil {
+		res.NormalizedValue = scale.Normalize(v)
+	}
+	return res, nil
 }
 
 // PutRating writes a rating for a given record.
@@ -41,8 +54,234 @@ func (h *Handler) PutRating(ctx context.Context, req *gen.PutRatingRequest) (*ge
 	if req == nil || req.RecordId == "" || req.UserId == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id or record id")
 	}
-	if err := h.ctrl.PutRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), &model.Rating{UserID: model.UserID(req.UserId), Value: model.RatingValue(req.RatingValue)}); err != nil {
-		return nil, err
+	// An unset value is 0 and rejected by the scales not including it.
+	if err := h.ctrl.PutRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), &model.Rating{UserID: model.UserID(req.UserId), Value: model.RatingValue(req.GetRatingValue()), ProviderID: req.ProviderId}); err != nil {
+		var limited *ratelimit.LimitedError
+		if errors.As(err, &limited) {
+			// The retry-after header carries the number of seconds until the rating can be retried.
+			retryAfter := strconv.Itoa(int(math.Ceil(limited.RetryAfter.Seconds())))
+			if err := grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter)); err != nil {
+				slog.WarnContext(ctx, "Failed to set retry-after header", "error", err)
+			}
+			return nil, status.Errorf(codes.ResourceExhausted, err.Error())
+		}
+		if errors.Is(err, rating.ErrInvalidRating) {
+			return nil, status.Errorf(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, err.Error())
 	}
 	return &gen.PutRatingResponse{}, nil
 }
+
+// GetRatingStats returns rating stats for a record with a breakdown per provider.
+func (h *Handler) GetRatingStats(ctx context.Context, req *gen.GetRatingStatsRequest) (*gen.GetRatingStatsResponse, error) {
+	if req == nil || req.RecordId == "" || req.RecordType == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+	stats, err := h.ctrl.GetRatingStats(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType))
+	if err != nil && errors.Is(err, rating.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, err.Error())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return model.RatingStatsToProto(stats), nil
+}
+
+// GetRatingTimeSeries returns per day or week rating counts and averages for a record.
+func (h *Handler) GetRatingTimeSeries(ctx context.Context, req *gen.GetRatingTimeSeriesRequest) (*gen.GetRatingTimeSeriesResponse, error) {
+	if req == nil || req.RecordId == "" || req.RecordType == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+	interval := model.TimeSeriesInterval(req.Interval)
+	if interval == "" {
+		interval = model.TimeSeriesIntervalDay
+	}
+	window := model.TimeWindowFromProto(req.From, req.To)
+	points, err := h.ctrl.GetRatingTimeSeries(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), interval, window)
+	if err != nil && errors.Is(err, rating.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, err.Error())
+	} else if err != nil && errors.Is(err, rating.ErrInvalidInterval) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return model.RatingTimeSeriesToProto(points), nil
+}
+
+// ListProviderRatings returns a page of the ratings received from a provider.
+func (h *Handler) ListProviderRatings(ctx context.Context, req *gen.ListProviderRatingsRequest) (*gen.ListProviderRatingsResponse, error) {
+	if req == nil || req.ProviderId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty provider id")
+	}
+	ratings, next, err := h.ctrl.ListProviderRatings(ctx, req.ProviderId, req.PageToken, int(req.PageSize))
+	if err != nil && errors.Is(err, rating.ErrInvalidPageToken) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	res := &gen.ListProviderRatingsResponse{NextPageToken: next}
+	for i := range ratings {
+		res.Ratings = append(res.Ratings, model.RatingToProto(&ratings[i]))
+	}
+	return res, nil
+}
+
+// PurgeProviderRatings deletes all ratings received from a provider.
+func (h *Handler) PurgeProviderRatings(ctx context.Context, req *gen.PurgeProviderRatingsRequest) (*gen.PurgeProviderRatingsResponse, error) {
+	if req == nil || req.ProviderId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty provider id")
+	}
+	deleted, err := h.ctrl.PurgeProviderRatings(ctx, req.ProviderId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return &gen.PurgeProviderRatingsResponse{DeletedCount: deleted}, nil
+}
+
+// ListTopRated returns the best rated records of a type.
+func (h *Handler) ListTopRated(ctx context.Context, req *gen.ListTopRatedRequest) (*gen.ListTopRatedResponse, error) {
+	if req == nil || req.RecordType == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty record type")
+	}
+	records, err := h.ctrl.ListTopRated(ctx, model.RecordType(req.RecordType), model.AggregationStrategy(req.Strategy), int(req.MinVotes), int(req.Limit))
+	if err != nil && errors.Is(err, leaderboard.ErrUnknownStrategy) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	res := &gen.ListTopRatedResponse{}
+	for i := range records {
+		res.Records = append(res.Records, model.RankedRecordToProto(&records[i]))
+	}
+	return res, nil
+}
+
+// ListTrending returns the records of a type rated most often within a window.
+func (h *Handler) ListTrending(ctx context.Context, req *gen.ListTrendingRequest) (*gen.ListTrendingResponse, error) {
+	if req == nil || req.RecordType == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty record type")
+	}
+	records, err := h.ctrl.ListTrending(ctx, model.RecordType(req.RecordType), req.Window.AsDuration(), int(req.Limit))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	res := &gen.ListTrendingResponse{}
+	for i := range records {
+		res.Records = append(res.Records, model.RankedRecordToProto(&records[i]))
+	}
+	return res, nil
+}
+
+// GetUserRating returns the rating of a user for a record.
+func (h *Handler) GetUserRating(ctx context.Context, req *gen.GetUserRatingRequest) (*gen.GetUserRatingResponse, error) {
+	if req == nil || req.UserId == "" || req.RecordId == "" || req.RecordType == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id or record id")
+	}
+	r, err := h.ctrl.GetUserRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), model.UserID(req.UserId))
+	if err != nil && errors.Is(err, rating.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, err.Error())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return &gen.GetUserRatingResponse{Rating: model.RatingToProto(r)}, nil
+}
+
+// ListUserRatings returns a page of the ratings of a user.
+func (h *Handler) ListUserRatings(ctx context.Context, req *gen.ListUserRatingsRequest) (*gen.ListUserRatingsResponse, error) {
+	if req == nil || req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id")
+	}
+	ratings, next, err := h.ctrl.ListUserRatings(ctx, model.UserID(req.UserId), req.PageToken, int(req.PageSize))
+	if err != nil && errors.Is(err, rating.ErrInvalidPageToken) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	res := &gen.ListUserRatingsResponse{NextPageToken: next}
+	for i := range ratings {
+		res.Ratings = append(res.Ratings, model.RatingToProto(&ratings[i]))
+	}
+	return res, nil
+}
+
+// ListQuarantinedRatings returns a page of the ratings quarantined by anomaly detection.
+func (h *Handler) ListQuarantinedRatings(ctx context.Context, req *gen.ListQuarantinedRatingsRequest) (*gen.ListQuarantinedRatingsResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	ratings, next, err := h.ctrl.ListQuarantinedRatings(ctx, req.PageToken, int(req.PageSize))
+	if err != nil && errors.Is(err, rating.ErrInvalidPageToken) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	} else if err != nil && errors.Is(err, rating.ErrModerationUnsupported) {
+		return nil, status.Errorf(codes.Unimplemented, err.Error())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	res := &gen.ListQuarantinedRatingsResponse{NextPageToken: next}
+	for i := range ratings {
+		res.Ratings = append(res.Ratings, model.QuarantinedRatingToProto(&ratings[i]))
+	}
+	return res, nil
+}
+
+// ModerateRating approves or rejects a quarantined rating.
+func (h *Handler) ModerateRating(ctx context.Context, req *gen.ModerateRatingRequest) (*gen.ModerateRatingResponse, error) {
+	if req == nil || req.UserId == "" || req.RecordId == "" || req.RecordType == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id or record id")
+	}
+	err := h.ctrl.ModerateRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), model.UserID(req.UserId), model.ModerationDecision(req.Decision))
+	if err != nil && errors.Is(err, rating.ErrNotQuarantined) {
+		return nil, status.Errorf(codes.NotFound, err.Error())
+	} else if err != nil && errors.Is(err, rating.ErrInvalidDecision) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	} else if err != nil && errors.Is(err, rating.ErrModerationUnsupported) {
+		return nil, status.Errorf(codes.Unimplemented, err.Error())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return &gen.ModerateRatingResponse{}, nil
+}
+
+// ListRecordTypes returns the rateable record types with their rating scales.
+func (h *Handler) ListRecordTypes(ctx context.Context, req *gen.ListRecordTypesRequest) (*gen.ListRecordTypesResponse, error) {
+	res := &gen.ListRecordTypesResponse{}
+	for _, t := range h.ctrl.ListRecordTypes() {
+		res.RecordTypes = append(res.RecordTypes, &gen.RecordTypeScale{RecordType: string(t.RecordType), Scale: model.RatingScaleToProto(t.Scale)})
+	}
+	return res, nil
+}
+
+// exportBatchSize defines how many ratings are sent per ExportRatings response.
+const exportBatchSize = 500
+
+// ExportRatings streams the ratings matching a filter in batches.
+func (h *Handler) ExportRatings(req *gen.ExportRatingsRequest, stream gen.RatingService_ExportRatingsServer) error {
+	if req == nil {
+		return status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	filter := model.ExportFilter{
+		RecordType: model.RecordType(req.RecordType),
+		ProviderID: req.ProviderId,
+		Window:     model.TimeWindowFromProto(req.From, req.To),
+	}
+	batch := &gen.ExportRatingsResponse{}
+	err := h.ctrl.ExportRatings(stream.Context(), filter, func(rating model.Rating) error {
+		batch.Ratings = append(batch.Ratings, model.RatingToProto(&rating))
+		if len(batch.Ratings) < exportBatchSize {
+			return nil
+		}
+		err := stream.Send(batch)
+		batch = &gen.ExportRatingsResponse{}
+		return err
+	})
+	if s, ok := status.FromError(err); ok && err != nil {
+		// Sending failed, the stream is broken.
+		return s.Err()
+	} else if err != nil {
+		return status.Errorf(codes.Internal, err.Error())
+	}
+	if len(batch.Ratings) > 0 {
+		return stream.Send(batch)
+	}
+	return nil
+}
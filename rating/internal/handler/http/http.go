@@ -3,11 +3,13 @@ package http
 import (
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
 
 	"github.com/ugurcancaykara/odd-service/rating/internal/controller/rating"
+	"github.com/ugurcancaykara/odd-service/rating/internal/ratelimit"
 	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
 )
 
@@ -44,17 +46,27 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if err := json.NewEncoder(w).Encode(v); err != nil {
-			log.Printf("Response encode error: %v\n", err)
+			slog.ErrorContext(r.Context(), "Failed to encode response", "error", err)
 		}
 	case http.MethodPut:
 		userID := model.UserID(r.FormValue("userId"))
-		v, err := strconv.ParseFloat("value", 64)
+		v, err := strconv.Atoi(r.FormValue("value"))
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
-		if err := h.ctrl.PutRating(r.Context(), recordID, recordType, &model.Rating{UserID: userID, Value: model.RatingValue(v)}); err != nil {
-			log.Printf("Repository put error: %v \n", err)
+		if err := h.ctrl.PutRating(r.Context(), recordID, recordType, &model.Rating{UserID: userID, Value: model.RatingValue(v), ProviderID: r.FormValue("providerId")}); err != nil {
+			var limited *ratelimit.LimitedError
+			if errors.As(err, &limited) {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limited.RetryAfter.Seconds()))))
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			if errors.Is(err, rating.ErrInvalidRating) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			slog.ErrorContext(r.Context(), "Failed to put rating", "error", err)
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 	default:
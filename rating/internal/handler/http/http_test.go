@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/rating/internal/controller/rating"
+	"github.com/ugurcancaykara/odd-service/rating/internal/ratelimit"
+	memoryrepo "github.com/ugurcancaykara/odd-service/rating/internal/repository/memory"
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+func TestHandler_Put(t *testing.T) {
+	repo := memoryrepo.New()
+	limiter := ratelimit.New(ratelimit.Config{User: ratelimit.Limit{Rate: 1, Burst: 1}}, ratelimit.NewMemoryStore())
+	h := New(rating.New(repo, nil, rating.WithRateLimiter(limiter)))
+
+	tests := []struct {
+		name           string
+		form           url.Values
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{
+			name:       "put",
+			form:       url.Values{"id": {"1"}, "type": {"movie"}, "userId": {"user1"}, "value": {"4"}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:           "rate limited user",
+			form:           url.Values{"id": {"2"}, "type": {"movie"}, "userId": {"user1"}, "value": {"5"}},
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "1",
+		},
+		{
+			name:       "invalid value",
+			form:       url.Values{"id": {"1"}, "type": {"movie"}, "userId": {"user2"}, "value": {"four"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "value out of scale",
+			form:       url.Values{"id": {"1"}, "type": {"movie"}, "userId": {"user3"}, "value": {"7"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing record id",
+			form:       url.Values{"type": {"movie"}, "userId": {"user2"}, "value": {"4"}},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.Handle(w, httptest.NewRequest(http.MethodPut, "/rating?"+tt.form.Encode(), nil))
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantRetryAfter, w.Header().Get("Retry-After"))
+		})
+	}
+
+	ratings, err := repo.Get(context.Background(), "1", "movie")
+	require.NoError(t, err)
+	require.Len(t, ratings, 1)
+	assert.Equal(t, model.RatingValue(4), ratings[0].Value)
+}
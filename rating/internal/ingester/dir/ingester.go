@@ -0,0 +1,139 @@
+package dir
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
+	"github.com/ugurcancaykara/odd-service/rating/internal/ingester/file"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// Ingester defines a directory-watch rating event ingester.
+// It periodically scans a directory and ingests every new file matching the pattern.
+// Files are expected to be in one of the formats supported by the file ingester.
+type Ingester struct {
+	path         string
+	pattern      string
+	archiveDir   string
+	failedDir    string
+	pollInterval time.Duration
+	logger       *slog.Logger
+}
+
+// NewIngester creates a new directory-watch ingester. Processed files are moved
+// to archiveDir if it is set, otherwise they are remembered and skipped until restart.
+// Files which fail to be ingested are moved to failedDir if it is set, otherwise they are
+// left in place and ingested again by the next scan. A nil logger logs to slog.Default().
+func NewIngester(path string, pattern string, archiveDir string, failedDir string, pollInterval time.Duration, logger *slog.Logger) *Ingester {
+	if pattern == "" {
+		pattern = "*.json*"
+	}
+	if pollInterval <= 0 {
+		pollInterval = 5 * time.Second
+	}
+	return &Ingester{path, pattern, archiveDir, failedDir, pollInterval, logging.OrDefault(logger)}
+}
+
+// Ingest starts watching the directory and returns a channel
+// containing rating events read from the files found in it.
+func (i *Ingester) Ingest(ctx context.Context) (chan model.RatingEvent, error) {
+	if _, err := os.Stat(i.path); err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{i.archiveDir, i.failedDir} {
+		if dir == "" {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	ch := make(chan model.RatingEvent, 1)
+	go func() {
+		defer close(ch)
+		processed := map[string]bool{}
+		for {
+			if err := i.scan(ctx, processed, ch); err != nil {
+				if errors.Is(err, context.Canceled) {
+					return
+				}
+				i.logger.Error("Failed to scan rating events directory", "path", i.path, "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(i.pollInterval):
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// Commit acknowledges a processed rating event. Files carry no offsets to commit, so it is a no-op.
+func (i *Ingester) Commit(_ context.Context, _ model.RatingEvent) error {
+	return nil
+}
+
+func (i *Ingester) scan(ctx context.Context, processed map[string]bool, ch chan<- model.RatingEvent) error {
+	matches, err := filepath.Glob(filepath.Join(i.path, i.pattern))
+	if err != nil {
+		return err
+	}
+	sort.Strings(matches)
+	for _, name := range matches {
+		if processed[name] {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		// Skip files which may still be written to, they will be picked up by the next scan.
+		if time.Since(info.ModTime()) < i.pollInterval {
+			continue
+		}
+		if err := i.ingestFile(ctx, name, ch); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			i.logger.Error("Failed to ingest rating events file", "path", name, "error", err)
+			i.fail(name)
+			continue
+		}
+		processed[name] = true
+		if i.archiveDir != "" {
+			if err := os.Rename(name, filepath.Join(i.archiveDir, filepath.Base(name))); err != nil {
+				i.logger.Error("Failed to archive rating events file", "path", name, "error", err)
+			} else {
+				delete(processed, name)
+			}
+		}
+	}
+	return nil
+}
+
+// fail moves a file which failed to be ingested to the failed directory, if it is set.
+// Otherwise the file is left in place to be ingested again.
+func (i *Ingester) fail(name string) {
+	if i.failedDir == "" {
+		return
+	}
+	if err := os.Rename(name, filepath.Join(i.failedDir, filepath.Base(name))); err != nil {
+		i.logger.Error("Failed to move rating events file to the failed directory", "path", name, "error", err)
+	}
+}
+
+func (i *Ingester) ingestFile(ctx context.Context, name string, ch chan<- model.RatingEvent) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return file.Decode(ctx, f, ch, i.logger)
+}
@@ -0,0 +1,95 @@
+package dir
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+const validEvents = `{"userId":"105","recordId":"1","recordType":"movie","value":5,"eventType":"put"}
+{"userId":"106","recordId":"1","recordType":"movie","value":4,"eventType":"put"}
+`
+
+// writeFile writes a file old enough not to be considered still written to.
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	old := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(path, old, old))
+}
+
+// receive reads n events from ch.
+func receive(t *testing.T, ch chan model.RatingEvent, n int) []model.UserID {
+	t.Helper()
+	var users []model.UserID
+	for len(users) < n {
+		select {
+		case e := <-ch:
+			users = append(users, e.UserID)
+		case <-time.After(time.Second):
+			t.Fatalf("received %d of %d events", len(users), n)
+		}
+	}
+	return users
+}
+
+func TestIngester_Ingest(t *testing.T) {
+	inbox := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "processed")
+	failed := filepath.Join(t.TempDir(), "failed")
+	writeFile(t, filepath.Join(inbox, "a.jsonl"), validEvents)
+	// A JSON array cut off in the middle fails after its first event is sent.
+	writeFile(t, filepath.Join(inbox, "b.json"), `[{"userId":"107","recordId":"2","recordType":"movie","value":3,"eventType":"put"},{"userId"`)
+	writeFile(t, filepath.Join(inbox, "c.txt"), validEvents)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := NewIngester(inbox, "", archive, failed, 10*time.Millisecond, nil).Ingest(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, []model.UserID{"105", "106", "107"}, receive(t, ch, 3))
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(failed, "b.json"))
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.FileExists(t, filepath.Join(archive, "a.jsonl"))
+	assert.NoFileExists(t, filepath.Join(archive, "b.json"), "files which fail aren't archived")
+	assert.FileExists(t, filepath.Join(inbox, "c.txt"), "files not matching the pattern are left alone")
+
+	writeFile(t, filepath.Join(inbox, "d.jsonl"), `{"userId":"108","recordId":"3","recordType":"movie","value":2,"eventType":"put"}`)
+	assert.Equal(t, []model.UserID{"108"}, receive(t, ch, 1), "new files are picked up by the next scan")
+}
+
+func TestIngester_IngestRetriesFailedFiles(t *testing.T) {
+	inbox := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "processed")
+	path := filepath.Join(inbox, "a.json")
+	writeFile(t, path, `[{"userId":"105","recordId":"1","recordType":"movie","value":5,"eventType":"put"},`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := NewIngester(inbox, "", archive, "", 10*time.Millisecond, nil).Ingest(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, []model.UserID{"105"}, receive(t, ch, 1))
+	assert.Equal(t, []model.UserID{"105"}, receive(t, ch, 1), "the file is ingested again by the next scan")
+	assert.FileExists(t, path, "the file isn't archived")
+
+	writeFile(t, path, `[{"userId":"105","recordId":"1","recordType":"movie","value":5,"eventType":"put"}]`)
+	receive(t, ch, 1)
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(archive, "a.json"))
+		return err == nil
+	}, time.Second, 10*time.Millisecond, "the fixed file is archived")
+}
+
+func TestIngester_IngestMissingDirectory(t *testing.T) {
+	_, err := NewIngester(filepath.Join(t.TempDir(), "missing"), "", "", "", 0, nil).Ingest(context.Background())
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
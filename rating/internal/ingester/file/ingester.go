@@ -0,0 +1,206 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/codec"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// Ingester defines a file-based rating event ingester.
+// It reads either a JSON array of events (the format of cmd/ratingingester/ratingsdata.json)
+// or JSON lines with one event per line. JSON lines files can be followed for appended events.
+type Ingester struct {
+	path         string
+	follow       bool
+	pollInterval time.Duration
+	logger       *slog.Logger
+}
+
+// NewIngester creates a new file ingester. If follow is set, the ingester keeps
+// tailing a JSON lines file and checks for new lines every pollInterval.
+// A nil logger logs to slog.Default().
+func NewIngester(path string, follow bool, pollInterval time.Duration, logger *slog.Logger) *Ingester {
+	if pollInterval <= 0 {
+		pollInterval = time.Second
+	}
+	return &Ingester{path, follow, pollInterval, logging.OrDefault(logger)}
+}
+
+// Ingest starts ingestion from the file and returns a channel
+// containing rating events read from it.
+func (i *Ingester) Ingest(ctx context.Context) (chan model.RatingEvent, error) {
+	f, err := os.Open(i.path)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan model.RatingEvent, 1)
+	go func() {
+		defer close(ch)
+		defer f.Close()
+		r := bufio.NewReader(f)
+		isArray, err := isJSONArray(r)
+		if err != nil && err != io.EOF {
+			i.logger.Error("Failed to read rating events file", "path", i.path, "error", err)
+			return
+		}
+		if isArray {
+			if err := decodeArray(ctx, r, ch, i.logger); err != nil && !errors.Is(err, context.Canceled) {
+				i.logger.Error("Failed to decode rating events file", "path", i.path, "error", err)
+			}
+			return
+		}
+		if err := i.tail(ctx, f, r, ch); err != nil && !errors.Is(err, context.Canceled) {
+			i.logger.Error("Failed to tail rating events file", "path", i.path, "error", err)
+		}
+	}()
+	return ch, nil
+}
+
+// Commit acknowledges a processed rating event. Files carry no offsets to commit, so it is a no-op.
+func (i *Ingester) Commit(_ context.Context, _ model.RatingEvent) error {
+	return nil
+}
+
+// tail reads JSON lines from the file. When the end of the file is reached, it either
+// returns or, in follow mode, waits for more data to be appended.
+func (i *Ingester) tail(ctx context.Context, f *os.File, r *bufio.Reader, ch chan<- model.RatingEvent) error {
+	var offset int64
+	var partial []byte
+	for {
+		line, err := r.ReadBytes('\n')
+		offset += int64(len(line))
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err == io.EOF {
+			// Keep incomplete lines until the writer finishes them.
+			partial = append(partial, line...)
+			if !i.follow {
+				return decodeLine(ctx, partial, ch, i.logger)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(i.pollInterval):
+			}
+			if truncated, err := isTruncated(f, offset); err != nil {
+				return err
+			} else if truncated {
+				if _, err := f.Seek(0, io.SeekStart); err != nil {
+					return err
+				}
+				offset, partial = 0, nil
+				r.Reset(f)
+			}
+			continue
+		}
+		line = append(partial, line...)
+		partial = nil
+		if err := decodeLine(ctx, line, ch, i.logger); err != nil {
+			return err
+		}
+	}
+}
+
+func isTruncated(f *os.File, offset int64) (bool, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	return info.Size() < offset, nil
+}
+
+// Decode reads all rating events from r, which contains either a JSON array of events
+// or JSON lines, and sends them to ch. Events which can't be decoded are logged and skipped.
+func Decode(ctx context.Context, r io.Reader, ch chan<- model.RatingEvent, logger *slog.Logger) error {
+	br := bufio.NewReader(r)
+	isArray, err := isJSONArray(br)
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if isArray {
+		return decodeArray(ctx, br, ch, logger)
+	}
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err := decodeLine(ctx, line, ch, logger); err != nil {
+			return err
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// isJSONArray reports whether the first non-whitespace character of r opens a JSON array.
+func isJSONArray(r *bufio.Reader) (bool, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return false, err
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+		return b == '[', r.UnreadByte()
+	}
+}
+
+func decodeArray(ctx context.Context, r io.Reader, ch chan<- model.RatingEvent, logger *slog.Logger) error {
+	dec := json.NewDecoder(r)
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		event, err := codec.Decode(raw, codec.ContentTypeJSON)
+		if err != nil {
+			logger.Warn("Skipping rating event which can't be decoded", "error", err)
+			continue
+		}
+		if err := send(ctx, ch, *event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeLine(ctx context.Context, line []byte, ch chan<- model.RatingEvent, logger *slog.Logger) error {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil
+	}
+	event, err := codec.Decode(line, codec.ContentTypeJSON)
+	if err != nil {
+		logger.Warn("Skipping rating event which can't be decoded", "error", err)
+		return nil
+	}
+	return send(ctx, ch, *event)
+}
+
+func send(ctx context.Context, ch chan<- model.RatingEvent, event model.RatingEvent) error {
+	select {
+	case ch <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,81 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+func TestIngester_Ingest(t *testing.T) {
+	want := []model.RatingEvent{
+		{SchemaVersion: model.CurrentSchemaVersion, UserID: "105", RecordID: "1", RecordType: "movie", Value: 5, ProviderID: "test-provider", EventType: "put"},
+		{SchemaVersion: model.CurrentSchemaVersion, UserID: "105", RecordID: "2", RecordType: "movie", Value: 4, ProviderID: "test-provider", EventType: "put"},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "JSON array",
+			content: `[{"userId":"105","recordId":"1","recordType":"movie","value":5,"providerId":"test-provider","eventType":"put"},{"userId":"105","recordId":"2","recordType":"movie","value":4,"providerId":"test-provider","eventType":"put"}]`,
+		},
+		{
+			name: "JSON lines",
+			content: `{"userId":"105","recordId":"1","recordType":"movie","value":5,"providerId":"test-provider","eventType":"put"}
+not a json line
+{"userId":"105","recordId":"2","recordType":"movie","value":4,"providerId":"test-provider","eventType":"put"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ratings.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o644))
+
+			ch, err := NewIngester(path, false, 0, nil).Ingest(context.Background())
+			require.NoError(t, err)
+			var got []model.RatingEvent
+			for e := range ch {
+				got = append(got, e)
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestIngester_IngestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratings.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"userId":"1","recordId":"1","recordType":"movie","value":3}`+"\n"), 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := NewIngester(path, true, 10*time.Millisecond, nil).Ingest(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, model.RecordID("1"), (<-ch).RecordID)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"userId":"1","recordId":"2",`)
+	require.NoError(t, err)
+	_, err = f.WriteString(`"recordType":"movie","value":4}` + "\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	select {
+	case e := <-ch:
+		assert.Equal(t, model.RecordID("2"), e.RecordID)
+	case <-time.After(time.Second):
+		t.Fatal("appended event was not ingested")
+	}
+
+	cancel()
+	for range ch {
+	}
+}
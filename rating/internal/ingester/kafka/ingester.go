@@ -2,63 +2,331 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/codec"
 	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// pollTimeout defines how long a single poll waits for a message before checking for cancellation.
+const pollTimeout = 100 * time.Millisecond
+
+// commitInterval defines how often acknowledged offsets are committed.
+const commitInterval = time.Second
+
+// Backoff of reads after errors, e.g. while the brokers are unreachable, doubled per consecutive error.
+const (
+	minReadBackoff = 100 * time.Millisecond
+	maxReadBackoff = 5 * time.Second
+)
+
+// ErrClosed is returned when the ingester consumer is already closed.
+var ErrClosed = errors.New("kafka consumer is closed")
+
+type deadLetterSink interface {
+	Write(ctx context.Context, entry model.DeadLetterEntry) error
+}
+
 // Ingester defines a Kafka ingester.
 type Ingester struct {
-	consumer *kafka.Consumer
-	topic    string
+	consumer   *kafka.Consumer
+	topic      string
+	deadLetter deadLetterSink
+	offsets    *offsetTracker
+	logger     *slog.Logger
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
 }
 
 // NewIngester creates a new Kafka ingester.
-func NewIngester(addr string, groupID string, topic string) (*Ingester, error) {
+// Offsets are not committed automatically, processed events must be acknowledged with Commit.
+// Messages which can't be decoded are written to deadLetter, if it is not nil.
+// The ingester logs per message, so logger should be sampled. A nil logger logs to slog.Default().
+func NewIngester(addr string, groupID string, topic string, deadLetter deadLetterSink, logger *slog.Logger) (*Ingester, error) {
 	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
-		"bootstrap.servers": addr,
-		"group.id":          groupID,
-		"auto.offset.reset": "earliest",
+		"bootstrap.servers":  addr,
+		"group.id":           groupID,
+		"auto.offset.reset":  "earliest",
+		"enable.auto.commit": false,
 	})
 	if err != nil {
 		return nil, err
 	}
-	return &Ingester{consumer, topic}, nil
+	return &Ingester{
+		consumer:   consumer,
+		topic:      topic,
+		deadLetter: deadLetter,
+		offsets:    newOffsetTracker(),
+		logger:     logging.OrDefault(logger),
+		stop:       make(chan struct{}),
+	}, nil
 }
 
 // Ingest starts ingestion from Kafka and returns a channel
-// containing rating events representing the data consumed from the topic
+// containing rating events representing the data consumed from the topic.
+// The channel is closed once the context is done or the ingester is closed.
+// Sending to the channel blocks, so a slow consumer stops the polling of new messages.
 func (i *Ingester) Ingest(ctx context.Context) (chan model.RatingEvent, error) {
-	if err := i.consumer.SubscribeTopics([]string{i.topic}, nil); err != nil {
+	if err := i.consumer.SubscribeTopics([]string{i.topic}, i.rebalance); err != nil {
 		return nil, err
 	}
-	fmt.Println("Ingest func before chan")
 	// we create a channel with 1 bufer
 	ch := make(chan model.RatingEvent, 1)
+	i.wg.Add(2)
 	go func() {
-		for {
+		defer i.wg.Done()
+		defer close(ch)
+		i.read(ctx, ch)
+	}()
+	go func() {
+		defer i.wg.Done()
+		i.commitLoop()
+	}()
+	return ch, nil
+}
+
+func (i *Ingester) read(ctx context.Context, ch chan model.RatingEvent) {
+	var backoff time.Duration
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-i.stop:
+			return
+		default:
+		}
+		msg, err := i.consumer.ReadMessage(pollTimeout)
+		if err != nil {
+			var kerr kafka.Error
+			if errors.As(err, &kerr) && kerr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			if errors.As(err, &kerr) && kerr.IsFatal() {
+				i.logger.ErrorContext(ctx, "Stopping Kafka ingestion after a fatal error", "topic", i.topic, "error", err)
+				return
+			}
+			backoff = min(max(2*backoff, minReadBackoff), maxReadBackoff)
+			i.logger.ErrorContext(ctx, "Failed to read Kafka message", "topic", i.topic, "error", err, "retryIn", backoff)
 			select {
+			case <-time.After(backoff):
 			case <-ctx.Done():
-				close(ch)
-				i.consumer.Close()
-			default:
+				return
+			case <-i.stop:
+				return
 			}
-			msg, err := i.consumer.ReadMessage(-1)
-			if err != nil {
-				fmt.Println("Consumer error: " + err.Error())
-				continue
+			continue
+		}
+		backoff = 0
+		source := &model.EventSource{
+			Topic:     *msg.TopicPartition.Topic,
+			Partition: msg.TopicPartition.Partition,
+			Offset:    int64(msg.TopicPartition.Offset),
+			Payload:   msg.Value,
+		}
+		headers := map[string]string{}
+		for _, h := range msg.Headers {
+			if h.Key == codec.HeaderContentType {
+				source.ContentType = string(h.Value)
 			}
-			var event model.RatingEvent
-			if err := json.Unmarshal(msg.Value, &event); err != nil {
-				fmt.Println("Unmarshall error: " + err.Error())
-				continue
+			headers[h.Key] = string(h.Value)
+		}
+		// The span continues the trace of the producer, processing the event continues this span.
+		msgCtx, span := tracing.Start(tracing.Extract(ctx, headers), source.Topic+" receive",
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", source.Topic),
+			attribute.Int("messaging.kafka.destination.partition", int(source.Partition)),
+			attribute.Int64("messaging.kafka.message.offset", source.Offset),
+		)
+		source.TraceContext = tracing.Inject(msgCtx)
+		i.offsets.add(source.Topic, source.Partition, source.Offset)
+		event, err := codec.Decode(msg.Value, source.ContentType)
+		if err != nil {
+			i.logger.WarnContext(msgCtx, "Rejecting Kafka message which can't be decoded",
+				"topic", source.Topic, "partition", source.Partition, "offset", source.Offset, "error", err)
+			i.rejectMessage(msgCtx, source, err)
+			tracing.End(span, err)
+			continue
+		}
+		span.End()
+		event.Source = source
+		select {
+		case ch <- *event:
+		case <-ctx.Done():
+			return
+		case <-i.stop:
+			return
+		}
+	}
+}
+
+// rejectMessage records a message which can't be decoded in the dead-letter sink and moves past it.
+// The offset is kept uncommitted if the dead-letter write fails, so the message is redelivered.
+func (i *Ingester) rejectMessage(ctx context.Context, source *model.EventSource, cause error) {
+	if i.deadLetter != nil {
+		entry := model.DeadLetterEntry{
+			Payload:     source.Payload,
+			ContentType: source.ContentType,
+			Reason:      model.DeadLetterReasonDecode,
+			Error:       cause.Error(),
+			Source:      source,
+			Time:        time.Now(),
+		}
+		if err := i.deadLetter.Write(ctx, entry); err != nil {
+			i.logger.ErrorContext(ctx, "Failed to write Kafka message to the dead-letter sink",
+				"topic", source.Topic, "partition", source.Partition, "offset", source.Offset, "error", err)
+			return
+		}
+	}
+	i.offsets.ack(source.Topic, source.Partition, source.Offset)
+}
+
+// Commit acknowledges a processed rating event. Events may be acknowledged out of order,
+// an offset is committed once all events read before it from the same partition are acknowledged.
+func (i *Ingester) Commit(_ context.Context, event model.RatingEvent) error {
+	if event.Source == nil {
+		return nil
+	}
+	i.offsets.ack(event.Source.Topic, event.Source.Partition, event.Source.Offset)
+	return nil
+}
+
+// Ping checks that the Kafka brokers are reachable and the consumed topic exists.
+func (i *Ingester) Ping(ctx context.Context) error {
+	timeout := 5 * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.closed {
+		return ErrClosed
+	}
+	md, err := i.consumer.GetMetadata(&i.topic, false, int(timeout.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	if t, ok := md.Topics[i.topic]; ok && t.Error.Code() != kafka.ErrNoError {
+		return t.Error
+	}
+	return nil
+}
+
+// Lag returns the number of messages in the assigned partitions which are not consumed yet.
+func (i *Ingester) Lag() (int64, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.closed {
+		return 0, ErrClosed
+	}
+	assignment, err := i.consumer.Assignment()
+	if err != nil {
+		return 0, err
+	}
+	positions, err := i.consumer.Position(assignment)
+	if err != nil {
+		return 0, err
+	}
+	var lag int64
+	for _, p := range positions {
+		// The position is unknown until a message of the partition is consumed.
+		if p.Offset < 0 {
+			continue
+		}
+		_, high, err := i.consumer.GetWatermarkOffsets(*p.Topic, p.Partition)
+		if err != nil {
+			return 0, err
+		}
+		lag += max(high-int64(p.Offset), 0)
+	}
+	return lag, nil
+}
+
+func (i *Ingester) commitLoop() {
+	ticker := time.NewTicker(commitInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-i.stop:
+			return
+		case <-ticker.C:
+			if err := i.commitReady(); err != nil {
+				i.logger.Error("Failed to commit Kafka offsets", "topic", i.topic, "error", err)
 			}
-			fmt.Println("event goes to channel which fetched from topic", event)
-			ch <- event
 		}
-	}()
+	}
+}
 
-	return ch, nil
+// commitReady commits the offsets of all partitions whose acknowledged offsets advanced.
+func (i *Ingester) commitReady() error {
+	ready := i.offsets.committable()
+	if len(ready) == 0 {
+		return nil
+	}
+	offsets := make([]kafka.TopicPartition, 0, len(ready))
+	for key, offset := range ready {
+		topic := key.topic
+		offsets = append(offsets, kafka.TopicPartition{Topic: &topic, Partition: key.partition, Offset: kafka.Offset(offset)})
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.closed {
+		i.offsets.restore(ready)
+		return ErrClosed
+	}
+	if _, err := i.consumer.CommitOffsets(offsets); err != nil {
+		i.offsets.restore(ready)
+		return err
+	}
+	return nil
+}
+
+// rebalance commits the acknowledged offsets of revoked partitions before they are assigned
+// to another consumer of the group.
+func (i *Ingester) rebalance(c *kafka.Consumer, event kafka.Event) error {
+	revoked, ok := event.(kafka.RevokedPartitions)
+	if !ok {
+		return nil
+	}
+	var offsets []kafka.TopicPartition
+	for _, p := range revoked.Partitions {
+		if offset := i.offsets.remove(*p.Topic, p.Partition); offset >= 0 {
+			offsets = append(offsets, kafka.TopicPartition{Topic: p.Topic, Partition: p.Partition, Offset: kafka.Offset(offset)})
+		}
+	}
+	if len(offsets) == 0 {
+		return nil
+	}
+	if _, err := c.CommitOffsets(offsets); err != nil {
+		i.logger.Error("Failed to commit Kafka offsets", "topic", i.topic, "error", err)
+	}
+	return nil
+}
+
+// Close stops the ingestion, commits the acknowledged offsets and closes the consumer.
+// It should be called after the events read from the ingester are processed.
+func (i *Ingester) Close() error {
+	i.stopOnce.Do(func() { close(i.stop) })
+	i.wg.Wait()
+	if err := i.commitReady(); err != nil && !errors.Is(err, ErrClosed) {
+		i.logger.Error("Failed to commit Kafka offsets", "topic", i.topic, "error", err)
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.closed {
+		return nil
+	}
+	i.closed = true
+	return i.consumer.Close()
 }
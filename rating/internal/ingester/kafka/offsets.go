@@ -0,0 +1,98 @@
+package kafka
+
+import "sync"
+
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// partitionOffsets tracks the offsets of a partition which were read but not yet acknowledged in order.
+type partitionOffsets struct {
+	inflight []int64
+	done     map[int64]bool
+	// next is the offset to commit, the offset of the next message to consume, or -1 if there is nothing to commit.
+	next int64
+}
+
+// offsetTracker tracks in-flight offsets per partition. Events are acknowledged out of order by
+// concurrent workers, so an offset is only committed once all offsets before it are acknowledged.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[partitionKey]*partitionOffsets
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: map[partitionKey]*partitionOffsets{}}
+}
+
+// add records a read offset. Offsets of a partition must be added in increasing order.
+func (t *offsetTracker) add(topic string, partition int32, offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := partitionKey{topic, partition}
+	p, ok := t.partitions[key]
+	if !ok {
+		p = &partitionOffsets{done: map[int64]bool{}, next: -1}
+		t.partitions[key] = p
+	}
+	p.inflight = append(p.inflight, offset)
+}
+
+// ack marks an offset as processed. Unknown offsets, e.g. of revoked partitions, are ignored.
+func (t *offsetTracker) ack(topic string, partition int32, offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p, ok := t.partitions[partitionKey{topic, partition}]
+	if !ok {
+		return
+	}
+	p.done[offset] = true
+	n := 0
+	for n < len(p.inflight) && p.done[p.inflight[n]] {
+		delete(p.done, p.inflight[n])
+		p.next = p.inflight[n] + 1
+		n++
+	}
+	p.inflight = p.inflight[n:]
+}
+
+// committable returns the offsets ready to be committed per partition and resets them.
+// Offsets which fail to be committed must be put back with restore.
+func (t *offsetTracker) committable() map[partitionKey]int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	res := map[partitionKey]int64{}
+	for key, p := range t.partitions {
+		if p.next >= 0 {
+			res[key] = p.next
+			p.next = -1
+		}
+	}
+	return res
+}
+
+// restore puts back offsets returned by committable which failed to be committed, unless their partition
+// was revoked or acknowledged further in the meantime.
+func (t *offsetTracker) restore(offsets map[partitionKey]int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, offset := range offsets {
+		if p, ok := t.partitions[key]; ok && p.next < offset {
+			p.next = offset
+		}
+	}
+}
+
+// remove stops tracking a partition and returns its offset ready to be committed, or -1.
+func (t *offsetTracker) remove(topic string, partition int32) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := partitionKey{topic, partition}
+	p, ok := t.partitions[key]
+	if !ok {
+		return -1
+	}
+	delete(t.partitions, key)
+	return p.next
+}
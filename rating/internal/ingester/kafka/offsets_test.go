@@ -0,0 +1,57 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	tracker := newOffsetTracker()
+	for _, offset := range []int64{10, 11, 12} {
+		tracker.add("ratings", 0, offset)
+	}
+	tracker.add("ratings", 1, 5)
+
+	tracker.ack("ratings", 0, 11)
+	assert.Empty(t, tracker.committable(), "offset 11 must wait for offset 10")
+
+	tracker.ack("ratings", 0, 10)
+	tracker.ack("ratings", 1, 5)
+	assert.Equal(t, map[partitionKey]int64{
+		{"ratings", 0}: 12,
+		{"ratings", 1}: 6,
+	}, tracker.committable())
+	assert.Empty(t, tracker.committable(), "committed offsets must be reset")
+
+	tracker.ack("ratings", 0, 12)
+	assert.Equal(t, int64(13), tracker.remove("ratings", 0))
+	assert.Equal(t, int64(-1), tracker.remove("ratings", 0))
+
+	tracker.ack("ratings", 0, 13)
+	assert.Equal(t, map[partitionKey]int64{}, tracker.committable(), "acks of revoked partitions must be ignored")
+}
+
+func TestOffsetTracker_Restore(t *testing.T) {
+	tracker := newOffsetTracker()
+	for _, offset := range []int64{10, 11} {
+		tracker.add("ratings", 0, offset)
+	}
+	tracker.add("ratings", 1, 5)
+	tracker.ack("ratings", 0, 10)
+	tracker.ack("ratings", 1, 5)
+
+	// The commit of the offsets failed.
+	tracker.restore(tracker.committable())
+	assert.Equal(t, map[partitionKey]int64{
+		{"ratings", 0}: 11,
+		{"ratings", 1}: 6,
+	}, tracker.committable(), "offsets failed to be committed must be committed again")
+
+	ready := map[partitionKey]int64{{"ratings", 0}: 11, {"ratings", 1}: 6}
+	tracker.ack("ratings", 0, 11)
+	tracker.remove("ratings", 1)
+	tracker.restore(ready)
+	assert.Equal(t, map[partitionKey]int64{{"ratings", 0}: 12}, tracker.committable(),
+		"restored offsets must not replace newer offsets or revive revoked partitions")
+}
@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// Ingester defines an in-memory rating event ingester backed by a channel.
+// It is mainly intended for tests.
+type Ingester struct {
+	once sync.Once
+	ch   chan model.RatingEvent
+
+	mu        sync.Mutex
+	committed []model.RatingEvent
+}
+
+// NewIngester creates a new in-memory ingester with a given channel buffer size.
+func NewIngester(size int) *Ingester {
+	return &Ingester{ch: make(chan model.RatingEvent, size)}
+}
+
+// Ingest returns a channel containing the published rating events.
+func (i *Ingester) Ingest(_ context.Context) (chan model.RatingEvent, error) {
+	return i.ch, nil
+}
+
+// Publish sends rating events to the ingestion channel.
+// It blocks until all events are accepted or the context is done.
+func (i *Ingester) Publish(ctx context.Context, events ...model.RatingEvent) error {
+	for _, e := range events {
+		select {
+		case i.ch <- e:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Commit records a processed rating event.
+func (i *Ingester) Commit(_ context.Context, event model.RatingEvent) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.committed = append(i.committed, event)
+	return nil
+}
+
+// Committed returns the rating events acknowledged so far.
+func (i *Ingester) Committed() []model.RatingEvent {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]model.RatingEvent(nil), i.committed...)
+}
+
+// Close closes the ingestion channel. Publish must not be called after Close.
+func (i *Ingester) Close() {
+	i.once.Do(func() { close(i.ch) })
+}
@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+func TestIngester(t *testing.T) {
+	ctx := context.Background()
+	events := []model.RatingEvent{
+		{UserID: "105", RecordID: "1", RecordType: "movie", Value: 5, EventType: model.RatingEventTypePut},
+		{UserID: "106", RecordID: "1", RecordType: "movie", EventType: model.RatingEventTypeDelete},
+	}
+	i := NewIngester(len(events))
+	ch, err := i.Ingest(ctx)
+	require.NoError(t, err)
+	require.NoError(t, i.Publish(ctx, events...))
+	i.Close()
+	i.Close()
+
+	var got []model.RatingEvent
+	for e := range ch {
+		got = append(got, e)
+		require.NoError(t, i.Commit(ctx, e))
+	}
+	assert.Equal(t, events, got)
+	assert.Equal(t, events, i.Committed())
+}
+
+func TestIngester_PublishCanceled(t *testing.T) {
+	i := NewIngester(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := i.Publish(ctx, model.RatingEvent{UserID: "105"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, i.Committed())
+}
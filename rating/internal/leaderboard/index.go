@@ -0,0 +1,45 @@
+package leaderboard
+
+import (
+	"sort"
+
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+type indexEntry struct {
+	id    model.RecordID
+	score float64
+}
+
+// index defines records sorted by score in descending order, ties are ordered by record id.
+type index struct {
+	entries []indexEntry
+}
+
+func (e indexEntry) before(o indexEntry) bool {
+	if e.score != o.score {
+		return e.score > o.score
+	}
+	return e.id < o.id
+}
+
+func (x *index) search(e indexEntry) int {
+	return sort.Search(len(x.entries), func(i int) bool {
+		return !x.entries[i].before(e)
+	})
+}
+
+func (x *index) insert(id model.RecordID, score float64) {
+	e := indexEntry{id, score}
+	i := x.search(e)
+	x.entries = append(x.entries, indexEntry{})
+	copy(x.entries[i+1:], x.entries[i:])
+	x.entries[i] = e
+}
+
+func (x *index) remove(id model.RecordID, score float64) {
+	i := x.search(indexEntry{id, score})
+	if i < len(x.entries) && x.entries[i].id == id {
+		x.entries = append(x.entries[:i], x.entries[i+1:]...)
+	}
+}
@@ -0,0 +1,388 @@
+package leaderboard
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// ErrUnknownStrategy is returned when a leaderboard is requested for an unsupported aggregation strategy.
+var ErrUnknownStrategy = errors.New("unknown aggregation strategy")
+
+// Config defines a leaderboard config.
+type Config struct {
+	// Strategy defines the aggregation strategy used when none is requested.
+	Strategy model.AggregationStrategy
+	// PriorMean and PriorVotes define the prior of the bayesian strategy: every record is ranked as if it had
+	// PriorVotes additional ratings of PriorMean, a fraction of the rating scale of its type from 0 for the
+	// lowest value to 1 for the highest.
+	PriorMean  float64
+	PriorVotes float64
+	// TrendingBucket defines the resolution of rating activity, TrendingRetention how long it is kept.
+	TrendingBucket    time.Duration
+	TrendingRetention time.Duration
+}
+
+// DefaultConfig defines the default leaderboard config.
+var DefaultConfig = Config{
+	Strategy:          model.AggregationStrategyWeighted,
+	PriorMean:         0.5,
+	PriorVotes:        10,
+	TrendingBucket:    time.Hour,
+	TrendingRetention: 7 * 24 * time.Hour,
+}
+
+// strategies lists the strategies a sorted index is maintained for, in the order of record.scores.
+var strategies = []model.AggregationStrategy{
+	model.AggregationStrategyMean,
+	model.AggregationStrategyWeighted,
+	model.AggregationStrategyBayesian,
+}
+
+// epsilon absorbs rounding errors of the running sums, records with a smaller total weight are not ranked.
+const epsilon = 1e-9
+
+type recordKey struct {
+	id  model.RecordID
+	typ model.RecordType
+}
+
+type vote struct {
+	value  float64
+	weight float64
+}
+
+// record holds the running sums of the ratings of a record and its current score per strategy.
+// Individual ratings aren't kept, updates pass the rating they replace so its vote can be subtracted.
+type record struct {
+	votes       int
+	sum         float64
+	weightedSum float64
+	weightTotal float64
+	scores      []float64
+	ranked      []bool
+}
+
+// activity holds the number of ratings of a record per time bucket.
+type activity map[int64]int
+
+// Leaderboard defines an in-memory leaderboard of records, updated incrementally with every rating write.
+// Records are kept sorted by score for each aggregation strategy and record type.
+type Leaderboard struct {
+	mu       sync.RWMutex
+	cfg      Config
+	weight   func(providerID string) float64
+	scale    func(recordType model.RecordType) (model.RatingScale, error)
+	now      func() time.Time
+	records  map[recordKey]*record
+	indexes  map[model.RecordType][]*index
+	activity map[model.RecordType]map[model.RecordID]activity
+	// pruned is the bucket in which expired activity was last dropped.
+	pruned int64
+}
+
+// New creates a new leaderboard. Ratings are weighted with weight when they are put,
+// so the leaderboard should be rebuilt once the weights change. The prior of the bayesian strategy
+// is placed on the rating scale of a record type returned by scale.
+func New(cfg Config, weight func(providerID string) float64, scale func(recordType model.RecordType) (model.RatingScale, error)) *Leaderboard {
+	if cfg.Strategy == "" {
+		cfg.Strategy = DefaultConfig.Strategy
+	}
+	if cfg.TrendingBucket <= 0 {
+		cfg.TrendingBucket = DefaultConfig.TrendingBucket
+	}
+	if cfg.TrendingRetention < cfg.TrendingBucket {
+		cfg.TrendingRetention = DefaultConfig.TrendingRetention
+	}
+	return &Leaderboard{
+		cfg:      cfg,
+		weight:   weight,
+		scale:    scale,
+		now:      time.Now,
+		records:  map[recordKey]*record{},
+		indexes:  map[model.RecordType][]*index{},
+		activity: map[model.RecordType]map[model.RecordID]activity{},
+	}
+}
+
+// Put records a rating. If it replaces an earlier rating of the same user for the record,
+// old must be that rating as it was put, otherwise nil.
+func (l *Leaderboard) Put(recordID model.RecordID, recordType model.RecordType, rating *model.Rating, old *model.Rating) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := recordKey{recordID, recordType}
+	r := l.record(key)
+	if old != nil {
+		r.remove(l.vote(old))
+	}
+	r.add(l.vote(rating))
+	l.reindex(key, r)
+	l.recordActivity(recordID, recordType, l.activityTime(rating))
+}
+
+// Delete removes a rating of a record, old being the rating as it was put.
+func (l *Leaderboard) Delete(recordID model.RecordID, recordType model.RecordType, old *model.Rating) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := recordKey{recordID, recordType}
+	r, ok := l.records[key]
+	if !ok {
+		return
+	}
+	r.remove(l.vote(old))
+	l.reindex(key, r)
+}
+
+// Reset replaces the ratings and the activity of a record with the given ratings, e.g. as read from the repository.
+func (l *Leaderboard) Reset(recordID model.RecordID, recordType model.RecordType, ratings []model.Rating) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := recordKey{recordID, recordType}
+	r := l.record(key)
+	r.votes, r.sum, r.weightedSum, r.weightTotal = 0, 0, 0, 0
+	delete(l.activity[recordType], recordID)
+	for i := range ratings {
+		r.add(l.vote(&ratings[i]))
+		l.recordActivity(recordID, recordType, l.activityTime(&ratings[i]))
+	}
+	l.reindex(key, r)
+}
+
+// record returns the record of a key, creating it if it has no ratings yet.
+func (l *Leaderboard) record(key recordKey) *record {
+	r, ok := l.records[key]
+	if !ok {
+		r = &record{
+			scores: make([]float64, len(strategies)),
+			ranked: make([]bool, len(strategies)),
+		}
+		l.records[key] = r
+	}
+	return r
+}
+
+func (l *Leaderboard) vote(rating *model.Rating) vote {
+	return vote{value: float64(rating.Value), weight: l.weight(rating.ProviderID)}
+}
+
+func (l *Leaderboard) activityTime(rating *model.Rating) time.Time {
+	if rating.UpdatedAt.IsZero() {
+		return l.now()
+	}
+	return rating.UpdatedAt
+}
+
+// TopRated returns up to limit records of a type with at least minVotes ratings, ordered by their score
+// for the given strategy. An empty strategy selects the configured one.
+func (l *Leaderboard) TopRated(recordType model.RecordType, strategy model.AggregationStrategy, minVotes int, limit int) ([]model.RankedRecord, error) {
+	if strategy == "" {
+		strategy = l.cfg.Strategy
+	}
+	s := strategyIndex(strategy)
+	if s < 0 {
+		return nil, ErrUnknownStrategy
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	indexes, ok := l.indexes[recordType]
+	if !ok {
+		return nil, nil
+	}
+	var res []model.RankedRecord
+	for _, e := range indexes[s].entries {
+		if limit > 0 && len(res) >= limit {
+			break
+		}
+		votes := l.records[recordKey{e.id, recordType}].votes
+		if votes < minVotes {
+			continue
+		}
+		res = append(res, model.RankedRecord{RecordID: e.id, RecordType: recordType, Score: e.score, Votes: votes})
+	}
+	return res, nil
+}
+
+// Mean returns the mean of the ratings of a record and their number.
+func (l *Leaderboard) Mean(recordID model.RecordID, recordType model.RecordType) (float64, int) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	r, ok := l.records[recordKey{recordID, recordType}]
+	if !ok || r.votes == 0 {
+		return 0, 0
+	}
+	return r.sum / float64(r.votes), r.votes
+}
+
+// Trending returns up to limit records of a type with the most ratings per hour within the window
+// ending now. The window is rounded up to whole trending buckets and capped by the trending retention.
+func (l *Leaderboard) Trending(recordType model.RecordType, window time.Duration, limit int) []model.RankedRecord {
+	if window <= 0 || window > l.cfg.TrendingRetention {
+		window = l.cfg.TrendingRetention
+	}
+	buckets := int64((window + l.cfg.TrendingBucket - 1) / l.cfg.TrendingBucket)
+	from := l.bucket(l.now()) - buckets + 1
+	hours := (time.Duration(buckets) * l.cfg.TrendingBucket).Hours()
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var res []model.RankedRecord
+	for id, a := range l.activity[recordType] {
+		count := 0
+		for b, n := range a {
+			if b >= from {
+				count += n
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		res = append(res, model.RankedRecord{RecordID: id, RecordType: recordType, Score: float64(count) / hours, Votes: count})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score > res[j].Score
+		}
+		return res[i].RecordID < res[j].RecordID
+	})
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res
+}
+
+func (r *record) add(v vote) {
+	r.votes++
+	r.sum += v.value
+	r.weightedSum += v.weight * v.value
+	r.weightTotal += v.weight
+}
+
+func (r *record) remove(v vote) {
+	r.votes--
+	r.sum -= v.value
+	r.weightedSum -= v.weight * v.value
+	r.weightTotal -= v.weight
+}
+
+// prior returns the mean and the number of votes of the prior of the bayesian strategy for a record type.
+// Records of types without a scale, e.g. no longer registered ones, are ranked without a prior.
+func (l *Leaderboard) prior(recordType model.RecordType) (float64, float64) {
+	scale, err := l.scale(recordType)
+	if err != nil {
+		return 0, 0
+	}
+	return float64(scale.Min) + l.cfg.PriorMean*float64(scale.Max-scale.Min), l.cfg.PriorVotes
+}
+
+// score returns the score of a record for a strategy and false if the record is not ranked for it.
+func (l *Leaderboard) score(r *record, strategy model.AggregationStrategy, priorMean float64, priorVotes float64) (float64, bool) {
+	switch strategy {
+	case model.AggregationStrategyMean:
+		if r.votes <= 0 {
+			return 0, false
+		}
+		return r.sum / float64(r.votes), true
+	case model.AggregationStrategyWeighted:
+		if r.weightTotal <= epsilon {
+			return 0, false
+		}
+		return r.weightedSum / r.weightTotal, true
+	case model.AggregationStrategyBayesian:
+		if r.weightTotal <= epsilon {
+			return 0, false
+		}
+		return (r.weightedSum + priorMean*priorVotes) / (r.weightTotal + priorVotes), true
+	}
+	return 0, false
+}
+
+// reindex moves a record to its new position in the sorted indexes of its type.
+func (l *Leaderboard) reindex(key recordKey, r *record) {
+	indexes, ok := l.indexes[key.typ]
+	if !ok {
+		indexes = make([]*index, len(strategies))
+		for i := range indexes {
+			indexes[i] = &index{}
+		}
+		l.indexes[key.typ] = indexes
+	}
+	priorMean, priorVotes := l.prior(key.typ)
+	for i, strategy := range strategies {
+		if r.ranked[i] {
+			indexes[i].remove(key.id, r.scores[i])
+		}
+		r.scores[i], r.ranked[i] = l.score(r, strategy, priorMean, priorVotes)
+		if r.ranked[i] {
+			indexes[i].insert(key.id, r.scores[i])
+		}
+	}
+	if r.votes <= 0 {
+		delete(l.records, key)
+	}
+}
+
+func (l *Leaderboard) bucket(t time.Time) int64 {
+	return t.UnixNano() / int64(l.cfg.TrendingBucket)
+}
+
+// recordActivity counts a rating in its time bucket. Once per bucket, the buckets older than the retention
+// are dropped from all records.
+func (l *Leaderboard) recordActivity(recordID model.RecordID, recordType model.RecordType, at time.Time) {
+	now := l.bucket(l.now())
+	oldest := now - int64(l.cfg.TrendingRetention/l.cfg.TrendingBucket) + 1
+	if now > l.pruned {
+		l.prune(oldest)
+		l.pruned = now
+	}
+	b := l.bucket(at)
+	if b < oldest {
+		return
+	}
+	records, ok := l.activity[recordType]
+	if !ok {
+		records = map[model.RecordID]activity{}
+		l.activity[recordType] = records
+	}
+	a, ok := records[recordID]
+	if !ok {
+		a = activity{}
+		records[recordID] = a
+	}
+	a[b]++
+}
+
+// prune drops the activity buckets before oldest and the records left without activity.
+func (l *Leaderboard) prune(oldest int64) {
+	for recordType, records := range l.activity {
+		for recordID, a := range records {
+			for b := range a {
+				if b < oldest {
+					delete(a, b)
+				}
+			}
+			if len(a) == 0 {
+				delete(records, recordID)
+			}
+		}
+		if len(records) == 0 {
+			delete(l.activity, recordType)
+		}
+	}
+}
+
+// Supported reports whether a leaderboard can rank records with an aggregation strategy.
+func Supported(strategy model.AggregationStrategy) bool {
+	return strategyIndex(strategy) >= 0
+}
+
+func strategyIndex(strategy model.AggregationStrategy) int {
+	for i, s := range strategies {
+		if s == strategy {
+			return i
+		}
+	}
+	return -1
+}
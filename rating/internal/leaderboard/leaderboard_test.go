@@ -0,0 +1,202 @@
+package leaderboard
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// stars5 rates every record type with 1 to 5 stars.
+func stars5(model.RecordType) (model.RatingScale, error) {
+	return model.RatingScaleStars5, nil
+}
+
+func TestLeaderboard_TopRated(t *testing.T) {
+	weights := map[string]float64{"spammy": 0}
+	// The prior is 3 stars.
+	l := New(Config{PriorMean: 0.5, PriorVotes: 2}, func(providerID string) float64 {
+		if w, ok := weights[providerID]; ok {
+			return w
+		}
+		return 1
+	}, stars5)
+	// ratings holds the ratings as they were put, the leaderboard is passed the rating it replaces.
+	ratings := map[[2]string]*model.Rating{}
+	put := func(recordID model.RecordID, userID model.UserID, value model.RatingValue, providerID string) {
+		rating := &model.Rating{UserID: userID, Value: value, ProviderID: providerID}
+		key := [2]string{string(recordID), string(userID)}
+		l.Put(recordID, model.RecordTypeMovie, rating, ratings[key])
+		ratings[key] = rating
+	}
+	put("1", "user1", 5, "")
+	put("2", "user1", 4, "")
+	put("2", "user2", 4, "")
+	put("2", "user3", 4, "")
+	put("3", "user1", 1, "spammy")
+	put("3", "user2", 3, "")
+
+	tests := []struct {
+		name     string
+		strategy model.AggregationStrategy
+		minVotes int
+		expected []model.RankedRecord
+	}{
+		{
+			name:     "Mean",
+			strategy: model.AggregationStrategyMean,
+			expected: []model.RankedRecord{
+				{RecordID: "1", RecordType: model.RecordTypeMovie, Score: 5, Votes: 1},
+				{RecordID: "2", RecordType: model.RecordTypeMovie, Score: 4, Votes: 3},
+				{RecordID: "3", RecordType: model.RecordTypeMovie, Score: 2, Votes: 2},
+			},
+		},
+		{
+			name:     "Weighted with vote threshold",
+			strategy: model.AggregationStrategyWeighted,
+			minVotes: 2,
+			expected: []model.RankedRecord{
+				{RecordID: "2", RecordType: model.RecordTypeMovie, Score: 4, Votes: 3},
+				{RecordID: "3", RecordType: model.RecordTypeMovie, Score: 3, Votes: 2},
+			},
+		},
+		{
+			// Record 1 scores (5+3*2)/(1+2), record 2 (12+3*2)/(3+2), record 3 (3+3*2)/(1+2).
+			name:     "Bayesian",
+			strategy: model.AggregationStrategyBayesian,
+			expected: []model.RankedRecord{
+				{RecordID: "1", RecordType: model.RecordTypeMovie, Score: 11.0 / 3, Votes: 1},
+				{RecordID: "2", RecordType: model.RecordTypeMovie, Score: 3.6, Votes: 3},
+				{RecordID: "3", RecordType: model.RecordTypeMovie, Score: 3, Votes: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.TopRated(model.RecordTypeMovie, tt.strategy, tt.minVotes, 10)
+			require.NoError(t, err)
+			require.Len(t, got, len(tt.expected))
+			for i := range got {
+				assert.Equal(t, tt.expected[i].RecordID, got[i].RecordID)
+				assert.Equal(t, tt.expected[i].Votes, got[i].Votes)
+				assert.InDelta(t, tt.expected[i].Score, got[i].Score, 1e-9)
+			}
+		})
+	}
+
+	// Updating and deleting ratings moves records in the index.
+	put("1", "user1", 1, "")
+	l.Delete("3", model.RecordTypeMovie, ratings[[2]string{"3", "user2"}])
+	got, err := l.TopRated(model.RecordTypeMovie, model.AggregationStrategyMean, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []model.RankedRecord{
+		{RecordID: "2", RecordType: model.RecordTypeMovie, Score: 4, Votes: 3},
+		{RecordID: "1", RecordType: model.RecordTypeMovie, Score: 1, Votes: 1},
+	}, got)
+
+	_, err = l.TopRated(model.RecordTypeMovie, "median", 0, 10)
+	assert.ErrorIs(t, err, ErrUnknownStrategy)
+}
+
+func TestLeaderboard_Trending(t *testing.T) {
+	now := time.Date(2024, time.January, 8, 12, 30, 0, 0, time.UTC)
+	l := New(Config{TrendingBucket: time.Hour, TrendingRetention: 48 * time.Hour}, func(string) float64 { return 1 }, stars5)
+	l.now = func() time.Time { return now }
+	put := func(recordID model.RecordID, userID model.UserID, ago time.Duration) {
+		l.Put(recordID, model.RecordTypeMovie, &model.Rating{UserID: userID, Value: 3, UpdatedAt: now.Add(-ago)}, nil)
+	}
+	put("1", "user1", 10*time.Minute)
+	put("1", "user2", 20*time.Minute)
+	put("2", "user1", 5*time.Minute)
+	for i := 0; i < 5; i++ {
+		put("2", model.UserID(rune('a'+i)), 30*time.Hour)
+	}
+	put("3", "user1", 72*time.Hour)
+
+	assert.Equal(t, []model.RankedRecord{
+		{RecordID: "1", RecordType: model.RecordTypeMovie, Score: 2, Votes: 2},
+		{RecordID: "2", RecordType: model.RecordTypeMovie, Score: 1, Votes: 1},
+	}, l.Trending(model.RecordTypeMovie, time.Hour, 10))
+
+	got := l.Trending(model.RecordTypeMovie, 0, 1)
+	assert.Equal(t, []model.RankedRecord{
+		{RecordID: "2", RecordType: model.RecordTypeMovie, Score: 6.0 / 48, Votes: 6},
+	}, got)
+}
+
+func TestLeaderboard_Reset(t *testing.T) {
+	now := time.Date(2024, time.January, 8, 12, 30, 0, 0, time.UTC)
+	l := New(Config{}, func(string) float64 { return 1 }, stars5)
+	l.now = func() time.Time { return now }
+	l.Put("1", model.RecordTypeMovie, &model.Rating{UserID: "user1", Value: 5}, nil)
+	l.Put("1", model.RecordTypeMovie, &model.Rating{UserID: "user2", Value: 5}, nil)
+	l.Put("2", model.RecordTypeMovie, &model.Rating{UserID: "user1", Value: 4}, nil)
+
+	l.Reset("1", model.RecordTypeMovie, []model.Rating{{UserID: "user1", Value: 2, UpdatedAt: now.Add(-2 * time.Hour)}})
+	mean, votes := l.Mean("1", model.RecordTypeMovie)
+	assert.Equal(t, 2.0, mean)
+	assert.Equal(t, 1, votes)
+	got, err := l.TopRated(model.RecordTypeMovie, model.AggregationStrategyMean, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []model.RankedRecord{
+		{RecordID: "2", RecordType: model.RecordTypeMovie, Score: 4, Votes: 1},
+		{RecordID: "1", RecordType: model.RecordTypeMovie, Score: 2, Votes: 1},
+	}, got)
+	assert.Equal(t, []model.RankedRecord{
+		{RecordID: "2", RecordType: model.RecordTypeMovie, Score: 1, Votes: 1},
+	}, l.Trending(model.RecordTypeMovie, time.Hour, 0), "the activity of the record is replaced")
+
+	l.Reset("2", model.RecordTypeMovie, nil)
+	_, votes = l.Mean("2", model.RecordTypeMovie)
+	assert.Zero(t, votes)
+	got, err = l.TopRated(model.RecordTypeMovie, model.AggregationStrategyMean, 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, got, 1)
+}
+
+func TestLeaderboard_PruneActivity(t *testing.T) {
+	now := time.Date(2024, time.January, 8, 12, 30, 0, 0, time.UTC)
+	l := New(Config{TrendingBucket: time.Hour, TrendingRetention: 2 * time.Hour}, func(string) float64 { return 1 }, stars5)
+	l.now = func() time.Time { return now }
+	l.Put("1", model.RecordTypeMovie, &model.Rating{UserID: "user1", Value: 3}, nil)
+	l.Put("2", model.RecordTypeMovie, &model.Rating{UserID: "user1", Value: 3}, nil)
+
+	now = now.Add(3 * time.Hour)
+	l.Put("3", model.RecordTypeMovie, &model.Rating{UserID: "user1", Value: 3}, nil)
+	assert.Len(t, l.activity[model.RecordTypeMovie], 1, "expired activity of other records is dropped")
+	assert.Contains(t, l.activity[model.RecordTypeMovie], model.RecordID("3"))
+}
+
+func TestLeaderboard_PriorScale(t *testing.T) {
+	l := New(Config{PriorMean: 0.5, PriorVotes: 2}, func(string) float64 { return 1 }, func(recordType model.RecordType) (model.RatingScale, error) {
+		switch recordType {
+		case model.RecordTypeMovie:
+			return model.RatingScaleStars5, nil
+		case "clip":
+			return model.RatingScaleThumbs, nil
+		}
+		return model.RatingScale{}, errors.New("unknown record type")
+	})
+	l.Put("1", model.RecordTypeMovie, &model.Rating{UserID: "user1", Value: 5}, nil)
+	l.Put("1", "clip", &model.Rating{UserID: "user1", Value: 1}, nil)
+	l.Put("1", "book", &model.Rating{UserID: "user1", Value: 4}, nil)
+
+	// The prior is placed on the scale of each record type: 3 stars, halfway between thumbs down and up.
+	for _, tt := range []struct {
+		recordType model.RecordType
+		want       float64
+	}{
+		{recordType: model.RecordTypeMovie, want: (5 + 3*2) / 3.0},
+		{recordType: "clip", want: (1 + 0.5*2) / 3.0},
+		{recordType: "book", want: 4},
+	} {
+		got, err := l.TopRated(tt.recordType, model.AggregationStrategyBayesian, 0, 0)
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		assert.InDelta(t, tt.want, got[0].Score, 1e-9, tt.recordType)
+	}
+}
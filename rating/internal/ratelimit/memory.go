@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval defines how often full buckets are dropped from a memory store.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Duration
+}
+
+// MemoryStore defines a token bucket store local to a service instance.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates a new memory token bucket store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}}
+}
+
+// Take takes a token from each of the buckets if all of them have one. Otherwise no token is taken
+// and it returns the key of the bucket which takes the longest to have a token again and how long that is.
+func (s *MemoryStore) Take(_ context.Context, buckets []Bucket, now time.Time) (string, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	refilled := make([]float64, len(buckets))
+	remaining := make([]float64, len(buckets))
+	var key string
+	var wait time.Duration
+	for i, k := range buckets {
+		refilled[i] = k.Limit.Capacity()
+		if b, ok := s.buckets[k.Key]; ok {
+			refilled[i] = k.Limit.Refill(b.tokens, now.Sub(b.updated))
+		}
+		var w time.Duration
+		remaining[i], w = k.Limit.Take(refilled[i])
+		if w > wait {
+			key, wait = k.Key, w
+		}
+	}
+	if wait > 0 {
+		remaining = refilled
+	}
+	for i, k := range buckets {
+		s.buckets[k.Key] = &bucket{tokens: remaining[i], updated: now, full: k.Limit.Full()}
+	}
+	return key, wait, nil
+}
+
+// sweep drops the buckets which have been refilled since they were last used, they are recreated full.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.full {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/ugurcancaykara/odd-service/pkg/metrics"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
+	"github.com/ugurcancaykara/odd-service/rating/internal/ratelimit"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Store defines a MySQL-based token bucket store shared by all instances of the rating service.
+type Store struct {
+	db *sql.DB
+}
+
+// New creates a new MySQL-based token bucket store connecting to a data source name, which must enable parseTime.
+func New(dsn string) (*Store, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db}, nil
+}
+
+// Take takes a token from each of the buckets if all of them have one. Otherwise no token is taken
+// and it returns the key of the bucket which takes the longest to have a token again and how long that is.
+// The bucket rows are locked while they are updated, so concurrent takes from several instances are serialized.
+func (s *Store) Take(ctx context.Context, buckets []ratelimit.Bucket, now time.Time) (_ string, _ time.Duration, err error) {
+	defer metrics.TimeQuery("ratelimit", "take")()
+	ctx, done := tracing.Trace(ctx, "ratelimit repository take", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	defer tx.Rollback()
+	keys := make([]string, 0, len(buckets))
+	args := make([]any, 0, len(buckets))
+	for _, b := range buckets {
+		keys = append(keys, "?")
+		args = append(args, b.Key)
+	}
+	// The rows are locked in key order, so that takes of overlapping buckets don't deadlock.
+	rows, err := tx.QueryContext(ctx, "SELECT bucket_key, tokens, updated_at FROM rate_limits WHERE bucket_key IN ("+
+		strings.Join(keys, ", ")+") ORDER BY bucket_key FOR UPDATE", args...)
+	if err != nil {
+		return "", 0, err
+	}
+	type state struct {
+		tokens  float64
+		updated time.Time
+	}
+	stored := map[string]state{}
+	for rows.Next() {
+		var key string
+		var st state
+		if err := rows.Scan(&key, &st.tokens, &st.updated); err != nil {
+			rows.Close()
+			return "", 0, err
+		}
+		stored[key] = st
+	}
+	if err := rows.Close(); err != nil {
+		return "", 0, err
+	}
+	if err := rows.Err(); err != nil {
+		return "", 0, err
+	}
+
+	values := make([]string, 0, len(buckets))
+	args = make([]any, 0, 3*len(buckets))
+	var limitedKey string
+	var wait time.Duration
+	for _, b := range buckets {
+		tokens := b.Limit.Capacity()
+		if st, ok := stored[b.Key]; ok {
+			tokens = b.Limit.Refill(st.tokens, now.Sub(st.updated))
+		}
+		tokens, w := b.Limit.Take(tokens)
+		if w > wait {
+			limitedKey, wait = b.Key, w
+		}
+		values = append(values, "(?, ?, ?)")
+		args = append(args, b.Key, tokens, now)
+	}
+	if wait > 0 {
+		return limitedKey, wait, nil
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO rate_limits (bucket_key, tokens, updated_at) VALUES "+strings.Join(values, ", ")+
+		" ON DUPLICATE KEY UPDATE tokens = VALUES(tokens), updated_at = VALUES(updated_at)", args...)
+	if err != nil {
+		return "", 0, err
+	}
+	return "", 0, tx.Commit()
+}
@@ -0,0 +1,95 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/rating/internal/ratelimit"
+)
+
+const lockQuery = "SELECT bucket_key, tokens, updated_at FROM rate_limits WHERE bucket_key IN (?, ?) ORDER BY bucket_key FOR UPDATE"
+
+func TestStore_Take(t *testing.T) {
+	now := time.Date(2024, time.January, 8, 12, 0, 0, 0, time.UTC)
+	buckets := []ratelimit.Bucket{
+		{Key: "provider:imdb", Limit: ratelimit.Limit{Rate: 10, Burst: 10}},
+		{Key: "user:user1", Limit: ratelimit.Limit{Rate: 1, Burst: 2}},
+	}
+	columns := []string{"bucket_key", "tokens", "updated_at"}
+	tests := []struct {
+		name       string
+		rows       *sqlmock.Rows
+		err        error
+		wantTokens []float64
+		wantKey    string
+		wantWait   time.Duration
+		wantErr    error
+	}{
+		{
+			name:       "new buckets",
+			rows:       sqlmock.NewRows(columns),
+			wantTokens: []float64{9, 1},
+		},
+		{
+			name: "refilled buckets",
+			rows: sqlmock.NewRows(columns).
+				AddRow("provider:imdb", 0, now.Add(-200*time.Millisecond)).
+				AddRow("user:user1", 0.5, now.Add(-time.Second)),
+			wantTokens: []float64{1, 0.5},
+		},
+		{
+			name: "empty user bucket",
+			rows: sqlmock.NewRows(columns).
+				AddRow("provider:imdb", 10, now).
+				AddRow("user:user1", 0.5, now),
+			wantKey:  "user:user1",
+			wantWait: 500 * time.Millisecond,
+		},
+		{
+			name:    "query error",
+			err:     errors.New("connection refused"),
+			wantErr: errors.New("connection refused"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			s := &Store{db: db}
+
+			mock.ExpectBegin()
+			query := mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WithArgs("provider:imdb", "user:user1")
+			if tt.err != nil {
+				query.WillReturnError(tt.err)
+			} else {
+				query.WillReturnRows(tt.rows)
+			}
+			if tt.wantTokens != nil {
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO rate_limits (bucket_key, tokens, updated_at) VALUES (?, ?, ?), (?, ?, ?)")).
+					WithArgs("provider:imdb", tt.wantTokens[0], now, "user:user1", tt.wantTokens[1], now).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			} else {
+				// No token is taken from any bucket if one of them is empty.
+				mock.ExpectRollback()
+			}
+
+			key, wait, err := s.Take(context.Background(), buckets, now)
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantKey, key)
+				assert.Equal(t, tt.wantWait, wait)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
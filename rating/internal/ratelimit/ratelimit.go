@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// ErrRateLimited is matched by errors returned when a rate limit is exceeded.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// LimitedError is returned when a rate limit is exceeded.
+type LimitedError struct {
+	// Key defines the exhausted bucket.
+	Key string
+	// RetryAfter defines how long to wait until the bucket has a token again.
+	RetryAfter time.Duration
+}
+
+func (e *LimitedError) Error() string {
+	return fmt.Sprintf("%v for %s, retry after %v", ErrRateLimited, e.Key, e.RetryAfter)
+}
+
+// Is reports whether target is ErrRateLimited.
+func (e *LimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// Limit defines a token bucket refilled with Rate tokens per second up to Burst tokens.
+// A zero rate disables limiting.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Config defines the rate limits of rating writes per user and per provider.
+// Providers missing from Providers are limited with Provider. Ingested ratings are limited per user
+// with IngestedUser instead of User, so that backfills are only bounded by their provider.
+type Config struct {
+	User         Limit
+	IngestedUser Limit
+	Provider     Limit
+	Providers    map[string]Limit
+}
+
+// Bucket defines a token bucket identified by a key.
+type Bucket struct {
+	Key   string
+	Limit Limit
+}
+
+// Store defines a store of token buckets.
+type Store interface {
+	// Take takes a token from each of the buckets if all of them have one. Otherwise no token is taken
+	// and it returns the key of the bucket which takes the longest to have a token again and how long that is.
+	Take(ctx context.Context, buckets []Bucket, now time.Time) (string, time.Duration, error)
+}
+
+// Limiter defines a rate limiter of rating writes keyed by user id and provider id.
+type Limiter struct {
+	cfg   atomic.Pointer[Config]
+	store Store
+	now   func() time.Time
+}
+
+// New creates a new rate limiter keeping its buckets in store.
+func New(cfg Config, store Store) *Limiter {
+	l := &Limiter{store: store, now: time.Now}
+	l.SetConfig(cfg)
+	return l
+}
+
+// SetConfig replaces the rate limits, the buckets keep their tokens.
+func (l *Limiter) SetConfig(cfg Config) {
+	l.cfg.Store(&cfg)
+}
+
+// Allow takes a token from the buckets of a user and a provider, empty ids are not limited.
+// It returns a *LimitedError if either bucket is empty, in which case no token is taken.
+func (l *Limiter) Allow(ctx context.Context, userID string, providerID string) error {
+	cfg := l.cfg.Load()
+	return l.take(ctx, cfg, Bucket{Key: "user:" + userID, Limit: cfg.User}, userID, providerID)
+}
+
+// AllowIngested is like Allow for an ingested rating, the user is limited with its own bucket of the ingested user limit.
+func (l *Limiter) AllowIngested(ctx context.Context, userID string, providerID string) error {
+	cfg := l.cfg.Load()
+	return l.take(ctx, cfg, Bucket{Key: "ingested-user:" + userID, Limit: cfg.IngestedUser}, userID, providerID)
+}
+
+func (l *Limiter) take(ctx context.Context, cfg *Config, user Bucket, userID string, providerID string) error {
+	buckets := make([]Bucket, 0, 2)
+	if providerID != "" {
+		limit, ok := cfg.Providers[providerID]
+		if !ok {
+			limit = cfg.Provider
+		}
+		if limit.Rate > 0 {
+			buckets = append(buckets, Bucket{Key: "provider:" + providerID, Limit: limit})
+		}
+	}
+	if userID != "" && user.Limit.Rate > 0 {
+		buckets = append(buckets, user)
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+	key, wait, err := l.store.Take(ctx, buckets, l.now())
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return &LimitedError{Key: key, RetryAfter: wait}
+	}
+	return nil
+}
+
+// Capacity returns the number of tokens of a full bucket, a bucket holds at least one token.
+func (l Limit) Capacity() float64 {
+	return float64(max(l.Burst, 1))
+}
+
+// Full returns how long it takes to refill an empty bucket.
+func (l Limit) Full() time.Duration {
+	return time.Duration(l.Capacity() / l.Rate * float64(time.Second))
+}
+
+// Refill returns the tokens of a bucket holding tokens after elapsed.
+func (l Limit) Refill(tokens float64, elapsed time.Duration) float64 {
+	return math.Min(l.Capacity(), tokens+elapsed.Seconds()*l.Rate)
+}
+
+// Take takes a token from a bucket holding tokens. It returns the remaining tokens or,
+// if the bucket is empty, the unchanged tokens and how long it takes to refill a token.
+func (l Limit) Take(tokens float64) (float64, time.Duration) {
+	if tokens >= 1 {
+		return tokens - 1, 0
+	}
+	return tokens, time.Duration(math.Ceil((1 - tokens) / l.Rate * float64(time.Second)))
+}
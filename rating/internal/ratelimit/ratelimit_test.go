@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2024, time.January, 8, 12, 0, 0, 0, time.UTC)
+	l := New(Config{
+		User:      Limit{Rate: 1, Burst: 2},
+		Provider:  Limit{Rate: 10, Burst: 10},
+		Providers: map[string]Limit{"small": {Rate: 0.5, Burst: 1}},
+	}, NewMemoryStore())
+	l.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		after      time.Duration
+		userID     string
+		providerID string
+		retryAfter time.Duration
+		key        string
+	}{
+		{name: "First of burst", userID: "user1"},
+		{name: "Second of burst", userID: "user1"},
+		{name: "Empty user bucket", userID: "user1", retryAfter: time.Second},
+		{name: "Other user", userID: "user2"},
+		{name: "Refilled", after: 500 * time.Millisecond, userID: "user1", retryAfter: 500 * time.Millisecond},
+		{name: "Refilled token", after: 500 * time.Millisecond, userID: "user1"},
+		{name: "Empty user bucket of provider", userID: "user1", providerID: "small", retryAfter: time.Second, key: "user:user1"},
+		{name: "Provider override", userID: "user3", providerID: "small"},
+		{name: "Empty provider bucket", userID: "user4", providerID: "small", retryAfter: 2 * time.Second, key: "provider:small"},
+		{name: "Default provider limit", userID: "user4", providerID: "large"},
+		{name: "No user", providerID: "large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.after)
+			err := l.Allow(ctx, tt.userID, tt.providerID)
+			if tt.retryAfter == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrRateLimited)
+			var limited *LimitedError
+			require.ErrorAs(t, err, &limited)
+			assert.Equal(t, tt.retryAfter, limited.RetryAfter)
+			if tt.key != "" {
+				assert.Equal(t, tt.key, limited.Key)
+			}
+		})
+	}
+}
+
+func TestLimiter_AllowIngested(t *testing.T) {
+	l := New(Config{User: Limit{Rate: 1, Burst: 1}, Provider: Limit{Rate: 1, Burst: 2}}, NewMemoryStore())
+	ctx := context.Background()
+	assert.NoError(t, l.AllowIngested(ctx, "user1", "imdb"))
+	assert.NoError(t, l.AllowIngested(ctx, "user1", "imdb"), "ingested ratings aren't limited per user by default")
+	assert.ErrorIs(t, l.AllowIngested(ctx, "user1", "imdb"), ErrRateLimited)
+
+	l.SetConfig(Config{User: Limit{Rate: 1, Burst: 5}, IngestedUser: Limit{Rate: 1, Burst: 1}})
+	assert.NoError(t, l.AllowIngested(ctx, "user2", ""))
+	assert.ErrorIs(t, l.AllowIngested(ctx, "user2", ""), ErrRateLimited)
+	assert.NoError(t, l.Allow(ctx, "user2", ""), "writes through the API have their own user limit")
+}
+
+func TestLimiter_SetConfig(t *testing.T) {
+	l := New(Config{User: Limit{Rate: 1, Burst: 1}}, NewMemoryStore())
+	ctx := context.Background()
+	assert.NoError(t, l.Allow(ctx, "user1", ""))
+	assert.ErrorIs(t, l.Allow(ctx, "user1", ""), ErrRateLimited)
+
+	l.SetConfig(Config{})
+	assert.NoError(t, l.Allow(ctx, "user1", ""), "a zero rate disables the limit")
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	now := time.Date(2024, time.January, 8, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	limit := Limit{Rate: 1, Burst: 5}
+	take := func(key string, now time.Time) {
+		_, _, err := s.Take(context.Background(), []Bucket{{Key: key, Limit: limit}}, now)
+		assert.NoError(t, err)
+	}
+	take("a", now)
+	take("b", now)
+	take("a", now.Add(sweepInterval-time.Second))
+	take("c", now.Add(sweepInterval))
+	assert.Len(t, s.buckets, 2)
+	assert.NotContains(t, s.buckets, "b")
+}
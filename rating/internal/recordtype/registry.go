@@ -0,0 +1,80 @@
+package recordtype
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// ErrUnknownRecordType is returned when a record type is not registered.
+var ErrUnknownRecordType = errors.New("unknown record type")
+
+// ErrValueOutOfScale is returned when a rating value is outside the scale of its record type.
+var ErrValueOutOfScale = errors.New("rating value out of scale")
+
+// Scales lists the predefined rating scales by name.
+var Scales = map[string]model.RatingScale{
+	model.RatingScaleStars5.Name:   model.RatingScaleStars5,
+	model.RatingScalePoints10.Name: model.RatingScalePoints10,
+	model.RatingScaleThumbs.Name:   model.RatingScaleThumbs,
+}
+
+// Registry defines the rateable record types and their rating scales.
+type Registry struct {
+	scales map[model.RecordType]model.RatingScale
+}
+
+// Default registers movies rated with 1 to 5 stars.
+var Default = &Registry{scales: map[model.RecordType]model.RatingScale{
+	model.RecordTypeMovie: model.RatingScaleStars5,
+}}
+
+// New creates a new registry of record types with their rating scales.
+func New(scales map[model.RecordType]model.RatingScale) (*Registry, error) {
+	r := &Registry{scales: map[model.RecordType]model.RatingScale{}}
+	for t, s := range scales {
+		if t == "" {
+			return nil, errors.New("empty record type")
+		}
+		if s.Min >= s.Max {
+			return nil, fmt.Errorf("invalid rating scale %q of record type %q: min %d not below max %d", s.Name, t, s.Min, s.Max)
+		}
+		r.scales[t] = s
+	}
+	return r, nil
+}
+
+// Scale returns the rating scale of a record type.
+func (r *Registry) Scale(t model.RecordType) (model.RatingScale, error) {
+	s, ok := r.scales[t]
+	if !ok {
+		return model.RatingScale{}, fmt.Errorf("%w %q", ErrUnknownRecordType, t)
+	}
+	return s, nil
+}
+
+// Validate checks that a record type is registered and a rating value is within its scale.
+func (r *Registry) Validate(t model.RecordType, v model.RatingValue) error {
+	s, err := r.Scale(t)
+	if err != nil {
+		return err
+	}
+	if !s.Contains(v) {
+		return fmt.Errorf("%w: rating value %d out of range [%d, %d] of %q", ErrValueOutOfScale, v, s.Min, s.Max, t)
+	}
+	return nil
+}
+
+// Types returns the registered record types in alphabetical order.
+func (r *Registry) Types() []model.RecordType {
+	res := make([]model.RecordType, 0, len(r.scales))
+	for t := range r.scales {
+		res = append(res, t)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i] < res[j]
+	})
+	return res
+}
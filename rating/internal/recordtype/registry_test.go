@@ -0,0 +1,82 @@
+package recordtype
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		scales  map[model.RecordType]model.RatingScale
+		wantErr bool
+	}{
+		{name: "predefined scales", scales: map[model.RecordType]model.RatingScale{"movie": model.RatingScaleStars5, "clip": model.RatingScaleThumbs}},
+		{name: "custom scale", scales: map[model.RecordType]model.RatingScale{"book": {Name: "book", Min: -2, Max: 2}}},
+		{name: "empty record type", scales: map[model.RecordType]model.RatingScale{"": model.RatingScaleStars5}, wantErr: true},
+		{name: "min equal to max", scales: map[model.RecordType]model.RatingScale{"book": {Name: "book", Min: 3, Max: 3}}, wantErr: true},
+		{name: "min above max", scales: map[model.RecordType]model.RatingScale{"book": {Name: "book", Min: 5, Max: 1}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.scales)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRegistry_Validate(t *testing.T) {
+	r, err := New(map[model.RecordType]model.RatingScale{
+		model.RecordTypeMovie:  model.RatingScaleStars5,
+		model.RecordTypeSeries: model.RatingScalePoints10,
+		"clip":                 model.RatingScaleThumbs,
+	})
+	require.NoError(t, err)
+	tests := []struct {
+		name       string
+		recordType model.RecordType
+		value      model.RatingValue
+		wantErr    error
+	}{
+		{name: "lowest value", recordType: model.RecordTypeMovie, value: 1},
+		{name: "highest value", recordType: model.RecordTypeMovie, value: 5},
+		{name: "below scale", recordType: model.RecordTypeMovie, value: 0, wantErr: ErrValueOutOfScale},
+		{name: "above scale", recordType: model.RecordTypeMovie, value: 6, wantErr: ErrValueOutOfScale},
+		{name: "negative value", recordType: model.RecordTypeSeries, value: -1, wantErr: ErrValueOutOfScale},
+		{name: "points", recordType: model.RecordTypeSeries, value: 10},
+		{name: "thumbs down", recordType: "clip", value: 0},
+		{name: "thumbs up", recordType: "clip", value: 1},
+		{name: "thumbs out of scale", recordType: "clip", value: 2, wantErr: ErrValueOutOfScale},
+		{name: "unknown record type", recordType: "book", value: 3, wantErr: ErrUnknownRecordType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, r.Validate(tt.recordType, tt.value), tt.wantErr)
+		})
+	}
+}
+
+func TestRegistry_Scale(t *testing.T) {
+	scale, err := Default.Scale(model.RecordTypeMovie)
+	require.NoError(t, err)
+	assert.Equal(t, model.RatingScaleStars5, scale, "movies are rated with stars by default")
+	_, err = Default.Scale(model.RecordTypeSeries)
+	assert.ErrorIs(t, err, ErrUnknownRecordType)
+}
+
+func TestRegistry_Types(t *testing.T) {
+	r, err := New(map[model.RecordType]model.RatingScale{
+		model.RecordTypeSeries:  model.RatingScalePoints10,
+		model.RecordTypeMovie:   model.RatingScaleStars5,
+		model.RecordTypeEpisode: model.RatingScaleStars5,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []model.RecordType{model.RecordTypeEpisode, model.RecordTypeMovie, model.RecordTypeSeries}, r.Types())
+}
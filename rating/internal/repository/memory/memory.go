@@ -2,6 +2,8 @@ package memory
 
 import (
 	"context"
+	"sort"
+	"sync"
 
 	"github.com/ugurcancaykara/odd-service/rating/internal/repository"
 	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
@@ -9,18 +11,32 @@ import (
 
 // Repository defines a rating repository
 type Repository struct {
+	sync.RWMutex
 	data map[model.RecordType]map[model.RecordID][]model.Rating
+	// byUser indexes the records rated by each user.
+	byUser map[model.UserID]map[recordKey]struct{}
+	// quarantine holds suspicious ratings awaiting moderation.
+	quarantine map[quarantineKey]model.QuarantinedRating
+}
+
+type recordKey struct {
+	recordType model.RecordType
+	recordID   model.RecordID
 }
 
 // New creates a new memory repository.
 func New() *Repository {
 	return &Repository{
-		map[model.RecordType]map[model.RecordID][]model.Rating{},
+		data:       map[model.RecordType]map[model.RecordID][]model.Rating{},
+		byUser:     map[model.UserID]map[recordKey]struct{}{},
+		quarantine: map[quarantineKey]model.QuarantinedRating{},
 	}
 }
 
 // Get retrieves all rating for a given record
 func (r *Repository) Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
+	r.RLock()
+	defer r.RUnlock()
 	if _, ok := r.data[recordType]; !ok {
 		return nil, repository.ErrNotFound
 	}
@@ -28,15 +44,266 @@ func (r *Repository) Get(ctx context.Context, recordID model.RecordID, recordTyp
 		return nil, repository.ErrNotFound
 	}
 
-	return r.data[recordType][recordID], nil
+	return append([]model.Rating(nil), r.data[recordType][recordID]...), nil
+
+}
 
+// Put adds a rating for a given record, replacing an earlier rating of the same user.
+// It returns the replaced rating, nil if there was none.
+func (r *Repository) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) (*model.Rating, error) {
+	r.Lock()
+	defer r.Unlock()
+	return r.put(recordID, recordType, rating), nil
+}
+
+// PutBatch adds or replaces multiple ratings, each rating carrying its record id and type.
+// It returns the replaced rating of each rating, nil if there was none.
+func (r *Repository) PutBatch(ctx context.Context, ratings []model.Rating) ([]*model.Rating, error) {
+	r.Lock()
+	defer r.Unlock()
+	res := make([]*model.Rating, len(ratings))
+	for i := range ratings {
+		res[i] = r.put(model.RecordID(ratings[i].RecordID), model.RecordType(ratings[i].RecordType), &ratings[i])
+	}
+	return res, nil
 }
 
-// Put adds a rating for a given record.
-func (r *Repository) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
+func (r *Repository) put(recordID model.RecordID, recordType model.RecordType, rating *model.Rating) *model.Rating {
 	if _, ok := r.data[recordType]; !ok {
 		r.data[recordType] = map[model.RecordID][]model.Rating{}
 	}
-	r.data[recordType][recordID] = append(r.data[recordType][recordID], *rating)
+	ratings := r.data[recordType][recordID]
+	for i := range ratings {
+		if ratings[i].UserID != rating.UserID {
+			continue
+		}
+		// The creation time of the first rating is kept when a user updates it.
+		old := ratings[i]
+		ratings[i] = *rating
+		if !old.CreatedAt.IsZero() {
+			ratings[i].CreatedAt = old.CreatedAt
+		}
+		return &old
+	}
+	r.data[recordType][recordID] = append(ratings, *rating)
+	if _, ok := r.byUser[rating.UserID]; !ok {
+		r.byUser[rating.UserID] = map[recordKey]struct{}{}
+	}
+	r.byUser[rating.UserID][recordKey{recordType, recordID}] = struct{}{}
+	return nil
+}
+
+func (r *Repository) unindex(userID model.UserID, recordID model.RecordID, recordType model.RecordType) {
+	delete(r.byUser[userID], recordKey{recordType, recordID})
+	if len(r.byUser[userID]) == 0 {
+		delete(r.byUser, userID)
+	}
+}
+
+// Delete removes the rating of a user for a given record and returns it, nil if there was none.
+func (r *Repository) Delete(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) (*model.Rating, error) {
+	r.Lock()
+	defer r.Unlock()
+	ratings := r.data[recordType][recordID]
+	var deleted *model.Rating
+	res := ratings[:0]
+	for _, rating := range ratings {
+		if rating.UserID == userID {
+			rating := rating
+			deleted = &rating
+			continue
+		}
+		res = append(res, rating)
+	}
+	if deleted == nil {
+		return nil, nil
+	}
+	r.data[recordType][recordID] = res
+	r.unindex(userID, recordID, recordType)
+	return deleted, nil
+}
+
+// GetUserRating retrieves the rating of a user for a given record.
+func (r *Repository) GetUserRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) (*model.Rating, error) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, rating := range r.data[recordType][recordID] {
+		if rating.UserID == userID {
+			rating.RecordID = string(recordID)
+			rating.RecordType = string(recordType)
+			return &rating, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// ListUserRatings retrieves up to pageSize ratings of a user ordered by record type and id,
+// starting after the record encoded in pageToken. It returns the token of the next page, which is empty on the last page.
+func (r *Repository) ListUserRatings(ctx context.Context, userID model.UserID, pageToken string, pageSize int) ([]model.Rating, string, error) {
+	var after recordKey
+	if pageToken != "" {
+		recordType, recordID, err := repository.DecodePageToken(pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		after = recordKey{recordType, recordID}
+	}
+	r.RLock()
+	defer r.RUnlock()
+	keys := make([]recordKey, 0, len(r.byUser[userID]))
+	for key := range r.byUser[userID] {
+		if pageToken == "" || after.less(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].less(keys[j])
+	})
+	next := ""
+	if len(keys) > pageSize {
+		keys = keys[:pageSize]
+		last := keys[len(keys)-1]
+		next = repository.EncodePageToken(last.recordType, last.recordID)
+	}
+	res := make([]model.Rating, 0, len(keys))
+	for _, key := range keys {
+		for _, rating := range r.data[key.recordType][key.recordID] {
+			if rating.UserID == userID {
+				rating.RecordID = string(key.recordID)
+				rating.RecordType = string(key.recordType)
+				res = append(res, rating)
+			}
+		}
+	}
+	return res, next, nil
+}
+
+func (k recordKey) less(o recordKey) bool {
+	if k.recordType != o.recordType {
+		return k.recordType < o.recordType
+	}
+	return k.recordID < o.recordID
+}
+
+// ListByProvider retrieves up to pageSize ratings received from a given provider ordered by record type, record id
+// and user id, starting after the rating encoded in pageToken. It returns the token of the next page, which is empty on the last page.
+func (r *Repository) ListByProvider(ctx context.Context, providerID string, pageToken string, pageSize int) ([]model.Rating, string, error) {
+	var after model.Rating
+	if pageToken != "" {
+		recordType, recordID, userID, err := repository.DecodeProviderPageToken(pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		after = model.Rating{RecordType: string(recordType), RecordID: string(recordID), UserID: userID}
+	}
+	r.RLock()
+	var res []model.Rating
+	for recordType, records := range r.data {
+		for recordID, ratings := range records {
+			for _, rating := range ratings {
+				if rating.ProviderID != providerID {
+					continue
+				}
+				rating.RecordID = string(recordID)
+				rating.RecordType = string(recordType)
+				if pageToken == "" || ratingLess(after, rating) {
+					res = append(res, rating)
+				}
+			}
+		}
+	}
+	r.RUnlock()
+	sort.Slice(res, func(i, j int) bool {
+		return ratingLess(res[i], res[j])
+	})
+	next := ""
+	if len(res) > pageSize {
+		res = res[:pageSize]
+		last := res[len(res)-1]
+		next = repository.EncodeProviderPageToken(model.RecordType(last.RecordType), model.RecordID(last.RecordID), last.UserID)
+	}
+	return res, next, nil
+}
+
+// ratingLess orders ratings by record type, record id and user id.
+func ratingLess(a model.Rating, b model.Rating) bool {
+	if a.RecordType != b.RecordType {
+		return a.RecordType < b.RecordType
+	}
+	if a.RecordID != b.RecordID {
+		return a.RecordID < b.RecordID
+	}
+	return a.UserID < b.UserID
+}
+
+// DeleteByProvider removes all ratings received from a given provider and returns how many were removed.
+func (r *Repository) DeleteByProvider(ctx context.Context, providerID string) (int64, error) {
+	r.Lock()
+	defer r.Unlock()
+	var deleted int64
+	for recordType, records := range r.data {
+		for recordID, ratings := range records {
+			res := ratings[:0]
+			for _, rating := range ratings {
+				if rating.ProviderID == providerID {
+					r.unindex(rating.UserID, recordID, recordType)
+					deleted++
+					continue
+				}
+				res = append(res, rating)
+			}
+			records[recordID] = res
+		}
+	}
+	return deleted, nil
+}
+
+// ForEach calls fn for every stored rating, each rating carrying its record id and type.
+// Iteration stops at the first error returned by fn.
+func (r *Repository) ForEach(ctx context.Context, fn func(model.Rating) error) error {
+	r.RLock()
+	defer r.RUnlock()
+	for recordType, records := range r.data {
+		for recordID, ratings := range records {
+			for _, rating := range ratings {
+				rating.RecordID = string(recordID)
+				rating.RecordType = string(recordType)
+				if err := fn(rating); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Export calls fn for every rating matching a filter ordered by record id, record type and user id,
+// each rating carrying its record id and type. The matching ratings are copied before fn is called,
+// so the export is a snapshot unaffected by concurrent writes.
+func (r *Repository) Export(ctx context.Context, filter model.ExportFilter, fn func(model.Rating) error) error {
+	var res []model.Rating
+	err := r.ForEach(ctx, func(rating model.Rating) error {
+		if filter.Matches(&rating) {
+			res = append(res, rating)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].RecordID != res[j].RecordID {
+			return res[i].RecordID < res[j].RecordID
+		}
+		if res[i].RecordType != res[j].RecordType {
+			return res[i].RecordType < res[j].RecordType
+		}
+		return res[i].UserID < res[j].UserID
+	})
+	for _, rating := range res {
+		if err := fn(rating); err != nil {
+			return err
+		}
+	}
 	return nil
 }
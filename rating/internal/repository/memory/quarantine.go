@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ugurcancaykara/odd-service/rating/internal/repository"
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+type quarantineKey struct {
+	recordKey
+	userID model.UserID
+}
+
+func (k quarantineKey) less(o quarantineKey) bool {
+	if k.recordKey != o.recordKey {
+		return k.recordKey.less(o.recordKey)
+	}
+	return k.userID < o.userID
+}
+
+// PutQuarantined stores a quarantined rating, replacing an earlier quarantined rating of the same user for the record.
+func (r *Repository) PutQuarantined(ctx context.Context, q *model.QuarantinedRating) error {
+	r.Lock()
+	defer r.Unlock()
+	key := quarantineKey{recordKey{model.RecordType(q.Rating.RecordType), model.RecordID(q.Rating.RecordID)}, q.Rating.UserID}
+	r.quarantine[key] = *q
+	return nil
+}
+
+// GetQuarantined retrieves the quarantined rating of a user for a given record.
+func (r *Repository) GetQuarantined(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) (*model.QuarantinedRating, error) {
+	r.RLock()
+	defer r.RUnlock()
+	q, ok := r.quarantine[quarantineKey{recordKey{recordType, recordID}, userID}]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &q, nil
+}
+
+// ListQuarantined retrieves up to pageSize quarantined ratings ordered by record type, record id and user id,
+// starting after the rating encoded in pageToken. It returns the token of the next page, which is empty on the last page.
+func (r *Repository) ListQuarantined(ctx context.Context, pageToken string, pageSize int) ([]model.QuarantinedRating, string, error) {
+	var after quarantineKey
+	if pageToken != "" {
+		recordType, recordID, userID, err := repository.DecodeQuarantinePageToken(pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		after = quarantineKey{recordKey{recordType, recordID}, userID}
+	}
+	r.RLock()
+	defer r.RUnlock()
+	keys := make([]quarantineKey, 0, len(r.quarantine))
+	for key := range r.quarantine {
+		if pageToken == "" || after.less(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].less(keys[j])
+	})
+	next := ""
+	if len(keys) > pageSize {
+		keys = keys[:pageSize]
+		last := keys[len(keys)-1]
+		next = repository.EncodeQuarantinePageToken(last.recordType, last.recordID, last.userID)
+	}
+	res := make([]model.QuarantinedRating, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, r.quarantine[key])
+	}
+	return res, next, nil
+}
+
+// DeleteQuarantined removes the quarantined rating of a user for a given record.
+func (r *Repository) DeleteQuarantined(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.quarantine, quarantineKey{recordKey{recordType, recordID}, userID})
+	return nil
+}
@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/rating/internal/repository"
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+func quarantined(recordID string, userID model.UserID, value model.RatingValue) *model.QuarantinedRating {
+	return &model.QuarantinedRating{
+		Rating:        model.Rating{RecordID: recordID, RecordType: string(model.RecordTypeMovie), UserID: userID, Value: value},
+		Reasons:       []model.AnomalyReason{model.AnomalyReasonBurst},
+		QuarantinedAt: time.Date(2024, time.January, 8, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestRepository_Quarantine(t *testing.T) {
+	ctx := context.Background()
+	r := New()
+	for _, q := range []*model.QuarantinedRating{
+		quarantined("2", "user1", 1),
+		quarantined("1", "user2", 5),
+		quarantined("1", "user1", 5),
+		quarantined("2", "user1", 2),
+	} {
+		require.NoError(t, r.PutQuarantined(ctx, q))
+	}
+
+	got, err := r.GetQuarantined(ctx, "2", model.RecordTypeMovie, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, quarantined("2", "user1", 2), got, "a quarantined rating replaces an earlier one of the user")
+	_, err = r.GetQuarantined(ctx, "2", model.RecordTypeMovie, "user2")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	var keys []string
+	token := ""
+	for {
+		page, next, err := r.ListQuarantined(ctx, token, 2)
+		require.NoError(t, err)
+		for _, q := range page {
+			keys = append(keys, q.Rating.RecordID+"/"+string(q.Rating.UserID))
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	assert.Equal(t, []string{"1/user1", "1/user2", "2/user1"}, keys)
+	_, _, err = r.ListQuarantined(ctx, "!", 2)
+	assert.ErrorIs(t, err, repository.ErrInvalidPageToken)
+
+	require.NoError(t, r.DeleteQuarantined(ctx, "1", model.RecordTypeMovie, "user1"))
+	require.NoError(t, r.DeleteQuarantined(ctx, "1", model.RecordTypeMovie, "user1"), "deleting a missing rating is a no-op")
+	_, err = r.GetQuarantined(ctx, "1", model.RecordTypeMovie, "user1")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	page, next, err := r.ListQuarantined(ctx, "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, next)
+	assert.Len(t, page, 2)
+
+	ratings, err := r.Get(ctx, "1", model.RecordTypeMovie)
+	assert.ErrorIs(t, err, repository.ErrNotFound, "quarantined ratings aren't stored as ratings")
+	assert.Empty(t, ratings)
+}
@@ -3,56 +3,402 @@ package mysql
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/ugurcancaykara/odd-service/pkg/logging"
+	"github.com/ugurcancaykara/odd-service/pkg/metrics"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
 	"github.com/ugurcancaykara/odd-service/rating/internal/repository"
 	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Repository defines a MySQL-based rating repository.
 type Repository struct {
-	db *sql.DB
+	db     *sql.DB
+	logger *slog.Logger
 }
 
-// New creates a new MySQL-based rating repository.
-func New() (*Repository, error) {
-	db, err := sql.Open("mysql", "root:password@/movie")
-	fmt.Println("successfully created sql.Open at repository level")
+// New creates a new MySQL-based rating repository connecting to a data source name,
+// which must enable parseTime, e.g. root:password@/movie?parseTime=true.
+// A nil logger logs to slog.Default().
+func New(dsn string, logger *slog.Logger) (*Repository, error) {
+	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		return nil, err
 	}
-	return &Repository{db}, nil
+	logger = logging.OrDefault(logger)
+	logger.Debug("Opened MySQL rating repository")
+	return &Repository{db: db, logger: logger}, nil
+}
+
+// Ping checks that the database is reachable.
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
 }
 
 // Get retrieves all ratings for a given record.
-func (r *Repository) Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
-	rows, err := r.db.QueryContext(ctx, "SELECT user_id, value FROM ratings WHERE record_id = ? AND record_type = ?", recordID, recordType)
+func (r *Repository) Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (_ []model.Rating, err error) {
+	defer metrics.TimeQuery("rating", "get")()
+	ctx, done := tracing.Trace(ctx, "rating repository get", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	res, err := r.query(ctx, "WHERE record_id = ? AND record_type = ?", recordID, recordType)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	if len(res) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return res, nil
+}
+
+// GetUserRating retrieves the rating of a user for a given record.
+func (r *Repository) GetUserRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) (_ *model.Rating, err error) {
+	defer metrics.TimeQuery("rating", "get_user_rating")()
+	ctx, done := tracing.Trace(ctx, "rating repository get_user_rating", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	res, err := r.query(ctx, "WHERE record_id = ? AND record_type = ? AND user_id = ?", recordID, recordType, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return &res[0], nil
+}
+
+// ListUserRatings retrieves up to pageSize ratings of a user ordered by record type and id,
+// starting after the record encoded in pageToken. It returns the token of the next page, which is empty on the last page.
+func (r *Repository) ListUserRatings(ctx context.Context, userID model.UserID, pageToken string, pageSize int) (_ []model.Rating, _ string, err error) {
+	defer metrics.TimeQuery("rating", "list_user_ratings")()
+	ctx, done := tracing.Trace(ctx, "rating repository list_user_ratings", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	where := "WHERE user_id = ?"
+	args := []any{userID}
+	if pageToken != "" {
+		recordType, recordID, err := repository.DecodePageToken(pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		where += " AND (record_type, record_id) > (?, ?)"
+		args = append(args, recordType, recordID)
+	}
+	// One more rating than requested is queried to find out whether there is a next page.
+	where += " ORDER BY record_type, record_id LIMIT ?"
+	args = append(args, pageSize+1)
+	res, err := r.query(ctx, where, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(res) > pageSize {
+		res = res[:pageSize]
+		last := res[len(res)-1]
+		next = repository.EncodePageToken(model.RecordType(last.RecordType), model.RecordID(last.RecordID))
+	}
+	return res, next, nil
+}
+
+// ListByProvider retrieves up to pageSize ratings received from a given provider ordered by record type, record id
+// and user id, starting after the rating encoded in pageToken. It returns the token of the next page, which is empty on the last page.
+func (r *Repository) ListByProvider(ctx context.Context, providerID string, pageToken string, pageSize int) (_ []model.Rating, _ string, err error) {
+	defer metrics.TimeQuery("rating", "list_by_provider")()
+	ctx, done := tracing.Trace(ctx, "rating repository list_by_provider", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	where := "WHERE provider_id = ?"
+	args := []any{providerID}
+	if pageToken != "" {
+		recordType, recordID, userID, err := repository.DecodeProviderPageToken(pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		where += " AND (record_type, record_id, user_id) > (?, ?, ?)"
+		args = append(args, recordType, recordID, userID)
+	}
+	// One more rating than requested is queried to find out whether there is a next page.
+	where += " ORDER BY record_type, record_id, user_id LIMIT ?"
+	args = append(args, pageSize+1)
+	res, err := r.query(ctx, where, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(res) > pageSize {
+		res = res[:pageSize]
+		last := res[len(res)-1]
+		next = repository.EncodeProviderPageToken(model.RecordType(last.RecordType), model.RecordID(last.RecordID), last.UserID)
+	}
+	return res, next, nil
+}
+
+// ForEach calls fn for every stored rating, each rating carrying its record id and type.
+// Ratings are streamed from the database, iteration stops at the first error returned by fn.
+// Only the query is timed, not the calls of fn.
+func (r *Repository) ForEach(ctx context.Context, fn func(model.Rating) error) (err error) {
+	ctx, done := tracing.Trace(ctx, "rating repository for_each", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	observe := metrics.TimeQuery("rating", "for_each")
+	rows, err := r.db.QueryContext(ctx, selectRatings)
+	observe()
+	if err != nil {
+		return err
+	}
+	return scanRows(rows, fn)
+}
+
+func (r *Repository) query(ctx context.Context, where string, args ...any) ([]model.Rating, error) {
 	var res []model.Rating
+	err := r.scan(ctx, func(rating model.Rating) error {
+		res = append(res, rating)
+		return nil
+	}, where, args...)
+	return res, err
+}
+
+const selectRatings = "SELECT record_id, record_type, user_id, value, provider_id, ingested_at, created_at, updated_at FROM ratings "
+
+func (r *Repository) scan(ctx context.Context, fn func(model.Rating) error, where string, args ...any) error {
+	rows, err := r.db.QueryContext(ctx, selectRatings+where, args...)
+	if err != nil {
+		return err
+	}
+	return scanRows(rows, fn)
+}
+
+func scanRows(rows *sql.Rows, fn func(model.Rating) error) error {
+	defer rows.Close()
 	for rows.Next() {
-		var userID string
+		var recordID, recordType, userID, providerID string
 		var value int32
-		if err := rows.Scan(&userID, &value); err != nil {
-			return nil, err
+		var ingestedAt, createdAt, updatedAt sql.NullTime
+		if err := rows.Scan(&recordID, &recordType, &userID, &value, &providerID, &ingestedAt, &createdAt, &updatedAt); err != nil {
+			return err
 		}
-		res = append(res, model.Rating{
-			UserID: model.UserID(userID),
-			Value:  model.RatingValue(value),
+		err := fn(model.Rating{
+			RecordID:   recordID,
+			RecordType: recordType,
+			UserID:     model.UserID(userID),
+			Value:      model.RatingValue(value),
+			ProviderID: providerID,
+			IngestedAt: ingestedAt.Time,
+			CreatedAt:  createdAt.Time,
+			UpdatedAt:  updatedAt.Time,
 		})
+		if err != nil {
+			return err
+		}
 	}
-	if len(res) == 0 {
-		return nil, repository.ErrNotFound
+	return rows.Err()
+}
+
+// exportPageSize defines how many ratings an export reads per query.
+const exportPageSize = 1000
+
+// Export calls fn for every rating matching a filter ordered by record id, record type and user id,
+// each rating carrying its record id and type. Ratings are read in pages within a read-only repeatable read
+// transaction, so the export is a consistent snapshot of the table as of its first page. Only the query of each page
+// is timed, not the calls of fn.
+func (r *Repository) Export(ctx context.Context, filter model.ExportFilter, fn func(model.Rating) error) (err error) {
+	ctx, done := tracing.Trace(ctx, "rating repository export", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
 	}
-	return res, nil
+	defer r.rollback(ctx, tx)
+	var conds []string
+	var args []any
+	if filter.RecordType != "" {
+		conds = append(conds, "record_type = ?")
+		args = append(args, filter.RecordType)
+	}
+	if filter.ProviderID != "" {
+		conds = append(conds, "provider_id = ?")
+		args = append(args, filter.ProviderID)
+	}
+	if !filter.Window.From.IsZero() {
+		conds = append(conds, "updated_at >= ?")
+		args = append(args, filter.Window.From)
+	}
+	if !filter.Window.To.IsZero() {
+		conds = append(conds, "updated_at < ?")
+		args = append(args, filter.Window.To)
+	}
+	var last *model.Rating
+	for {
+		pageConds, pageArgs := conds, args
+		if last != nil {
+			pageConds = append(pageConds[:len(pageConds):len(pageConds)], "(record_id, record_type, user_id) > (?, ?, ?)")
+			pageArgs = append(pageArgs[:len(pageArgs):len(pageArgs)], last.RecordID, last.RecordType, last.UserID)
+		}
+		where := ""
+		if len(pageConds) > 0 {
+			where = "WHERE " + strings.Join(pageConds, " AND ")
+		}
+		observe := metrics.TimeQuery("rating", "export")
+		rows, err := tx.QueryContext(ctx, selectRatings+where+" ORDER BY record_id, record_type, user_id LIMIT ?", append(pageArgs, exportPageSize)...)
+		observe()
+		if err != nil {
+			return err
+		}
+		n := 0
+		err = scanRows(rows, func(rating model.Rating) error {
+			n++
+			last = &rating
+			return fn(rating)
+		})
+		if err != nil {
+			return err
+		}
+		if n < exportPageSize {
+			return tx.Commit()
+		}
+	}
+}
+
+// insertRatings inserts ratings, replacing earlier ratings of the same user for a record but keeping their creation time.
+const insertRatings = "INSERT INTO ratings (record_id, record_type, user_id, value, provider_id, ingested_at, created_at, updated_at) VALUES "
+const upsertRatings = " ON DUPLICATE KEY UPDATE value = VALUES(value), provider_id = VALUES(provider_id), ingested_at = VALUES(ingested_at), updated_at = VALUES(updated_at)"
+
+// Put adds a rating for a given record, replacing an earlier rating of the same user.
+// It returns the replaced rating, nil if there was none.
+func (r *Repository) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) (_ *model.Rating, err error) {
+	defer metrics.TimeQuery("rating", "put")()
+	ctx, done := tracing.Trace(ctx, "rating repository put", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	stored := *rating
+	stored.RecordID, stored.RecordType = string(recordID), string(recordType)
+	old, err := r.putBatch(ctx, []model.Rating{stored})
+	if err != nil {
+		return nil, err
+	}
+	return old[0], nil
+}
+
+// PutBatch adds or replaces multiple ratings with a single multi-row insert, each rating carrying its record id and type.
+// It returns the replaced rating of each rating, nil if there was none.
+func (r *Repository) PutBatch(ctx context.Context, ratings []model.Rating) (_ []*model.Rating, err error) {
+	defer metrics.TimeQuery("rating", "put_batch")()
+	ctx, done := tracing.Trace(ctx, "rating repository put_batch", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	if len(ratings) == 0 {
+		return nil, nil
+	}
+	return r.putBatch(ctx, ratings)
+}
+
+// putBatch reads and locks the ratings to be replaced, then upserts the ratings within a transaction.
+func (r *Repository) putBatch(ctx context.Context, ratings []model.Rating) ([]*model.Rating, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer r.rollback(ctx, tx)
+	current, err := lockRatings(ctx, tx, ratings)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*model.Rating, len(ratings))
+	placeholders := make([]string, 0, len(ratings))
+	args := make([]any, 0, 8*len(ratings))
+	for i, rating := range ratings {
+		key := ratingKey{rating.RecordID, rating.RecordType, rating.UserID}
+		// A batch may hold several ratings of a user for a record, each replacing the one before.
+		res[i] = current[key]
+		rating := rating
+		if res[i] != nil && !res[i].CreatedAt.IsZero() {
+			rating.CreatedAt = res[i].CreatedAt
+		}
+		current[key] = &rating
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, rating.RecordID, rating.RecordType, rating.UserID, rating.Value, rating.ProviderID,
+			nullTime(rating.IngestedAt), nullTime(rating.CreatedAt), nullTime(rating.UpdatedAt))
+	}
+	if _, err := tx.ExecContext(ctx, insertRatings+strings.Join(placeholders, ", ")+upsertRatings, args...); err != nil {
+		return nil, err
+	}
+	return res, tx.Commit()
 }
 
-// Put adds a rating for a given record.
-func (r *Repository) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
-	_, err := r.db.ExecContext(ctx, "INSERT INTO ratings (record_id, record_type, user_id, value) VALUES (?, ?, ?, ?)",
-		recordID, recordType, rating.UserID, rating.Value)
-	return err
+type ratingKey struct {
+	recordID   string
+	recordType string
+	userID     model.UserID
+}
+
+// lockRatings reads the stored ratings of the users for the records of the given ratings, locking them
+// until the transaction ends.
+func lockRatings(ctx context.Context, tx *sql.Tx, ratings []model.Rating) (map[ratingKey]*model.Rating, error) {
+	placeholders := make([]string, 0, len(ratings))
+	args := make([]any, 0, 3*len(ratings))
+	for _, rating := range ratings {
+		placeholders = append(placeholders, "(?, ?, ?)")
+		args = append(args, rating.RecordID, rating.RecordType, rating.UserID)
+	}
+	rows, err := tx.QueryContext(ctx, selectRatings+"WHERE (record_id, record_type, user_id) IN ("+strings.Join(placeholders, ", ")+") FOR UPDATE", args...)
+	if err != nil {
+		return nil, err
+	}
+	res := map[ratingKey]*model.Rating{}
+	err = scanRows(rows, func(rating model.Rating) error {
+		res[ratingKey{rating.RecordID, rating.RecordType, rating.UserID}] = &rating
+		return nil
+	})
+	return res, err
+}
+
+// rollback rolls back a transaction unless it is already committed. Failures are only logged, the error
+// which made the transaction fail is returned instead.
+func (r *Repository) rollback(ctx context.Context, tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		r.logger.WarnContext(ctx, "Failed to roll back rating transaction", "error", err)
+	}
+}
+
+// nullTime stores zero times as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// Delete removes the rating of a user for a given record and returns it, nil if there was none.
+func (r *Repository) Delete(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) (_ *model.Rating, err error) {
+	defer metrics.TimeQuery("rating", "delete")()
+	ctx, done := tracing.Trace(ctx, "rating repository delete", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer r.rollback(ctx, tx)
+	key := ratingKey{string(recordID), string(recordType), userID}
+	current, err := lockRatings(ctx, tx, []model.Rating{{RecordID: key.recordID, RecordType: key.recordType, UserID: userID}})
+	if err != nil {
+		return nil, err
+	}
+	deleted := current[key]
+	if deleted == nil {
+		return nil, nil
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM ratings WHERE record_id = ? AND record_type = ? AND user_id = ?",
+		recordID, recordType, userID)
+	if err != nil {
+		return nil, err
+	}
+	return deleted, tx.Commit()
+}
+
+// DeleteByProvider removes all ratings received from a given provider and returns how many were removed.
+func (r *Repository) DeleteByProvider(ctx context.Context, providerID string) (_ int64, err error) {
+	defer metrics.TimeQuery("rating", "delete_by_provider")()
+	ctx, done := tracing.Trace(ctx, "rating repository delete_by_provider", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	res, err := r.db.ExecContext(ctx, "DELETE FROM ratings WHERE provider_id = ?", providerID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
 }
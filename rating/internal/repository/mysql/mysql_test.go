@@ -0,0 +1,102 @@
+package mysql
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+var ratingColumns = []string{"record_id", "record_type", "user_id", "value", "provider_id", "ingested_at", "created_at", "updated_at"}
+
+const lockQuery = "FROM ratings WHERE (record_id, record_type, user_id) IN "
+
+func TestRepository_Put(t *testing.T) {
+	created := time.Date(2023, time.March, 4, 20, 15, 0, 0, time.UTC)
+	now := time.Date(2024, time.January, 8, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		rows *sqlmock.Rows
+		want *model.Rating
+	}{
+		{
+			name: "new rating",
+			rows: sqlmock.NewRows(ratingColumns),
+		},
+		{
+			name: "replaced rating",
+			rows: sqlmock.NewRows(ratingColumns).AddRow("1", "movie", "user1", 2, "imdb", created, created, created),
+			want: &model.Rating{RecordID: "1", RecordType: "movie", UserID: "user1", Value: 2, ProviderID: "imdb", IngestedAt: created, CreatedAt: created, UpdatedAt: created},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMock(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(lockQuery+"((?, ?, ?)) FOR UPDATE")).
+				WithArgs("1", "movie", model.UserID("user1")).
+				WillReturnRows(tt.rows)
+			createdAt := now
+			if tt.want != nil {
+				createdAt = tt.want.CreatedAt
+			}
+			mock.ExpectExec(regexp.QuoteMeta("INSERT INTO ratings")).
+				WithArgs("1", "movie", model.UserID("user1"), model.RatingValue(5), "", nullTime(now), nullTime(createdAt), nullTime(now)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			old, err := r.Put(context.Background(), "1", "movie", &model.Rating{UserID: "user1", Value: 5, IngestedAt: now, CreatedAt: now, UpdatedAt: now})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, old)
+		})
+	}
+}
+
+func TestRepository_PutBatch(t *testing.T) {
+	r, mock := newMock(t)
+	ratings := []model.Rating{
+		{RecordID: "1", RecordType: "movie", UserID: "user1", Value: 3},
+		{RecordID: "2", RecordType: "movie", UserID: "user1", Value: 4},
+		{RecordID: "1", RecordType: "movie", UserID: "user1", Value: 5},
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(lockQuery + "((?, ?, ?), (?, ?, ?), (?, ?, ?)) FOR UPDATE")).
+		WillReturnRows(sqlmock.NewRows(ratingColumns).AddRow("2", "movie", "user1", 1, "", nil, nil, nil))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO ratings")).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	old, err := r.PutBatch(context.Background(), ratings)
+	require.NoError(t, err)
+	require.Len(t, old, 3)
+	assert.Nil(t, old[0])
+	assert.Equal(t, model.RatingValue(1), old[1].Value)
+	assert.Equal(t, &ratings[0], old[2], "a rating replaces the one before it in the batch")
+}
+
+func TestRepository_Delete(t *testing.T) {
+	r, mock := newMock(t)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).
+		WithArgs("1", "movie", model.UserID("user1")).
+		WillReturnRows(sqlmock.NewRows(ratingColumns).AddRow("1", "movie", "user1", 4, "", nil, nil, nil))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM ratings WHERE record_id = ? AND record_type = ? AND user_id = ?")).
+		WithArgs(model.RecordID("1"), model.RecordType("movie"), model.UserID("user1")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	deleted, err := r.Delete(ctx, "1", "movie", "user1")
+	require.NoError(t, err)
+	assert.Equal(t, &model.Rating{RecordID: "1", RecordType: "movie", UserID: "user1", Value: 4}, deleted)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(lockQuery)).WillReturnRows(sqlmock.NewRows(ratingColumns))
+	mock.ExpectRollback()
+	deleted, err = r.Delete(ctx, "1", "movie", "user1")
+	require.NoError(t, err)
+	assert.Nil(t, deleted, "deleting a missing rating is a no-op")
+}
@@ -0,0 +1,122 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/ugurcancaykara/odd-service/pkg/metrics"
+	"github.com/ugurcancaykara/odd-service/pkg/tracing"
+	"github.com/ugurcancaykara/odd-service/rating/internal/repository"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PutQuarantined stores a quarantined rating, replacing an earlier quarantined rating of the same user for the record.
+func (r *Repository) PutQuarantined(ctx context.Context, q *model.QuarantinedRating) (err error) {
+	defer metrics.TimeQuery("rating", "put_quarantined")()
+	ctx, done := tracing.Trace(ctx, "rating repository put_quarantined", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	reasons := make([]string, 0, len(q.Reasons))
+	for _, reason := range q.Reasons {
+		reasons = append(reasons, string(reason))
+	}
+	_, err = r.db.ExecContext(ctx, "INSERT INTO quarantined_ratings (record_id, record_type, user_id, value, provider_id, ingested_at, created_at, updated_at, reasons, quarantined_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"+
+		" ON DUPLICATE KEY UPDATE value = VALUES(value), provider_id = VALUES(provider_id), ingested_at = VALUES(ingested_at), created_at = VALUES(created_at), updated_at = VALUES(updated_at), reasons = VALUES(reasons), quarantined_at = VALUES(quarantined_at)",
+		q.Rating.RecordID, q.Rating.RecordType, q.Rating.UserID, q.Rating.Value, q.Rating.ProviderID,
+		nullTime(q.Rating.IngestedAt), nullTime(q.Rating.CreatedAt), nullTime(q.Rating.UpdatedAt),
+		strings.Join(reasons, ","), q.QuarantinedAt)
+	return err
+}
+
+// GetQuarantined retrieves the quarantined rating of a user for a given record.
+func (r *Repository) GetQuarantined(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) (_ *model.QuarantinedRating, err error) {
+	defer metrics.TimeQuery("rating", "get_quarantined")()
+	ctx, done := tracing.Trace(ctx, "rating repository get_quarantined", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	res, err := r.queryQuarantined(ctx, "WHERE record_id = ? AND record_type = ? AND user_id = ?", recordID, recordType, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return &res[0], nil
+}
+
+// ListQuarantined retrieves up to pageSize quarantined ratings ordered by record type, record id and user id,
+// starting after the rating encoded in pageToken. It returns the token of the next page, which is empty on the last page.
+func (r *Repository) ListQuarantined(ctx context.Context, pageToken string, pageSize int) (_ []model.QuarantinedRating, _ string, err error) {
+	defer metrics.TimeQuery("rating", "list_quarantined")()
+	ctx, done := tracing.Trace(ctx, "rating repository list_quarantined", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	where := ""
+	var args []any
+	if pageToken != "" {
+		recordType, recordID, userID, err := repository.DecodeQuarantinePageToken(pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		where = "WHERE (record_type, record_id, user_id) > (?, ?, ?) "
+		args = append(args, recordType, recordID, userID)
+	}
+	// One more rating than requested is queried to find out whether there is a next page.
+	where += "ORDER BY record_type, record_id, user_id LIMIT ?"
+	args = append(args, pageSize+1)
+	res, err := r.queryQuarantined(ctx, where, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(res) > pageSize {
+		res = res[:pageSize]
+		last := res[len(res)-1].Rating
+		next = repository.EncodeQuarantinePageToken(model.RecordType(last.RecordType), model.RecordID(last.RecordID), last.UserID)
+	}
+	return res, next, nil
+}
+
+// DeleteQuarantined removes the quarantined rating of a user for a given record.
+func (r *Repository) DeleteQuarantined(ctx context.Context, recordID model.RecordID, recordType model.RecordType, userID model.UserID) (err error) {
+	defer metrics.TimeQuery("rating", "delete_quarantined")()
+	ctx, done := tracing.Trace(ctx, "rating repository delete_quarantined", attribute.String("db.system", "mysql"))
+	defer func() { done(err) }()
+	_, err = r.db.ExecContext(ctx, "DELETE FROM quarantined_ratings WHERE record_id = ? AND record_type = ? AND user_id = ?",
+		recordID, recordType, userID)
+	return err
+}
+
+func (r *Repository) queryQuarantined(ctx context.Context, where string, args ...any) ([]model.QuarantinedRating, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT record_id, record_type, user_id, value, provider_id, ingested_at, created_at, updated_at, reasons, quarantined_at FROM quarantined_ratings "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []model.QuarantinedRating
+	for rows.Next() {
+		var recordID, recordType, userID, providerID, reasons string
+		var value int32
+		var ingestedAt, createdAt, updatedAt sql.NullTime
+		var q model.QuarantinedRating
+		if err := rows.Scan(&recordID, &recordType, &userID, &value, &providerID, &ingestedAt, &createdAt, &updatedAt, &reasons, &q.QuarantinedAt); err != nil {
+			return nil, err
+		}
+		q.Rating = model.Rating{
+			RecordID:   recordID,
+			RecordType: recordType,
+			UserID:     model.UserID(userID),
+			Value:      model.RatingValue(value),
+			ProviderID: providerID,
+			IngestedAt: ingestedAt.Time,
+			CreatedAt:  createdAt.Time,
+			UpdatedAt:  updatedAt.Time,
+		}
+		for _, reason := range strings.Split(reasons, ",") {
+			if reason != "" {
+				q.Reasons = append(q.Reasons, model.AnomalyReason(reason))
+			}
+		}
+		res = append(res, q)
+	}
+	return res, rows.Err()
+}
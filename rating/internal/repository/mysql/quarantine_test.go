@@ -0,0 +1,138 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/rating/internal/repository"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+func newMock(t *testing.T) (*Repository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+		db.Close()
+	})
+	return &Repository{db: db, logger: slog.Default()}, mock
+}
+
+var quarantineColumns = []string{"record_id", "record_type", "user_id", "value", "provider_id", "ingested_at", "created_at", "updated_at", "reasons", "quarantined_at"}
+
+func TestRepository_PutQuarantined(t *testing.T) {
+	r, mock := newMock(t)
+	at := time.Date(2024, time.January, 8, 12, 0, 0, 0, time.UTC)
+	q := &model.QuarantinedRating{
+		Rating:        model.Rating{RecordID: "1", RecordType: "movie", UserID: "user1", Value: 5, ProviderID: "imdb", UpdatedAt: at},
+		Reasons:       []model.AnomalyReason{model.AnomalyReasonBurst, model.AnomalyReasonNewUserSpike},
+		QuarantinedAt: at,
+	}
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO quarantined_ratings")).
+		WithArgs("1", "movie", model.UserID("user1"), model.RatingValue(5), "imdb",
+			sql.NullTime{}, sql.NullTime{}, sql.NullTime{Time: at, Valid: true}, "burst,new_user_spike", at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, r.PutQuarantined(context.Background(), q))
+}
+
+func TestRepository_GetQuarantined(t *testing.T) {
+	at := time.Date(2024, time.January, 8, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		rows    *sqlmock.Rows
+		err     error
+		want    *model.QuarantinedRating
+		wantErr error
+	}{
+		{
+			name: "found",
+			rows: sqlmock.NewRows(quarantineColumns).AddRow("1", "movie", "user1", 5, "imdb", nil, at, at, "burst,outlier", at),
+			want: &model.QuarantinedRating{
+				Rating:        model.Rating{RecordID: "1", RecordType: "movie", UserID: "user1", Value: 5, ProviderID: "imdb", CreatedAt: at, UpdatedAt: at},
+				Reasons:       []model.AnomalyReason{model.AnomalyReasonBurst, model.AnomalyReasonOutlier},
+				QuarantinedAt: at,
+			},
+		},
+		{
+			name:    "not found",
+			rows:    sqlmock.NewRows(quarantineColumns),
+			wantErr: repository.ErrNotFound,
+		},
+		{
+			name:    "query error",
+			err:     errors.New("connection refused"),
+			wantErr: errors.New("connection refused"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMock(t)
+			query := mock.ExpectQuery(regexp.QuoteMeta("FROM quarantined_ratings WHERE record_id = ? AND record_type = ? AND user_id = ?")).
+				WithArgs(model.RecordID("1"), model.RecordTypeMovie, model.UserID("user1"))
+			if tt.err != nil {
+				query.WillReturnError(tt.err)
+			} else {
+				query.WillReturnRows(tt.rows)
+			}
+			got, err := r.GetQuarantined(context.Background(), "1", model.RecordTypeMovie, "user1")
+			if tt.wantErr != nil {
+				if errors.Is(tt.wantErr, repository.ErrNotFound) {
+					assert.ErrorIs(t, err, tt.wantErr)
+				} else {
+					assert.EqualError(t, err, tt.wantErr.Error())
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRepository_ListQuarantined(t *testing.T) {
+	r, mock := newMock(t)
+	at := time.Date(2024, time.January, 8, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	// One more rating than requested is queried to find out whether there is a next page.
+	mock.ExpectQuery(regexp.QuoteMeta("FROM quarantined_ratings ORDER BY record_type, record_id, user_id LIMIT ?")).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(quarantineColumns).
+			AddRow("1", "movie", "user1", 5, "", nil, nil, nil, "burst", at).
+			AddRow("1", "movie", "user2", 5, "", nil, nil, nil, "burst", at).
+			AddRow("2", "movie", "user1", 1, "", nil, nil, nil, "", at))
+	page, next, err := r.ListQuarantined(ctx, "", 2)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, model.UserID("user2"), page[1].Rating.UserID)
+	assert.Equal(t, repository.EncodeQuarantinePageToken("movie", "1", "user2"), next)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM quarantined_ratings WHERE (record_type, record_id, user_id) > (?, ?, ?) ORDER BY record_type, record_id, user_id LIMIT ?")).
+		WithArgs(model.RecordTypeMovie, model.RecordID("1"), model.UserID("user2"), 3).
+		WillReturnRows(sqlmock.NewRows(quarantineColumns).AddRow("2", "movie", "user1", 1, "", nil, nil, nil, "", at))
+	page, next, err = r.ListQuarantined(ctx, next, 2)
+	require.NoError(t, err)
+	assert.Empty(t, next)
+	require.Len(t, page, 1)
+	assert.Empty(t, page[0].Reasons)
+
+	_, _, err = r.ListQuarantined(ctx, "!", 2)
+	assert.ErrorIs(t, err, repository.ErrInvalidPageToken)
+}
+
+func TestRepository_DeleteQuarantined(t *testing.T) {
+	r, mock := newMock(t)
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM quarantined_ratings WHERE record_id = ? AND record_type = ? AND user_id = ?")).
+		WithArgs(model.RecordID("1"), model.RecordTypeMovie, model.UserID("user1")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, r.DeleteQuarantined(context.Background(), "1", model.RecordTypeMovie, "user1"))
+}
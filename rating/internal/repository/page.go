@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	model "github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// ErrInvalidPageToken is returned when a page token can't be decoded.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// EncodePageToken returns a page token continuing a listing of a user's ratings after a record.
+// Ratings of a user are listed ordered by record type and record id.
+func EncodePageToken(recordType model.RecordType, recordID model.RecordID) string {
+	return encodePageToken(string(recordType), string(recordID))
+}
+
+// DecodePageToken returns the last record of the previous page encoded in a page token.
+func DecodePageToken(token string) (model.RecordType, model.RecordID, error) {
+	fields, err := decodePageToken(token, 2)
+	if err != nil {
+		return "", "", err
+	}
+	return model.RecordType(fields[0]), model.RecordID(fields[1]), nil
+}
+
+// EncodeQuarantinePageToken returns a page token continuing a listing of quarantined ratings after a rating.
+// Quarantined ratings are listed ordered by record type, record id and user id.
+func EncodeQuarantinePageToken(recordType model.RecordType, recordID model.RecordID, userID model.UserID) string {
+	return encodePageToken(string(recordType), string(recordID), string(userID))
+}
+
+// DecodeQuarantinePageToken returns the last quarantined rating of the previous page encoded in a page token.
+func DecodeQuarantinePageToken(token string) (model.RecordType, model.RecordID, model.UserID, error) {
+	fields, err := decodePageToken(token, 3)
+	if err != nil {
+		return "", "", "", err
+	}
+	return model.RecordType(fields[0]), model.RecordID(fields[1]), model.UserID(fields[2]), nil
+}
+
+// EncodeProviderPageToken returns a page token continuing a listing of a provider's ratings after a rating.
+// Ratings of a provider are listed ordered by record type, record id and user id.
+func EncodeProviderPageToken(recordType model.RecordType, recordID model.RecordID, userID model.UserID) string {
+	return encodePageToken(string(recordType), string(recordID), string(userID))
+}
+
+// DecodeProviderPageToken returns the last rating of the previous page encoded in a page token.
+func DecodeProviderPageToken(token string) (model.RecordType, model.RecordID, model.UserID, error) {
+	fields, err := decodePageToken(token, 3)
+	if err != nil {
+		return "", "", "", err
+	}
+	return model.RecordType(fields[0]), model.RecordID(fields[1]), model.UserID(fields[2]), nil
+}
+
+func encodePageToken(fields ...string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(fields, "\x00")))
+}
+
+func decodePageToken(token string, n int) ([]string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	fields := strings.Split(string(b), "\x00")
+	if len(fields) != n {
+		return nil, ErrInvalidPageToken
+	}
+	return fields, nil
+}
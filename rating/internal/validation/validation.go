@@ -0,0 +1,76 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ugurcancaykara/odd-service/rating/internal/recordtype"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// ErrInvalidEvent is returned when a rating event fails validation.
+var ErrInvalidEvent = errors.New("invalid rating event")
+
+// Config defines rating event validation rules.
+type Config struct {
+	// RecordTypes defines the accepted record types and the scales their values are checked against.
+	RecordTypes *recordtype.Registry
+	// Providers lists the accepted provider ids. Any provider is accepted if it is empty.
+	Providers []string
+}
+
+// DefaultConfig accepts 1-5 ratings for movies from any provider.
+var DefaultConfig = Config{
+	RecordTypes: recordtype.Default,
+}
+
+// Validator defines a rating event validator.
+type Validator struct {
+	recordTypes *recordtype.Registry
+	providers   map[string]bool
+}
+
+// New creates a new rating event validator.
+func New(cfg Config) *Validator {
+	v := &Validator{
+		recordTypes: cfg.RecordTypes,
+		providers:   map[string]bool{},
+	}
+	if v.recordTypes == nil {
+		v.recordTypes = recordtype.Default
+	}
+	for _, p := range cfg.Providers {
+		v.providers[p] = true
+	}
+	return v
+}
+
+// Validate checks a rating event, returning an error wrapping ErrInvalidEvent if it is not valid.
+func (v *Validator) Validate(e *model.RatingEvent) error {
+	switch {
+	case e.UserID == "":
+		return invalid("empty user id")
+	case e.RecordID == "":
+		return invalid("empty record id")
+	case e.RecordType == "":
+		return invalid("empty record type")
+	case e.EventType != "" && e.EventType != model.RatingEventTypePut && e.EventType != model.RatingEventTypeDelete:
+		return invalid("unknown event type %q", e.EventType)
+	case len(v.providers) > 0 && !v.providers[e.ProviderID]:
+		return invalid("unknown provider %q", e.ProviderID)
+	}
+	if _, err := v.recordTypes.Scale(e.RecordType); err != nil {
+		return invalid("%v", err)
+	}
+	// Delete events carry no value.
+	if e.EventType != model.RatingEventTypeDelete {
+		if err := v.recordTypes.Validate(e.RecordType, e.Value); err != nil {
+			return invalid("%v", err)
+		}
+	}
+	return nil
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidEvent, fmt.Sprintf(format, args...))
+}
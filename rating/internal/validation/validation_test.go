@@ -0,0 +1,64 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/rating/internal/recordtype"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+func TestValidator_Validate(t *testing.T) {
+	recordTypes, err := recordtype.New(map[model.RecordType]model.RatingScale{
+		model.RecordTypeMovie: model.RatingScaleStars5,
+		"episode":             model.RatingScaleThumbs,
+	})
+	require.NoError(t, err)
+	put := func(recordType model.RecordType, value model.RatingValue) model.RatingEvent {
+		return model.RatingEvent{UserID: "105", RecordID: "1", RecordType: recordType, Value: value, ProviderID: "imdb", EventType: model.RatingEventTypePut}
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		event   func(e *model.RatingEvent)
+		wantErr bool
+	}{
+		{name: "valid put", event: func(*model.RatingEvent) {}},
+		{name: "no event type means put", event: func(e *model.RatingEvent) { e.EventType = "" }},
+		{name: "empty user id", event: func(e *model.RatingEvent) { e.UserID = "" }, wantErr: true},
+		{name: "empty record id", event: func(e *model.RatingEvent) { e.RecordID = "" }, wantErr: true},
+		{name: "empty record type", event: func(e *model.RatingEvent) { e.RecordType = "" }, wantErr: true},
+		{name: "unknown record type", event: func(e *model.RatingEvent) { e.RecordType = "book" }, wantErr: true},
+		{name: "unknown event type", event: func(e *model.RatingEvent) { e.EventType = "upsert" }, wantErr: true},
+		{name: "value below scale", event: func(e *model.RatingEvent) { e.Value = 0 }, wantErr: true},
+		{name: "value above scale", event: func(e *model.RatingEvent) { e.Value = 6 }, wantErr: true},
+		{name: "value within the scale of the record type", event: func(e *model.RatingEvent) { *e = put("episode", 0) }},
+		{name: "value outside the scale of the record type", event: func(e *model.RatingEvent) { *e = put("episode", 2) }, wantErr: true},
+		{name: "delete without value", event: func(e *model.RatingEvent) { e.EventType, e.Value = model.RatingEventTypeDelete, 0 }},
+		{name: "delete of unknown record type", event: func(e *model.RatingEvent) { e.EventType, e.RecordType = model.RatingEventTypeDelete, "book" }, wantErr: true},
+		{name: "accepted provider", cfg: Config{Providers: []string{"imdb", "letterboxd"}}, event: func(*model.RatingEvent) {}},
+		{name: "unknown provider", cfg: Config{Providers: []string{"letterboxd"}}, event: func(*model.RatingEvent) {}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.RecordTypes = recordTypes
+			e := put(model.RecordTypeMovie, 4)
+			tt.event(&e)
+			err := New(cfg).Validate(&e)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidEvent)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNew_DefaultRecordTypes(t *testing.T) {
+	v := New(Config{})
+	assert.NoError(t, v.Validate(&model.RatingEvent{UserID: "105", RecordID: "1", RecordType: model.RecordTypeMovie, Value: 5}))
+	assert.ErrorIs(t, v.Validate(&model.RatingEvent{UserID: "105", RecordID: "1", RecordType: model.RecordTypeMovie, Value: 10}), ErrInvalidEvent)
+}
@@ -0,0 +1,151 @@
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ugurcancaykara/odd-service/gen"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+	"google.golang.org/protobuf/proto"
+)
+
+// HeaderContentType defines the Kafka message header carrying the payload content type.
+const HeaderContentType = "content-type"
+
+// Supported rating event content types.
+const (
+	ContentTypeJSON  = "application/json"
+	ContentTypeProto = "application/x-protobuf"
+)
+
+// ErrUnsupportedContentType is returned when a payload has an unknown content type.
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// ErrUnsupportedSchemaVersion is returned when an event has a schema version newer than supported.
+var ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+
+// ErrMissingValue is returned when a put event has no rating value, which would be taken for a zero value otherwise.
+var ErrMissingValue = errors.New("missing rating value")
+
+// legacyRecordTypes maps the numeric record types used by schema version 1 producers.
+var legacyRecordTypes = map[int]model.RecordType{
+	1: model.RecordTypeMovie,
+}
+
+// ContentType returns the content type for an encoding name, json or proto.
+func ContentType(encoding string) (string, error) {
+	switch encoding {
+	case "json":
+		return ContentTypeJSON, nil
+	case "proto", "protobuf":
+		return ContentTypeProto, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedContentType, encoding)
+	}
+}
+
+// Encode encodes a rating event with the current schema version.
+func Encode(e *model.RatingEvent, contentType string) ([]byte, error) {
+	v := *e
+	v.SchemaVersion = model.CurrentSchemaVersion
+	switch contentType {
+	case ContentTypeJSON:
+		return json.Marshal(&v)
+	case ContentTypeProto:
+		return proto.Marshal(model.RatingEventToProto(&v))
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+	}
+}
+
+// Decode decodes a rating event and upgrades it to the current schema version.
+// Payloads without a content type are treated as JSON, which is what legacy producers send.
+func Decode(payload []byte, contentType string) (*model.RatingEvent, error) {
+	switch contentType {
+	case "", ContentTypeJSON:
+		return decodeJSON(payload)
+	case ContentTypeProto:
+		var e gen.RatingEvent
+		if err := proto.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return upgrade(model.RatingEventFromProto(&e), e.Value != nil)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+	}
+}
+
+// jsonEvent mirrors model.RatingEvent but accepts the numeric record types of schema version 1.
+type jsonEvent struct {
+	SchemaVersion int                   `json:"schemaVersion"`
+	UserID        model.UserID          `json:"userId"`
+	RecordID      model.RecordID        `json:"recordId"`
+	RecordType    json.RawMessage       `json:"recordType"`
+	Value         *model.RatingValue    `json:"value"`
+	ProviderID    string                `json:"providerId"`
+	EventType     model.RatingEventType `json:"eventType"`
+	Timestamp     time.Time             `json:"timestamp"`
+}
+
+func decodeJSON(payload []byte) (*model.RatingEvent, error) {
+	var v jsonEvent
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return nil, err
+	}
+	e := &model.RatingEvent{
+		SchemaVersion: v.SchemaVersion,
+		UserID:        v.UserID,
+		RecordID:      v.RecordID,
+		ProviderID:    v.ProviderID,
+		EventType:     v.EventType,
+		Timestamp:     v.Timestamp,
+	}
+	if len(v.RecordType) > 0 && string(v.RecordType) != "null" {
+		var recordType string
+		if err := json.Unmarshal(v.RecordType, &recordType); err == nil {
+			e.RecordType = model.RecordType(recordType)
+		} else if n, err := strconv.Atoi(string(v.RecordType)); err == nil && v.SchemaVersion <= 1 {
+			t, ok := legacyRecordTypes[n]
+			if !ok {
+				return nil, fmt.Errorf("unknown legacy record type %d", n)
+			}
+			e.RecordType = t
+		} else {
+			return nil, fmt.Errorf("invalid record type %s", v.RecordType)
+		}
+	}
+	if v.Value != nil {
+		e.Value = *v.Value
+	}
+	return upgrade(e, v.Value != nil)
+}
+
+// upgrade upgrades a decoded event and checks that it has a value unless it is a delete event.
+func upgrade(e *model.RatingEvent, hasValue bool) (*model.RatingEvent, error) {
+	e, err := Upgrade(e)
+	if err != nil {
+		return nil, err
+	}
+	if !hasValue && e.EventType != model.RatingEventTypeDelete {
+		return nil, ErrMissingValue
+	}
+	return e, nil
+}
+
+// Upgrade converts an event of an older schema version to the current one.
+func Upgrade(e *model.RatingEvent) (*model.RatingEvent, error) {
+	if e.SchemaVersion > model.CurrentSchemaVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedSchemaVersion, e.SchemaVersion)
+	}
+	if e.SchemaVersion <= 1 {
+		// Version 1 producers only sent put events and didn't always set the event type.
+		if e.EventType == "" {
+			e.EventType = model.RatingEventTypePut
+		}
+		e.SchemaVersion = 2
+	}
+	return e, nil
+}
@@ -0,0 +1,112 @@
+package codec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	event := &model.RatingEvent{
+		UserID:     "105",
+		RecordID:   "1",
+		RecordType: model.RecordTypeMovie,
+		Value:      5,
+		ProviderID: "test-provider",
+		EventType:  model.RatingEventTypePut,
+	}
+	backfill := *event
+	backfill.Timestamp = time.Date(2023, time.March, 4, 20, 15, 0, 0, time.UTC)
+	thumbsDown := model.RatingEvent{UserID: "105", RecordID: "2", RecordType: "clip", Value: 0, EventType: model.RatingEventTypePut}
+	deleted := model.RatingEvent{UserID: "105", RecordID: "1", RecordType: model.RecordTypeMovie, EventType: model.RatingEventTypeDelete}
+
+	for _, contentType := range []string{ContentTypeJSON, ContentTypeProto} {
+		for _, e := range []*model.RatingEvent{event, &backfill, &thumbsDown, &deleted} {
+			want := *e
+			want.SchemaVersion = model.CurrentSchemaVersion
+			t.Run(contentType, func(t *testing.T) {
+				b, err := Encode(e, contentType)
+				require.NoError(t, err)
+				got, err := Decode(b, contentType)
+				require.NoError(t, err)
+				assert.Equal(t, &want, got)
+			})
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       string
+		contentType   string
+		expected      *model.RatingEvent
+		expectedError error
+	}{
+		{
+			name:        "Legacy event with numeric record type and no event type",
+			payload:     `{"userId":"105","recordId":"1","recordType":1,"value":5,"providerId":"test-provider"}`,
+			contentType: "",
+			expected: &model.RatingEvent{
+				SchemaVersion: model.CurrentSchemaVersion,
+				UserID:        "105",
+				RecordID:      "1",
+				RecordType:    model.RecordTypeMovie,
+				Value:         5,
+				ProviderID:    "test-provider",
+				EventType:     model.RatingEventTypePut,
+			},
+		},
+		{
+			name:          "Put event without value",
+			payload:       `{"userId":"105","recordId":"1","recordType":"movie","eventType":"put"}`,
+			contentType:   ContentTypeJSON,
+			expectedError: ErrMissingValue,
+		},
+		{
+			name:        "Put event with zero value",
+			payload:     `{"schemaVersion":2,"userId":"105","recordId":"1","recordType":"clip","value":0,"eventType":"put"}`,
+			contentType: ContentTypeJSON,
+			expected: &model.RatingEvent{
+				SchemaVersion: model.CurrentSchemaVersion,
+				UserID:        "105",
+				RecordID:      "1",
+				RecordType:    "clip",
+				EventType:     model.RatingEventTypePut,
+			},
+		},
+		{
+			name:          "Protobuf put event without value",
+			payload:       "\x12\x03105\x1a\x011\x22\x05movie\x3a\x03put",
+			contentType:   ContentTypeProto,
+			expectedError: ErrMissingValue,
+		},
+		{
+			name:          "Newer schema version",
+			payload:       `{"schemaVersion":3,"userId":"105","recordId":"1","recordType":"movie","value":5}`,
+			contentType:   ContentTypeJSON,
+			expectedError: ErrUnsupportedSchemaVersion,
+		},
+		{
+			name:          "Unknown content type",
+			payload:       `<rating/>`,
+			contentType:   "application/xml",
+			expectedError: ErrUnsupportedContentType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode([]byte(tt.payload), tt.contentType)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
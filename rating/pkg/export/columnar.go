@@ -0,0 +1,290 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// The columnar format is laid out like Parquet: the file starts with columnarMagic, followed by row groups
+// holding a chunk per column, and ends with a JSON footer describing the columns and the offsets of every chunk,
+// the footer length as a 4 byte little endian integer and columnarMagic again.
+// String columns hold a uvarint length and the bytes of every value, integer columns a zigzag varint per value
+// and time columns the Unix time in microseconds as a zigzag varint, 0 for unknown times.
+const columnarMagic = "ODDC"
+
+// DefaultRowGroupSize defines how many ratings a columnar row group holds by default.
+const DefaultRowGroupSize = 10000
+
+// ErrInvalidColumnar is returned when a file is not a valid columnar export.
+var ErrInvalidColumnar = errors.New("invalid columnar export")
+
+// Column types of columnar exports.
+const (
+	ColumnTypeString = "string"
+	ColumnTypeInt    = "int"
+	ColumnTypeTime   = "time"
+)
+
+// columnTypes defines the type of each of Columns.
+var columnTypes = []string{ColumnTypeString, ColumnTypeString, ColumnTypeString, ColumnTypeInt, ColumnTypeString, ColumnTypeTime, ColumnTypeTime, ColumnTypeTime}
+
+// Footer defines the footer of a columnar export.
+type Footer struct {
+	Version   int        `json:"version"`
+	Columns   []Column   `json:"columns"`
+	Rows      int        `json:"rows"`
+	RowGroups []RowGroup `json:"rowGroups"`
+}
+
+// Column defines a column of a columnar export.
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// RowGroup defines a row group of a columnar export with the location of its column chunks in column order.
+type RowGroup struct {
+	Rows   int     `json:"rows"`
+	Chunks []Chunk `json:"chunks"`
+}
+
+// Chunk defines the location of a column chunk in a columnar export.
+type Chunk struct {
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+}
+
+// ColumnarWriter defines a writer of columnar exports buffering a row group in memory.
+type ColumnarWriter struct {
+	w            *countingWriter
+	rowGroupSize int
+	rows         []model.Rating
+	footer       Footer
+	err          error
+}
+
+// NewColumnarWriter creates a new columnar export writer flushing a row group every rowGroupSize ratings.
+func NewColumnarWriter(w io.Writer, rowGroupSize int) *ColumnarWriter {
+	footer := Footer{Version: 1}
+	for i, name := range Columns {
+		footer.Columns = append(footer.Columns, Column{Name: name, Type: columnTypes[i]})
+	}
+	return &ColumnarWriter{w: &countingWriter{w: w}, rowGroupSize: max(rowGroupSize, 1), footer: footer}
+}
+
+// Write buffers a rating, flushing the row group once it is full.
+func (w *ColumnarWriter) Write(r *model.Rating) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.w.n == 0 {
+		w.write([]byte(columnarMagic))
+	}
+	w.rows = append(w.rows, *r)
+	if len(w.rows) >= w.rowGroupSize {
+		w.flush()
+	}
+	return w.err
+}
+
+// Close flushes the last row group and writes the footer.
+func (w *ColumnarWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.w.n == 0 {
+		w.write([]byte(columnarMagic))
+	}
+	w.flush()
+	footer, err := json.Marshal(w.footer)
+	if err != nil {
+		return err
+	}
+	w.write(footer)
+	w.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	w.write([]byte(columnarMagic))
+	return w.err
+}
+
+func (w *ColumnarWriter) flush() {
+	if len(w.rows) == 0 || w.err != nil {
+		return
+	}
+	group := RowGroup{Rows: len(w.rows)}
+	var chunk []byte
+	for i := range Columns {
+		chunk = chunk[:0]
+		for j := range w.rows {
+			chunk = appendValue(chunk, &w.rows[j], i)
+		}
+		group.Chunks = append(group.Chunks, Chunk{Offset: w.w.n, Size: int64(len(chunk))})
+		w.write(chunk)
+	}
+	w.footer.Rows += len(w.rows)
+	w.footer.RowGroups = append(w.footer.RowGroups, group)
+	w.rows = w.rows[:0]
+}
+
+func (w *ColumnarWriter) write(b []byte) {
+	if w.err == nil {
+		_, w.err = w.w.Write(b)
+	}
+}
+
+func appendValue(b []byte, r *model.Rating, column int) []byte {
+	switch column {
+	case 0:
+		return appendString(b, r.RecordID)
+	case 1:
+		return appendString(b, r.RecordType)
+	case 2:
+		return appendString(b, string(r.UserID))
+	case 3:
+		return binary.AppendVarint(b, int64(r.Value))
+	case 4:
+		return appendString(b, r.ProviderID)
+	case 5:
+		return appendTime(b, r.IngestedAt)
+	case 6:
+		return appendTime(b, r.CreatedAt)
+	default:
+		return appendTime(b, r.UpdatedAt)
+	}
+}
+
+func appendString(b []byte, s string) []byte {
+	return append(binary.AppendUvarint(b, uint64(len(s))), s...)
+}
+
+func appendTime(b []byte, t time.Time) []byte {
+	if t.IsZero() {
+		return binary.AppendVarint(b, 0)
+	}
+	return binary.AppendVarint(b, t.UnixMicro())
+}
+
+// countingWriter counts the bytes written to find the offsets of column chunks.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.n += int64(n)
+	return n, err
+}
+
+// ReadColumnarFooter reads the footer of a columnar export of a given size.
+func ReadColumnarFooter(r io.ReaderAt, size int64) (*Footer, error) {
+	const trailer = 4 + len(columnarMagic)
+	if size < int64(len(columnarMagic)+trailer) {
+		return nil, ErrInvalidColumnar
+	}
+	tail := make([]byte, trailer)
+	if _, err := r.ReadAt(tail, size-int64(trailer)); err != nil {
+		return nil, err
+	}
+	if string(tail[4:]) != columnarMagic {
+		return nil, ErrInvalidColumnar
+	}
+	n := int64(binary.LittleEndian.Uint32(tail))
+	if n > size-int64(len(columnarMagic)+trailer) {
+		return nil, ErrInvalidColumnar
+	}
+	b := make([]byte, n)
+	if _, err := r.ReadAt(b, size-int64(trailer)-n); err != nil {
+		return nil, err
+	}
+	var footer Footer
+	if err := json.Unmarshal(b, &footer); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidColumnar, err)
+	}
+	if footer.Version != 1 || len(footer.Columns) != len(Columns) {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidColumnar, footer.Version)
+	}
+	return &footer, nil
+}
+
+// ReadColumnar calls fn for every rating of a columnar export of a given size, reading a row group at a time.
+func ReadColumnar(r io.ReaderAt, size int64, fn func(model.Rating) error) error {
+	footer, err := ReadColumnarFooter(r, size)
+	if err != nil {
+		return err
+	}
+	for _, group := range footer.RowGroups {
+		if len(group.Chunks) != len(Columns) {
+			return ErrInvalidColumnar
+		}
+		rows := make([]model.Rating, group.Rows)
+		for i, chunk := range group.Chunks {
+			br := bufio.NewReader(io.NewSectionReader(r, chunk.Offset, chunk.Size))
+			for j := range rows {
+				if err := readValue(br, &rows[j], i); err != nil {
+					return fmt.Errorf("%w: column %s: %v", ErrInvalidColumnar, Columns[i], err)
+				}
+			}
+		}
+		for _, rating := range rows {
+			if err := fn(rating); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func readValue(r *bufio.Reader, rating *model.Rating, column int) error {
+	var err error
+	switch column {
+	case 0:
+		rating.RecordID, err = readString(r)
+	case 1:
+		rating.RecordType, err = readString(r)
+	case 2:
+		var s string
+		s, err = readString(r)
+		rating.UserID = model.UserID(s)
+	case 3:
+		var v int64
+		v, err = binary.ReadVarint(r)
+		rating.Value = model.RatingValue(v)
+	case 4:
+		rating.ProviderID, err = readString(r)
+	case 5:
+		rating.IngestedAt, err = readTime(r)
+	case 6:
+		rating.CreatedAt, err = readTime(r)
+	default:
+		rating.UpdatedAt, err = readTime(r)
+	}
+	return err
+}
+
+func readString(r *bufio.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if _, err := io.CopyN(&b, r, int64(n)); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func readTime(r *bufio.Reader) (time.Time, error) {
+	v, err := binary.ReadVarint(r)
+	if err != nil || v == 0 {
+		return time.Time{}, err
+	}
+	return time.UnixMicro(v).UTC(), nil
+}
@@ -0,0 +1,101 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+// Format defines an export file format.
+type Format string
+
+// Supported export formats.
+const (
+	FormatJSONL    = Format("jsonl")
+	FormatCSV      = Format("csv")
+	FormatColumnar = Format("columnar")
+)
+
+// ErrUnsupportedFormat is returned when an export format is not supported.
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// Writer defines a writer of exported ratings.
+type Writer interface {
+	// Write writes a rating carrying its record id and type.
+	Write(r *model.Rating) error
+	// Close flushes buffered ratings and finishes the file, it doesn't close the underlying writer.
+	Close() error
+}
+
+// NewWriter creates a writer of exported ratings in a given format.
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatJSONL:
+		return &jsonlWriter{json.NewEncoder(w)}, nil
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatColumnar:
+		return NewColumnarWriter(w, DefaultRowGroupSize), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+}
+
+// jsonlWriter writes a JSON object per line.
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (w *jsonlWriter) Write(r *model.Rating) error {
+	return w.enc.Encode(r)
+}
+
+func (w *jsonlWriter) Close() error {
+	return nil
+}
+
+// Columns lists the columns of CSV and columnar exports.
+var Columns = []string{"record_id", "record_type", "user_id", "value", "provider_id", "ingested_at", "created_at", "updated_at"}
+
+// csvWriter writes a header line followed by a line per rating, times are RFC 3339 in UTC and empty when unknown.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(Columns); err != nil {
+		return nil, err
+	}
+	return &csvWriter{cw}, nil
+}
+
+func (w *csvWriter) Write(r *model.Rating) error {
+	return w.w.Write([]string{
+		r.RecordID,
+		r.RecordType,
+		string(r.UserID),
+		strconv.Itoa(int(r.Value)),
+		r.ProviderID,
+		csvTime(r.IngestedAt),
+		csvTime(r.CreatedAt),
+		csvTime(r.UpdatedAt),
+	})
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
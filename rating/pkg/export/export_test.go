@@ -0,0 +1,102 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugurcancaykara/odd-service/rating/pkg/model"
+)
+
+var ratings = []model.Rating{
+	{
+		RecordID:   "1",
+		RecordType: string(model.RecordTypeMovie),
+		UserID:     "105",
+		Value:      5,
+		ProviderID: "test-provider",
+		IngestedAt: time.Date(2024, time.January, 8, 12, 0, 0, 123456000, time.UTC),
+		CreatedAt:  time.Date(2024, time.January, 8, 11, 0, 0, 0, time.UTC),
+		UpdatedAt:  time.Date(2024, time.January, 8, 11, 30, 0, 0, time.UTC),
+	},
+	{RecordID: "1", RecordType: string(model.RecordTypeMovie), UserID: "106", Value: 1},
+	{RecordID: "2", RecordType: string(model.RecordTypeSeries), UserID: "105", Value: 3, ProviderID: "other, provider"},
+}
+
+func TestNewWriter(t *testing.T) {
+	tests := []struct {
+		format   Format
+		expected string
+	}{
+		{
+			format: FormatJSONL,
+			expected: `{"recordId":"1","recordType":"movie","userId":"105","value":5,"providerId":"test-provider","ingestedAt":"2024-01-08T12:00:00.123456Z","createdAt":"2024-01-08T11:00:00Z","updatedAt":"2024-01-08T11:30:00Z"}
+{"recordId":"1","recordType":"movie","userId":"106","value":1,"ingestedAt":"0001-01-01T00:00:00Z","createdAt":"0001-01-01T00:00:00Z","updatedAt":"0001-01-01T00:00:00Z"}
+{"recordId":"2","recordType":"series","userId":"105","value":3,"providerId":"other, provider","ingestedAt":"0001-01-01T00:00:00Z","createdAt":"0001-01-01T00:00:00Z","updatedAt":"0001-01-01T00:00:00Z"}
+`,
+		},
+		{
+			format: FormatCSV,
+			expected: `record_id,record_type,user_id,value,provider_id,ingested_at,created_at,updated_at
+1,movie,105,5,test-provider,2024-01-08T12:00:00.123456Z,2024-01-08T11:00:00Z,2024-01-08T11:30:00Z
+1,movie,106,1,,,,
+2,series,105,3,"other, provider",,,
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var b bytes.Buffer
+			w, err := NewWriter(tt.format, &b)
+			require.NoError(t, err)
+			for i := range ratings {
+				require.NoError(t, w.Write(&ratings[i]))
+			}
+			require.NoError(t, w.Close())
+			assert.Equal(t, tt.expected, b.String())
+		})
+	}
+
+	_, err := NewWriter("xml", &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestColumnar(t *testing.T) {
+	var b bytes.Buffer
+	w := NewColumnarWriter(&b, 2)
+	for i := range ratings {
+		require.NoError(t, w.Write(&ratings[i]))
+	}
+	require.NoError(t, w.Close())
+
+	r := bytes.NewReader(b.Bytes())
+	footer, err := ReadColumnarFooter(r, r.Size())
+	require.NoError(t, err)
+	assert.Equal(t, 3, footer.Rows)
+	require.Len(t, footer.RowGroups, 2)
+	assert.Equal(t, 2, footer.RowGroups[0].Rows)
+	assert.Equal(t, 1, footer.RowGroups[1].Rows)
+
+	var got []model.Rating
+	require.NoError(t, ReadColumnar(r, r.Size(), func(rating model.Rating) error {
+		got = append(got, rating)
+		return nil
+	}))
+	assert.Equal(t, ratings, got)
+
+	_, err = ReadColumnarFooter(bytes.NewReader(b.Bytes()[:b.Len()-1]), int64(b.Len()-1))
+	assert.ErrorIs(t, err, ErrInvalidColumnar)
+}
+
+func TestColumnar_Empty(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, NewColumnarWriter(&b, DefaultRowGroupSize).Close())
+	r := bytes.NewReader(b.Bytes())
+	footer, err := ReadColumnarFooter(r, r.Size())
+	require.NoError(t, err)
+	assert.Equal(t, 0, footer.Rows)
+	assert.Empty(t, footer.RowGroups)
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// DeadLetterReason defines why a rating event was sent to the dead-letter sink.
+type DeadLetterReason string
+
+// Existing dead-letter reasons
+const (
+	DeadLetterReasonDecode   = DeadLetterReason("decode")
+	DeadLetterReasonValidate = DeadLetterReason("validate")
+	DeadLetterReasonPersist  = DeadLetterReason("persist")
+)
+
+// DeadLetterEntry defines a rating event which couldn't be processed.
+// Event is set when the payload could be decoded. When replaying entries, a fixed Event
+// takes precedence over the raw Payload.
+type DeadLetterEntry struct {
+	Payload     []byte           `json:"payload"`
+	ContentType string           `json:"contentType,omitempty"`
+	Event       *RatingEvent     `json:"event,omitempty"`
+	Reason      DeadLetterReason `json:"reason"`
+	Error       string           `json:"error"`
+	Source      *EventSource     `json:"source,omitempty"`
+	Time        time.Time        `json:"time"`
+}
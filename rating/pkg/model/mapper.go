@@ -0,0 +1,165 @@
+package model
+
+import (
+	"time"
+
+	"github.com/ugurcancaykara/odd-service/gen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// RatingEventToProto converts a RatingEvent struct into a generated proto counterpart.
+func RatingEventToProto(e *RatingEvent) *gen.RatingEvent {
+	res := &gen.RatingEvent{
+		SchemaVersion: int32(e.SchemaVersion),
+		UserId:        string(e.UserID),
+		RecordId:      string(e.RecordID),
+		RecordType:    string(e.RecordType),
+		ProviderId:    e.ProviderID,
+		EventType:     string(e.EventType),
+	}
+	if e.EventType != RatingEventTypeDelete {
+		res.Value = proto.Int32(int32(e.Value))
+	}
+	if !e.Timestamp.IsZero() {
+		res.Timestamp = timestamppb.New(e.Timestamp)
+	}
+	return res
+}
+
+// RatingEventFromProto converts a generated proto counterpart into a RatingEvent struct.
+func RatingEventFromProto(e *gen.RatingEvent) *RatingEvent {
+	return &RatingEvent{
+		SchemaVersion: int(e.SchemaVersion),
+		UserID:        UserID(e.UserId),
+		RecordID:      RecordID(e.RecordId),
+		RecordType:    RecordType(e.RecordType),
+		Value:         RatingValue(e.GetValue()),
+		ProviderID:    e.ProviderId,
+		EventType:     RatingEventType(e.EventType),
+		Timestamp:     timeFromProto(e.Timestamp),
+	}
+}
+
+// RatingToProto converts a Rating struct into a generated proto counterpart.
+func RatingToProto(r *Rating) *gen.Rating {
+	res := &gen.Rating{
+		RecordId:   r.RecordID,
+		RecordType: r.RecordType,
+		UserId:     string(r.UserID),
+		Value:      int32(r.Value),
+		ProviderId: r.ProviderID,
+	}
+	if !r.IngestedAt.IsZero() {
+		res.IngestedAt = timestamppb.New(r.IngestedAt)
+	}
+	if !r.CreatedAt.IsZero() {
+		res.CreatedAt = timestamppb.New(r.CreatedAt)
+	}
+	if !r.UpdatedAt.IsZero() {
+		res.UpdatedAt = timestamppb.New(r.UpdatedAt)
+	}
+	return res
+}
+
+// TimeWindowFromProto converts optional proto timestamps into a TimeWindow.
+func TimeWindowFromProto(from *timestamppb.Timestamp, to *timestamppb.Timestamp) TimeWindow {
+	var w TimeWindow
+	if from != nil {
+		w.From = from.AsTime()
+	}
+	if to != nil {
+		w.To = to.AsTime()
+	}
+	return w
+}
+
+// RatingTimeSeriesToProto converts time series points into a generated proto counterpart.
+func RatingTimeSeriesToProto(points []RatingTimeSeriesPoint) *gen.GetRatingTimeSeriesResponse {
+	res := &gen.GetRatingTimeSeriesResponse{}
+	for _, p := range points {
+		res.Points = append(res.Points, &gen.RatingTimeSeriesPoint{
+			Start:   timestamppb.New(p.Start),
+			Count:   int64(p.Count),
+			Average: p.Average,
+		})
+	}
+	return res
+}
+
+// RatingStatsToProto converts a RatingStats struct into a generated proto counterpart.
+func RatingStatsToProto(s *RatingStats) *gen.GetRatingStatsResponse {
+	res := &gen.GetRatingStatsResponse{
+		Count:           int64(s.Count),
+		RatingValue:     s.Aggregated,
+		NormalizedValue: s.Normalized,
+		Scale:           RatingScaleToProto(s.Scale),
+	}
+	for _, p := range s.Providers {
+		res.Providers = append(res.Providers, &gen.ProviderRatingStats{
+			ProviderId: p.ProviderID,
+			Count:      int64(p.Count),
+			Average:    p.Average,
+			Weight:     p.Weight,
+		})
+	}
+	return res
+}
+
+// RatingScaleToProto converts a RatingScale struct into a generated proto counterpart.
+func RatingScaleToProto(s RatingScale) *gen.RatingScale {
+	return &gen.RatingScale{Name: s.Name, Min: int32(s.Min), Max: int32(s.Max)}
+}
+
+// RankedRecordToProto converts a RankedRecord struct into a generated proto counterpart.
+func RankedRecordToProto(r *RankedRecord) *gen.RankedRecord {
+	return &gen.RankedRecord{
+		RecordId:   string(r.RecordID),
+		RecordType: string(r.RecordType),
+		Score:      r.Score,
+		Votes:      int64(r.Votes),
+	}
+}
+
+// RankedRecordFromProto converts a generated proto counterpart into a RankedRecord struct.
+func RankedRecordFromProto(r *gen.RankedRecord) *RankedRecord {
+	return &RankedRecord{
+		RecordID:   RecordID(r.RecordId),
+		RecordType: RecordType(r.RecordType),
+		Score:      r.Score,
+		Votes:      int(r.Votes),
+	}
+}
+
+// RatingFromProto converts a generated proto counterpart into a Rating struct.
+func RatingFromProto(r *gen.Rating) *Rating {
+	return &Rating{
+		RecordID:   r.RecordId,
+		RecordType: r.RecordType,
+		UserID:     UserID(r.UserId),
+		Value:      RatingValue(r.Value),
+		ProviderID: r.ProviderId,
+		IngestedAt: timeFromProto(r.IngestedAt),
+		CreatedAt:  timeFromProto(r.CreatedAt),
+		UpdatedAt:  timeFromProto(r.UpdatedAt),
+	}
+}
+
+// QuarantinedRatingToProto converts a QuarantinedRating struct into a generated proto counterpart.
+func QuarantinedRatingToProto(q *QuarantinedRating) *gen.QuarantinedRating {
+	res := &gen.QuarantinedRating{
+		Rating:        RatingToProto(&q.Rating),
+		QuarantinedAt: timestamppb.New(q.QuarantinedAt),
+	}
+	for _, r := range q.Reasons {
+		res.Reasons = append(res.Reasons, string(r))
+	}
+	return res
+}
+
+func timeFromProto(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.AsTime()
+}
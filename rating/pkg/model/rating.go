@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // RecordID defines a record id. Together with RecordType
 // identifies unique records across all types.
 type RecordID string
@@ -11,6 +13,8 @@ type RecordType string
 // Existing record types
 const (
 	RecordTypeMovie       = RecordType("movie")
+	RecordTypeSeries      = RecordType("series")
+	RecordTypeEpisode     = RecordType("episode")
 	RatingEventTypePut    = "put"
 	RatingEventTypeDelete = "delete"
 )
@@ -21,6 +25,42 @@ type UserID string
 // RatingValue defines a value of a rating record.
 type RatingValue int
 
+// RatingScale defines the allowed values of the ratings of a record type, from Min to Max inclusive.
+type RatingScale struct {
+	Name string      `json:"name"`
+	Min  RatingValue `json:"min"`
+	Max  RatingValue `json:"max"`
+}
+
+// Predefined rating scales
+var (
+	// RatingScaleStars5 defines 1 to 5 stars.
+	RatingScaleStars5 = RatingScale{Name: "stars5", Min: 1, Max: 5}
+	// RatingScalePoints10 defines 1 to 10 points.
+	RatingScalePoints10 = RatingScale{Name: "points10", Min: 1, Max: 10}
+	// RatingScaleThumbs defines thumbs down (0) or up (1).
+	RatingScaleThumbs = RatingScale{Name: "thumbs", Min: 0, Max: 1}
+)
+
+// Contains reports whether v is an allowed value of the scale.
+func (s RatingScale) Contains(v RatingValue) bool {
+	return v >= s.Min && v <= s.Max
+}
+
+// Normalize maps a value of the scale, such as an aggregated rating, to the range 0 to 1.
+func (s RatingScale) Normalize(v float64) float64 {
+	if s.Max == s.Min {
+		return 0
+	}
+	return (v - float64(s.Min)) / float64(s.Max-s.Min)
+}
+
+// RecordTypeScale defines a rateable record type and its rating scale.
+type RecordTypeScale struct {
+	RecordType RecordType  `json:"recordType"`
+	Scale      RatingScale `json:"scale"`
+}
+
 // Rating defines an individual rating created by a user
 // for some record.
 type Rating struct {
@@ -28,20 +68,167 @@ type Rating struct {
 	RecordType string      `json:"recordType"`
 	UserID     UserID      `json:"userId"`
 	Value      RatingValue `json:"value"`
+	ProviderID string      `json:"providerId,omitempty"`
+	// IngestedAt defines when the rating was received by the rating service.
+	IngestedAt time.Time `json:"ingestedAt"`
+	// CreatedAt defines when the user first rated the record, UpdatedAt when the rating last changed.
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TimeWindow defines a time range of ratings, From is inclusive and To exclusive.
+// A zero From or To leaves the window unbounded on that side.
+type TimeWindow struct {
+	From time.Time
+	To   time.Time
+}
+
+// Contains reports whether t is inside the window.
+func (w TimeWindow) Contains(t time.Time) bool {
+	return (w.From.IsZero() || !t.Before(w.From)) && (w.To.IsZero() || t.Before(w.To))
+}
+
+// ExportFilter defines the ratings to export, empty fields match every rating.
+// Window bounds the last update time of the ratings.
+type ExportFilter struct {
+	RecordType RecordType
+	ProviderID string
+	Window     TimeWindow
+}
+
+// Matches reports whether a rating carrying its record type passes the filter.
+func (f ExportFilter) Matches(r *Rating) bool {
+	return (f.RecordType == "" || RecordType(r.RecordType) == f.RecordType) &&
+		(f.ProviderID == "" || r.ProviderID == f.ProviderID) &&
+		f.Window.Contains(r.UpdatedAt)
+}
+
+// TimeSeriesInterval defines the bucket size of a rating time series.
+type TimeSeriesInterval string
+
+// Supported time series intervals
+const (
+	TimeSeriesIntervalDay  = TimeSeriesInterval("day")
+	TimeSeriesIntervalWeek = TimeSeriesInterval("week")
+)
+
+// RatingTimeSeriesPoint defines the ratings of a record last updated within a time bucket.
+type RatingTimeSeriesPoint struct {
+	Start   time.Time `json:"start"`
+	Count   int       `json:"count"`
+	Average float64   `json:"average"`
+}
+
+// RatingStats defines rating statistics of a record.
+type RatingStats struct {
+	Count int `json:"count"`
+	// Aggregated defines the aggregated rating weighted by provider trust, Normalized the same rating mapped
+	// from the scale of the record type to the range 0 to 1.
+	Aggregated float64               `json:"aggregated"`
+	Normalized float64               `json:"normalized"`
+	Scale      RatingScale           `json:"scale"`
+	Providers  []ProviderRatingStats `json:"providers"`
+}
+
+// ProviderRatingStats defines statistics of the ratings of a record received from a single provider.
+type ProviderRatingStats struct {
+	ProviderID string  `json:"providerId"`
+	Count      int     `json:"count"`
+	Average    float64 `json:"average"`
+	Weight     float64 `json:"weight"`
+}
+
+// AggregationStrategy defines how the ratings of a record are aggregated into a score for ranking.
+type AggregationStrategy string
+
+// Supported aggregation strategies
+const (
+	// AggregationStrategyMean averages all ratings.
+	AggregationStrategyMean = AggregationStrategy("mean")
+	// AggregationStrategyWeighted averages ratings weighted by provider trust.
+	AggregationStrategyWeighted = AggregationStrategy("weighted")
+	// AggregationStrategyBayesian is the trust-weighted average pulled towards a prior,
+	// so records with few ratings don't outrank records with many.
+	AggregationStrategyBayesian = AggregationStrategy("bayesian")
+)
+
+// RankedRecord defines a record in a leaderboard. For top rated records Score is the aggregated rating
+// and Votes the number of ratings, for trending records Score is the number of ratings per hour
+// and Votes the number of ratings within the window.
+type RankedRecord struct {
+	RecordID   RecordID   `json:"recordId"`
+	RecordType RecordType `json:"recordType"`
+	Score      float64    `json:"score"`
+	Votes      int        `json:"votes"`
+}
+
+// AnomalyReason defines why a rating was flagged as suspicious.
+type AnomalyReason string
+
+// Supported anomaly reasons
+const (
+	// AnomalyReasonBurst flags ratings of a record received at a much higher rate than usual.
+	AnomalyReasonBurst = AnomalyReason("burst")
+	// AnomalyReasonNewUserSpike flags ratings of new users when a record suddenly receives many of them.
+	AnomalyReasonNewUserSpike = AnomalyReason("new_user_spike")
+	// AnomalyReasonOutlier flags ratings far from the average of a record while the recent ratings shift the same way.
+	AnomalyReasonOutlier = AnomalyReason("outlier")
+)
+
+// QuarantinedRating defines a suspicious rating held back from aggregates until it is moderated.
+type QuarantinedRating struct {
+	Rating        Rating          `json:"rating"`
+	Reasons       []AnomalyReason `json:"reasons"`
+	QuarantinedAt time.Time       `json:"quarantinedAt"`
 }
 
+// ModerationDecision defines the outcome of reviewing a quarantined rating.
+type ModerationDecision string
+
+// Supported moderation decisions
+const (
+	// ModerationDecisionApprove stores a quarantined rating like any other rating.
+	ModerationDecisionApprove = ModerationDecision("approve")
+	// ModerationDecisionReject discards a quarantined rating.
+	ModerationDecisionReject = ModerationDecision("reject")
+)
+
 // RatingEventType defines the type of a rating event
 type RatingEventType string
 
+// CurrentSchemaVersion defines the rating event schema version produced by this code.
+// Version 1 events carry no version, may use numeric record types and may omit the event type.
+const CurrentSchemaVersion = 2
+
 // An example of the provided rating data would be as follow in json:
 // [{"userId":"105","recordId":"1","recordType":1,"value":5,"providerId":"test-provier","eventType":"put"},
 // {"userId":"105"},"recordId":"2","recordType":1,"value":4,"providerId":"test-provider","eventType":"put"]
 // RatingEvent defines an event containing rating information. For provided data from Kafka
 type RatingEvent struct {
-	UserID     UserID          `json:"userId"`
-	RecordID   RecordID        `json:"recordId"`
-	RecordType RecordType      `json:"recordType"`
-	Value      RatingValue     `json:"value"`
-	ProviderID string          `json:"providerId"`
-	EventType  RatingEventType `json:"eventType"`
+	SchemaVersion int             `json:"schemaVersion,omitempty"`
+	UserID        UserID          `json:"userId"`
+	RecordID      RecordID        `json:"recordId"`
+	RecordType    RecordType      `json:"recordType"`
+	Value         RatingValue     `json:"value"`
+	ProviderID    string          `json:"providerId"`
+	EventType     RatingEventType `json:"eventType"`
+	// Timestamp defines when the user rated the record, e.g. for backfills of past ratings.
+	// Ratings of events without a timestamp are created at the time they are ingested.
+	Timestamp time.Time `json:"timestamp"`
+	// Source is set by the ingester which read the event and is never serialized.
+	Source *EventSource `json:"-"`
+}
+
+// EventSource defines the location a rating event was ingested from.
+// Ingesters use it to acknowledge events after they are processed.
+type EventSource struct {
+	Topic     string `json:"topic,omitempty"`
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+	// Payload contains the raw message the event was decoded from.
+	Payload []byte `json:"-"`
+	// ContentType defines the encoding of the payload.
+	ContentType string `json:"-"`
+	// TraceContext carries the trace context of the handling of the message the event was decoded from.
+	TraceContext map[string]string `json:"-"`
 }
@@ -0,0 +1,3 @@
+-- Adds provider attribution and the ingestion time to ratings created before schema.sql included them.
+ALTER TABLE ratings ADD COLUMN provider_id VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN ingested_at DATETIME(6) NULL;
+CREATE INDEX ratings_provider_id ON ratings (provider_id);
@@ -0,0 +1,12 @@
+-- Adds creation and update times to ratings and keeps a single rating per user and record.
+-- Ratings are copied into a new table since duplicate ratings of a user have to be merged before adding the unique key,
+-- the duplicates are copied in the order they were ingested so the latest one wins, ratings without an ingestion time
+-- are copied first.
+CREATE TABLE ratings_new LIKE ratings;
+ALTER TABLE ratings_new ADD COLUMN created_at DATETIME(6) NULL, ADD COLUMN updated_at DATETIME(6) NULL,
+    ADD UNIQUE KEY ratings_record_user (record_id, record_type, user_id);
+INSERT INTO ratings_new (record_id, record_type, user_id, value, provider_id, ingested_at, created_at, updated_at)
+    SELECT record_id, record_type, user_id, value, provider_id, ingested_at, ingested_at, ingested_at FROM ratings ORDER BY ingested_at
+    ON DUPLICATE KEY UPDATE value = VALUES(value), provider_id = VALUES(provider_id), ingested_at = VALUES(ingested_at), updated_at = VALUES(updated_at);
+RENAME TABLE ratings TO ratings_old, ratings_new TO ratings;
+DROP TABLE ratings_old;
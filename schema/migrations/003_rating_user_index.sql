@@ -0,0 +1,2 @@
+-- Adds a secondary index listing the ratings of a user ordered by record.
+CREATE INDEX ratings_user ON ratings (user_id, record_type, record_id);
@@ -0,0 +1,2 @@
+-- Adds the table holding suspicious ratings until they are moderated.
+CREATE TABLE IF NOT EXISTS quarantined_ratings (record_id VARCHAR(255), record_type VARCHAR(255), user_id VARCHAR(255), value INT, provider_id VARCHAR(255) NOT NULL DEFAULT '', ingested_at DATETIME(6) NULL, created_at DATETIME(6) NULL, updated_at DATETIME(6) NULL, reasons VARCHAR(255) NOT NULL DEFAULT '', quarantined_at DATETIME(6) NOT NULL, PRIMARY KEY (record_type, record_id, user_id));
@@ -0,0 +1,2 @@
+-- Adds the token buckets of the shared rate limit backend.
+CREATE TABLE IF NOT EXISTS rate_limits (bucket_key VARCHAR(255) PRIMARY KEY, tokens DOUBLE NOT NULL, updated_at DATETIME(6) NOT NULL);
@@ -0,0 +1,6 @@
+-- Replaces the movies table with a metadata table keyed by record type and id, holding type-specific fields as JSON.
+-- The movies are copied into a new table which is then swapped in, the movies table is kept as movies_backup
+-- and can be dropped once the migration is verified.
+CREATE TABLE IF NOT EXISTS metadata_new (record_type VARCHAR(255) NOT NULL, id VARCHAR(255) NOT NULL, title VARCHAR(255), description TEXT, director VARCHAR(255) NOT NULL DEFAULT '', details JSON NULL, PRIMARY KEY (record_type, id));
+INSERT IGNORE INTO metadata_new (record_type, id, title, description, director) SELECT 'movie', id, title, description, COALESCE(director, '') FROM movies;
+RENAME TABLE movies TO movies_backup, metadata_new TO metadata;
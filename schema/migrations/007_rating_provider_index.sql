@@ -0,0 +1,3 @@
+-- Replaces the provider index with one listing the ratings of a provider ordered by record and user.
+CREATE INDEX ratings_provider ON ratings (provider_id, record_type, record_id, user_id);
+DROP INDEX ratings_provider_id ON ratings;
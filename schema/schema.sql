@@ -1,3 +1,4 @@
-CREATE TABLE IF NOT EXISTS movies (id VARCHAR(255), title VARCHAR(255), description TEXT, director VARCHAR(255));
-CREATE TABLE IF NOT EXISTS ratings (record_id VARCHAR(255), record_type VARCHAR(255), user_id VARCHAR(255), value INT);
-
+CREATE TABLE IF NOT EXISTS metadata (record_type VARCHAR(255) NOT NULL, id VARCHAR(255) NOT NULL, title VARCHAR(255), description TEXT, director VARCHAR(255) NOT NULL DEFAULT '', details JSON NULL, PRIMARY KEY (record_type, id));
+CREATE TABLE IF NOT EXISTS ratings (record_id VARCHAR(255), record_type VARCHAR(255), user_id VARCHAR(255), value INT, provider_id VARCHAR(255) NOT NULL DEFAULT '', ingested_at DATETIME(6) NULL, created_at DATETIME(6) NULL, updated_at DATETIME(6) NULL, UNIQUE KEY ratings_record_user (record_id, record_type, user_id), INDEX ratings_provider (provider_id, record_type, record_id, user_id), INDEX ratings_user (user_id, record_type, record_id));
+CREATE TABLE IF NOT EXISTS quarantined_ratings (record_id VARCHAR(255), record_type VARCHAR(255), user_id VARCHAR(255), value INT, provider_id VARCHAR(255) NOT NULL DEFAULT '', ingested_at DATETIME(6) NULL, created_at DATETIME(6) NULL, updated_at DATETIME(6) NULL, reasons VARCHAR(255) NOT NULL DEFAULT '', quarantined_at DATETIME(6) NOT NULL, PRIMARY KEY (record_type, record_id, user_id));
+CREATE TABLE IF NOT EXISTS rate_limits (bucket_key VARCHAR(255) PRIMARY KEY, tokens DOUBLE NOT NULL, updated_at DATETIME(6) NOT NULL);
@@ -15,6 +15,7 @@ import (
 	ratingtest "github.com/ugurcancaykara/odd-service/rating/pkg/testutil"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -71,6 +72,7 @@ func main() {
 		Title:       "The Movie",
 		Description: "The Movie, the one and only",
 		Director:    "Mr. D",
+		RecordType:  "movie",
 	}
 
 	if _, err := metadataClient.PutMetadata(ctx, &gen.PutMetadataRequest{Metadata: m}); err != nil {
@@ -103,14 +105,16 @@ func main() {
 
 	log.Println("Saving first rating via rating service")
 
+	// A user has a single rating per record, so the ratings are put by different users.
 	const userID = "user0"
+	const secondUserID = "user1"
 	const recordTypeMovie = "movie"
 	firstRating := int32(5)
 	if _, err = ratingClient.PutRating(ctx, &gen.PutRatingRequest{
 		UserId:      userID,
 		RecordId:    m.Id,
 		RecordType:  recordTypeMovie,
-		RatingValue: firstRating,
+		RatingValue: proto.Int32(firstRating),
 	}); err != nil {
 		log.Fatalf("put rating: %v", err)
 	}
@@ -133,10 +137,10 @@ func main() {
 
 	secondRating := int32(1)
 	if _, err = ratingClient.PutRating(ctx, &gen.PutRatingRequest{
-		UserId:      userID,
+		UserId:      secondUserID,
 		RecordId:    m.Id,
 		RecordType:  recordTypeMovie,
-		RatingValue: secondRating,
+		RatingValue: proto.Int32(secondRating),
 	}); err != nil {
 		log.Fatalf("put rating: %v", err)
 	}
@@ -167,6 +171,30 @@ func main() {
 		log.Fatalf("get movie details after update mismatch: %v", err)
 	}
 
+	log.Println("Saving series metadata via metadata service")
+
+	series := &gen.Metadata{
+		Id:          "the-movie",
+		RecordType:  "series",
+		Title:       "The Series",
+		Description: "The Movie, the series",
+		Series:      &gen.SeriesDetails{Creator: "Mr. D", Seasons: 2, FirstYear: 2020},
+	}
+	if _, err := metadataClient.PutMetadata(ctx, &gen.PutMetadataRequest{Metadata: series}); err != nil {
+		log.Fatalf("put series metadata: %v", err)
+	}
+
+	log.Println("Getting series details via movie service")
+
+	getMovieDetailsResp, err = movieClient.GetMovieDetails(ctx, &gen.GetMovieDetailsRequest{MovieId: series.Id, RecordType: series.RecordType})
+	if err != nil {
+		log.Fatalf("get series details: %v", err)
+	}
+	wantSeriesDetails := &gen.MovieDetails{Metadata: series}
+	if diff := cmp.Diff(getMovieDetailsResp.MovieDetails, wantSeriesDetails, cmpopts.IgnoreUnexported(gen.MovieDetails{}, gen.Metadata{}, gen.SeriesDetails{})); diff != "" {
+		log.Fatalf("get series details mismatch: %v", diff)
+	}
+
 	log.Println("Integration test execution successful")
 }
 